@@ -1,16 +1,22 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"time"
 
 	"backend/internal/db"
 	"backend/internal/embeddings"
-	"encoding/json"
+	"backend/internal/sync"
+	"backend/internal/vectorstore"
 	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
 	"github.com/mattn/go-sqlite3"
 )
 
 func main() {
+	force := flag.Bool("force", false, "re-embed every active market, even if its text is unchanged")
+	flag.Parse()
+
 	sqlite_vec.Auto()
 	_ = sqlite3.SQLITE_DELETE
 
@@ -26,31 +32,32 @@ func main() {
 	}
 	defer embService.Close()
 
-	// 1. Manually embed all active markets
+	// 1. Only the active index can be backfilled; a new model or template needs a rebuild
+	index, err := vectorstore.ActiveIndex(database)
+	if err != nil {
+		log.Fatalf("Failed to load embedding index: %v", err)
+	}
+	if !vectorstore.SameModel(index, embService.Model(), embService.Dimension()) || index.TemplateVersion != sync.EmbeddingTemplateVersion {
+		log.Fatalf("Active index %d was built with %s (%d dims, template v%d), not %s (%d dims, template v%d); run reindex_embeddings",
+			index.ID, index.Model, index.Dimension, index.TemplateVersion,
+			embService.Model(), embService.Dimension(), sync.EmbeddingTemplateVersion)
+	}
+
+	// The syncer only needs the DB and embedding service to embed markets
+	syncer := sync.NewSyncer(database, nil, embService, nil, nil)
+
+	// 2. Embed all active markets through the same path as the event sync
 	var markets []db.Market
 	database.Where("status = ?", "active").Find(&markets)
 	log.Printf("Found %d active markets", len(markets))
 
-	for i, m := range markets {
-		// Log progress every 10 items
-		if i%10 == 0 {
-			log.Printf("Processing market %d/%d: %s", i+1, len(markets), m.Ticker)
-		}
-
-		embeddingText := m.Title + " " + m.Description + " " + m.Category
-		vec, err := embService.Generate(embeddingText)
-		if err != nil {
-			log.Printf("Failed to generate embedding: %v", err)
-			continue
-		}
-
-		vecBytes, _ := json.Marshal(vec)
-		vecString := string(vecBytes)
-
-		query := `INSERT OR REPLACE INTO vec_markets(id, embedding) VALUES (?, ?)`
-		if err := database.Exec(query, m.ID, vecString).Error; err != nil {
-			log.Printf("Failed to insert embedding for %s: %v", m.Ticker, err)
-		}
+	stats := syncer.EmbedMarkets(index, markets, *force)
+	log.Printf("Embedded %d markets in %v (%.1f markets/s), %d unchanged, %d failed",
+		stats.Embedded, stats.Duration.Round(time.Millisecond), stats.Rate(), stats.Skipped, stats.Failed)
+
+	count, err := syncer.Vectors.Count()
+	if err != nil {
+		log.Printf("Failed to count embeddings: %v", err)
 	}
-	log.Println("Finished backfilling embeddings.")
+	log.Printf("Finished backfilling embeddings. %d embeddings indexed.", count)
 }
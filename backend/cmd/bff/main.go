@@ -30,7 +30,7 @@ func main() {
 	// Enable CORS for frontend development
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -46,6 +46,17 @@ func main() {
 		api.GET("/markets", h.GetMarkets)
 		api.GET("/markets/by-event", h.GetMarketsByEvent)
 		api.GET("/events", h.GetEvents)
+		api.GET("/portfolio/equity", h.GetEquityCurve)
+		api.GET("/portfolio/pnl", h.GetPnL)
+		api.GET("/portfolio/exposure", h.GetExposure)
+		api.GET("/portfolio/strategies", h.GetStrategyPerformance)
+		api.GET("/jobs", h.ListJobs)
+		api.GET("/jobs/:name/runs", h.GetJobRuns)
+		api.POST("/jobs/:name/run", h.RunJob)
+		api.POST("/jobs/:name/cancel", h.CancelJob)
+		api.GET("/slm/calls", h.ListSLMCalls)
+		api.GET("/slm/calls/:id", h.GetSLMCall)
+		api.GET("/slm/stats", h.GetSLMStats)
 	}
 
 	// 5. Start Server
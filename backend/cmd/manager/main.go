@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"backend/internal/embeddings"
 	"backend/internal/kalshi"
 	"backend/internal/manager"
+	"backend/internal/scheduler"
 	"backend/internal/slm"
 	"backend/internal/sync"
 
@@ -42,6 +44,9 @@ func main() {
 	)
 	if err != nil {
 		log.Printf("Warning: Failed to init Kalshi client: %v", err)
+	} else if rps, err := strconv.ParseFloat(os.Getenv("KALSHI_RATE_LIMIT"), 64); err == nil && rps > 0 {
+		// Requests per second shared by the sync jobs; raise it on higher API tiers
+		kClient.Limiter = kalshi.NewRateLimiter(rps)
 	}
 
 	// 3. Initialize Embedding Service
@@ -65,18 +70,50 @@ func main() {
 	if modelName == "" {
 		modelName = "qwen3:14b"
 	}
-	slmService, err := slm.NewService(modelName)
+	// Every SLM call is recorded so any implication can be inspected later
+	slmConfig := slm.ConfigFromEnv(modelName)
+	slmConfig.Traces = slm.NewTraceStore(database)
+	slmService, err := slm.NewServiceWithConfig(slmConfig)
 	if err != nil {
 		log.Printf("Warning: Failed to init SLM service: %v", err)
 	}
 
 	// 6. Initialize Syncer
 	syncer := sync.NewSyncer(database, kClient, embService, slmService, redisClient)
+	if dollars, err := strconv.ParseFloat(os.Getenv("PAPER_STARTING_BALANCE"), 64); err == nil && dollars > 0 {
+		syncer.PaperStartingBalance = int64(dollars * 100)
+	}
+	// Compare as many pairs at once as the SLM server runs in parallel (OLLAMA_NUM_PARALLEL)
+	if n, err := strconv.Atoi(os.Getenv("SLM_CONCURRENCY")); err == nil && n > 0 {
+		syncer.ComparisonWorkers = n
+	}
+	if tokens, err := strconv.Atoi(os.Getenv("SLM_CYCLE_TOKEN_BUDGET")); err == nil && tokens >= 0 {
+		syncer.ComparisonBudget.Tokens = tokens
+	}
+	if d, err := time.ParseDuration(os.Getenv("SLM_CYCLE_TIME_BUDGET")); err == nil && d >= 0 {
+		syncer.ComparisonBudget.Time = d
+	}
+
+	// Rebuild the vector index in the background if the embedding model or text
+	// template changed; searches use the current index until the new one is swapped in
+	if embService != nil {
+		go func() {
+			if err := syncer.BuildEmbeddingIndex(false); err != nil {
+				log.Printf("Embedding index build failed: %v", err)
+			}
+		}()
+	}
 
-	// 7. Initialize Handler
-	h := manager.NewHandler(database, kClient, embService, syncer)
+	// 7. Initialize Scheduler with the background jobs
+	sched := scheduler.New(database, redisClient)
+	if err := manager.RegisterJobs(sched, syncer); err != nil {
+		log.Fatalf("Could not register jobs: %v", err)
+	}
+
+	// 8. Initialize Handler
+	h := manager.NewHandler(database, kClient, embService, syncer, sched)
 
-	// 8. Start Manager API
+	// 9. Start Manager API
 	go func() {
 		r := gin.Default()
 		r.GET("/providers/:name/balance", h.GetProviderBalance)
@@ -85,6 +122,14 @@ func main() {
 		r.GET("/events", h.GetEvents)
 		r.GET("/events/:event_id", h.GetEvent)
 		r.POST("/markets/search", h.SearchMarkets)
+		r.POST("/sync/events", h.TriggerEventSync)
+		r.GET("/jobs", h.ListJobs)
+		r.GET("/jobs/:name/runs", h.GetJobRuns)
+		r.POST("/jobs/:name/run", h.RunJob)
+		r.POST("/jobs/:name/cancel", h.CancelJob)
+		r.GET("/slm/calls", h.ListSLMCalls)
+		r.GET("/slm/calls/:id", h.GetSLMCall)
+		r.GET("/slm/stats", h.GetSLMStats)
 
 		log.Println("Manager API running on :8081")
 		if err := r.Run(":8081"); err != nil {
@@ -92,7 +137,7 @@ func main() {
 		}
 	}()
 
-	// 9. Setup Context for graceful shutdown
+	// 10. Setup Context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -105,20 +150,7 @@ func main() {
 		cancel()
 	}()
 
-	// 10. Execution Loop
-	ticker := time.NewTicker(24 * time.Hour)
-	defer ticker.Stop()
-
-	// Run initial sync on startup
-	go h.RunSyncCycle()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Manager stopped.")
-			return
-		case <-ticker.C:
-			go h.RunSyncCycle()
-		}
-	}
+	// 11. Run background jobs on their schedules until shutdown
+	sched.Run(ctx)
+	log.Println("Manager stopped.")
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"backend/internal/db"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"github.com/mattn/go-sqlite3"
+)
+
+const usage = `usage: migrate [command]
+
+commands:
+  up          apply all pending migrations (default)
+  down [n]    roll back the last n applied migrations (default 1)
+  status      list migrations and whether they have been applied`
+
+func main() {
+	// Register sqlite-vec extension (vec_markets is created by a migration)
+	sqlite_vec.Auto()
+	// Force registration of sqlite3 driver to ensure extensions are loaded
+	_ = sqlite3.SQLITE_DELETE
+
+	command := "up"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	database, err := db.Open()
+	if err != nil {
+		log.Fatalf("Could not connect to DB: %v", err)
+	}
+
+	switch command {
+	case "up":
+		applied, err := db.MigrateUp(database)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Schema is up to date.")
+		}
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps %q", os.Args[2])
+			}
+		}
+		rolledBack, err := db.MigrateDown(database, steps)
+		for _, m := range rolledBack {
+			log.Printf("Rolled back %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+
+	case "status":
+		states, err := db.MigrationStatus(database)
+		if err != nil {
+			log.Fatalf("Could not read migration status: %v", err)
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"backend/internal/db"
+	"backend/internal/embeddings"
+	"backend/internal/sync"
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"github.com/mattn/go-sqlite3"
+)
+
+// reindex_embeddings rebuilds the market vector index with the configured embedding
+// model and text template, then swaps it in. The manager does the same in the
+// background on startup; this runs the build in the foreground.
+func main() {
+	force := flag.Bool("force", false, "rebuild even if the active index already matches the model and template")
+	flag.Parse()
+
+	sqlite_vec.Auto()
+	_ = sqlite3.SQLITE_DELETE
+
+	log.Println("Connecting to DB...")
+	database, err := db.Connect()
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+
+	embService, err := embeddings.NewService()
+	if err != nil {
+		log.Fatalf("Failed to init embedding service: %v", err)
+	}
+	defer embService.Close()
+
+	syncer := sync.NewSyncer(database, nil, embService, nil, nil)
+	if err := syncer.BuildEmbeddingIndex(*force); err != nil {
+		log.Fatalf("Re-index failed: %v", err)
+	}
+	log.Println("Embedding index is up to date.")
+}
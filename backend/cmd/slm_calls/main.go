@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"github.com/mattn/go-sqlite3"
+)
+
+// slm_calls lists recorded SLM calls, or shows one in full, to see why a pair of
+// markets was or wasn't found to be related.
+//
+//	go run ./cmd/slm_calls -ticker KXFED-26DEC-T4.00
+//	go run ./cmd/slm_calls -id 1234
+func main() {
+	id := flag.Uint("id", 0, "show this call with every reply")
+	ticker := flag.String("ticker", "", "only calls comparing this market")
+	backend := flag.String("backend", "", "only calls to this backend")
+	failed := flag.Bool("failed", false, "only calls that ended in an error")
+	limit := flag.Int("limit", 20, "calls to list")
+	flag.Parse()
+
+	sqlite_vec.Auto()
+	_ = sqlite3.SQLITE_DELETE
+
+	database, err := db.Connect()
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	traces := slm.NewTraceStore(database)
+
+	// 1. One call in full
+	if *id > 0 {
+		call, err := traces.Get(*id)
+		if err != nil {
+			log.Fatalf("Failed to fetch call %d: %v", *id, err)
+		}
+		show(call)
+		return
+	}
+
+	// 2. Or a list
+	calls, err := traces.List(slm.TraceFilter{Ticker: *ticker, Backend: *backend, Failed: *failed, Limit: *limit})
+	if err != nil {
+		log.Fatalf("Failed to list calls: %v", err)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tSOURCE\tTARGET\tBACKEND\tMODEL\tPROMPTS\tMS\tTOKENS\tANSWER")
+	for _, call := range calls {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n", call.ID, call.CreatedAt.Local().Format("01-02 15:04:05"),
+			call.SourceTicker, call.TargetTicker, call.Backend, call.Model, call.PromptVersion, call.LatencyMs, call.Tokens, answer(call))
+	}
+	tw.Flush()
+}
+
+// answer summarizes a call's outcome
+func answer(call db.SLMCall) string {
+	if call.Error != "" {
+		return "error"
+	}
+	var result slm.ComparisonResult
+	if err := json.Unmarshal([]byte(call.Result), &result); err != nil {
+		return "?"
+	}
+	outcome := func(o *string) string {
+		if o == nil {
+			return "null"
+		}
+		return *o
+	}
+	return fmt.Sprintf("yes->%s no->%s", outcome(result.SourceYes), outcome(result.SourceNo))
+}
+
+func show(call *db.SLMCall) {
+	fmt.Printf("Call %d at %s\n", call.ID, call.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("%s vs %s\n", call.SourceTicker, call.TargetTicker)
+	fmt.Printf("Backend %s, model %s, prompts %s, temperature %g\n", call.Backend, call.Model, call.PromptVersion, call.Temperature)
+	fmt.Printf("%dms, %d tokens, %d attempts\n", call.LatencyMs, call.Tokens, call.Attempts)
+
+	var prompts []string
+	json.Unmarshal([]byte(call.Prompt), &prompts)
+	for i, prompt := range prompts {
+		fmt.Printf("\n--- Prompt %d ---\n%s\n", i+1, prompt)
+	}
+	if len(prompts) == 0 && call.Markets != "" {
+		fmt.Printf("\nPrompts %s, rendered from %s\n", call.PromptHash, call.Markets)
+	}
+
+	var outputs []string
+	json.Unmarshal([]byte(call.Output), &outputs)
+	for i, output := range outputs {
+		fmt.Printf("\n--- Reply %d ---\n%s\n", i+1, output)
+	}
+
+	if call.Error != "" {
+		fmt.Printf("\n--- Error ---\n%s\n", call.Error)
+		return
+	}
+	var result map[string]any
+	json.Unmarshal([]byte(call.Result), &result)
+	pretty, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Printf("\n--- Result ---\n%s\n", pretty)
+}
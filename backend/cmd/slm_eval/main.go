@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// slm_eval runs a labeled dataset of market pairs through the SLM comparison with
+// a given model, prompt version and output format, and reports precision and
+// recall per implication type, so models and prompts can be chosen on evidence.
+//
+//	go run ./cmd/slm_eval -model qwen3:14b -prompts v1
+//	go run ./cmd/slm_eval -fake   # exercise the harness against a built-in fake server
+
+//go:embed dataset.jsonl
+var defaultDataset []byte
+
+// evalMarket is a market as written in the dataset
+type evalMarket struct {
+	Ticker      string `json:"ticker"`
+	Title       string `json:"title"`
+	YesSubTitle string `json:"yes_sub_title"`
+	Category    string `json:"category"`
+}
+
+func (m evalMarket) market() db.Market {
+	return db.Market{ExternalID: m.Ticker, Ticker: m.Ticker, Title: m.Title, YesSubTitle: m.YesSubTitle, Category: m.Category}
+}
+
+// description matches how the prompts present a market
+func (m evalMarket) description() string {
+	if m.YesSubTitle != "" {
+		return fmt.Sprintf("%s (%s)", m.Title, m.YesSubTitle)
+	}
+	return m.Title
+}
+
+// evalCase is a labeled market pair: the target outcomes the source's YES and NO
+// necessarily imply, null when there is none
+type evalCase struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"` // e.g. mutual_exclusion, numeric_ladder, correlation
+	Source    evalMarket `json:"source"`
+	Target    evalMarket `json:"target"`
+	SourceYes *string    `json:"source_yes"`
+	SourceNo  *string    `json:"source_no"`
+}
+
+// evalOutcome is one comparison's answer
+type evalOutcome struct {
+	SourceYes, SourceNo string // target_yes, target_no or none
+	Err                 error
+	Tokens              int
+	Latency             time.Duration
+}
+
+const none = "none"
+
+func outcomeOf(s *string) string {
+	if s == nil {
+		return none
+	}
+	return *s
+}
+
+// reversed gives the labels for the pair compared the other way round: by
+// contraposition, source=a => target=b becomes target=not b => source=not a
+func (c evalCase) reversed() (yes, no string) {
+	yes, no = none, none
+	for _, label := range []struct {
+		sourceOutcome string
+		target        *string
+	}{{"yes", c.SourceYes}, {"no", c.SourceNo}} {
+		if label.target == nil {
+			continue
+		}
+		implied := "target_no"
+		if label.sourceOutcome == "no" {
+			implied = "target_yes"
+		}
+		if *label.target == "target_yes" {
+			no = implied
+		} else {
+			yes = implied
+		}
+	}
+	return yes, no
+}
+
+func main() {
+	datasetPath := flag.String("dataset", "", "labeled pairs as JSON lines (default: the built-in dataset)")
+	model := flag.String("model", envOr("SLM_MODEL", "qwen3:14b"), "model to evaluate")
+	baseURL := flag.String("url", envOr("SLM_URL", "http://localhost:8088/v1"), "OpenAI-compatible API")
+	prompts := flag.String("prompts", slm.DefaultPromptVersion, "prompt version: "+strings.Join(slm.PromptVersions(), ", "))
+	promptDir := flag.String("prompt-dir", "", "directory of prompt templates to evaluate instead of a built-in version")
+	format := flag.String("format", slm.FormatJSONSchema, "response format: json_schema, json_object, gbnf or none")
+	singleTurn := flag.Bool("single-turn", false, "reason and answer in one request")
+	retries := flag.Int("retries", 2, "extra attempts when an answer doesn't parse")
+	reverseCheck := flag.Bool("reverse", true, "also compare each pair the other way round and keep only contrapositive-consistent implications")
+	samples := flag.Int("samples", slm.DefaultSamples, "answers per direction to vote on")
+	temperature := flag.Float64("temperature", slm.DefaultTemperature, "temperature of the samples after the first")
+	minConfidence := flag.Float64("min-confidence", slm.DefaultMinConfidence, "share of answers per direction that must agree on an implication")
+	concurrency := flag.Int("concurrency", 1, "comparisons run at once")
+	fake := flag.Bool("fake", false, "answer from the labels with a built-in fake OpenAI-compatible server")
+	verbose := flag.Bool("v", false, "print every pair and a line per SLM call")
+	fullLog := flag.Bool("vv", false, "like -v, also printing every prompt and reply")
+	flag.Parse()
+
+	logLevel := slm.LogSummary
+	switch {
+	case *fullLog:
+		*verbose, logLevel = true, slm.LogFull
+	case !*verbose:
+		log.SetOutput(io.Discard)
+	}
+
+	// 1. Load the dataset
+	data := defaultDataset
+	if *datasetPath != "" {
+		var err error
+		if data, err = os.ReadFile(*datasetPath); err != nil {
+			fatalf("Failed to read dataset: %v", err)
+		}
+	}
+	cases, err := parseDataset(data)
+	if err != nil {
+		fatalf("Invalid dataset: %v", err)
+	}
+
+	// 2. Start the fake server if asked
+	if *fake {
+		srv := httptest.NewServer(fakeServer(cases))
+		defer srv.Close()
+		*baseURL = srv.URL + "/v1"
+		*model = "fake"
+	}
+
+	// 3. Build the service under test
+	service, err := slm.NewServiceWithConfig(slm.Config{
+		BaseURL:       *baseURL,
+		Model:         *model,
+		Format:        *format,
+		SingleTurn:    *singleTurn,
+		ParseRetries:  *retries,
+		PromptVersion: *prompts,
+		PromptDir:     *promptDir,
+		LogLevel:      logLevel,
+		Checks: slm.Checks{
+			Reverse:       *reverseCheck,
+			Samples:       *samples,
+			Temperature:   *temperature,
+			MinConfidence: *minConfidence,
+		},
+	})
+	if err != nil {
+		fatalf("Failed to init SLM service: %v", err)
+	}
+
+	// 4. Compare every pair
+	started := time.Now()
+	outcomes := evaluate(service, cases, *concurrency, *verbose)
+
+	// 5. Report
+	report(os.Stdout, cases, outcomes, service.Stats(), time.Since(started),
+		fmt.Sprintf("model %s, prompts %s, format %s, single turn %t, reverse check %t, %d samples",
+			*model, promptName(*prompts, *promptDir), *format, *singleTurn, *reverseCheck, *samples))
+}
+
+// evaluate compares every pair, concurrency at a time
+func evaluate(service slm.Service, cases []evalCase, concurrency int, verbose bool) []evalOutcome {
+	outcomes := make([]evalOutcome, len(cases))
+	var done atomic.Int64
+	var g errgroup.Group
+	g.SetLimit(max(concurrency, 1))
+	for i, c := range cases {
+		g.Go(func() error {
+			start := time.Now()
+			result, err := service.CompareMarkets(c.Source.market(), c.Target.market())
+			out := evalOutcome{SourceYes: none, SourceNo: none, Err: err, Latency: time.Since(start)}
+			if err == nil {
+				out.SourceYes = outcomeOf(result.SourceYes)
+				out.SourceNo = outcomeOf(result.SourceNo)
+				out.Tokens = result.TokensUsed
+			}
+			outcomes[i] = out
+			if verbose {
+				fmt.Printf("%-16s yes->%-10s (want %-10s) no->%-10s (want %-10s) %v err=%v\n", c.ID,
+					out.SourceYes, outcomeOf(c.SourceYes), out.SourceNo, outcomeOf(c.SourceNo), out.Latency.Round(time.Millisecond), err)
+			} else {
+				fmt.Fprintf(os.Stderr, "\r%d/%d", done.Add(1), len(cases))
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	if !verbose {
+		fmt.Fprintln(os.Stderr)
+	}
+	return outcomes
+}
+
+func parseDataset(data []byte) ([]evalCase, error) {
+	var cases []evalCase
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c evalCase
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		for _, o := range []string{outcomeOf(c.SourceYes), outcomeOf(c.SourceNo)} {
+			if o != none && o != "target_yes" && o != "target_no" {
+				return nil, fmt.Errorf("line %d: invalid outcome %q", line, o)
+			}
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no pairs")
+	}
+	return cases, scanner.Err()
+}
+
+// implicationTypes are scored separately, since models tend to be good at some
+// (mutual exclusion's YES -> NO) and poor at others
+var implicationTypes = []struct {
+	field, outcome string
+}{
+	{"source_yes", "target_yes"},
+	{"source_yes", "target_no"},
+	{"source_no", "target_yes"},
+	{"source_no", "target_no"},
+}
+
+type score struct {
+	labeled, predicted, correct int
+}
+
+func (s score) precision() string { return percent(s.correct, s.predicted) }
+func (s score) recall() string    { return percent(s.correct, s.labeled) }
+
+func percent(n, d int) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(d))
+}
+
+func report(w io.Writer, cases []evalCase, outcomes []evalOutcome, stats slm.Stats, elapsed time.Duration, setup string) {
+	var total score
+	scores := make([]score, len(implicationTypes))
+	kinds := map[string]*score{} // labeled = pairs, correct = pairs fully right
+	errors, tokens := 0, 0
+	var latency time.Duration
+
+	for i, c := range cases {
+		out := outcomes[i]
+		if out.Err != nil {
+			errors++
+		}
+		tokens += out.Tokens
+		latency += out.Latency
+
+		got := map[string]string{"source_yes": out.SourceYes, "source_no": out.SourceNo}
+		want := map[string]string{"source_yes": outcomeOf(c.SourceYes), "source_no": outcomeOf(c.SourceNo)}
+		for j, t := range implicationTypes {
+			labeled := want[t.field] == t.outcome
+			predicted := got[t.field] == t.outcome
+			if labeled {
+				scores[j].labeled++
+				total.labeled++
+			}
+			if predicted {
+				scores[j].predicted++
+				total.predicted++
+			}
+			if labeled && predicted {
+				scores[j].correct++
+				total.correct++
+			}
+		}
+
+		k := kinds[c.Kind]
+		if k == nil {
+			k = &score{}
+			kinds[c.Kind] = k
+		}
+		k.labeled++
+		if out.Err == nil && got["source_yes"] == want["source_yes"] && got["source_no"] == want["source_no"] {
+			k.correct++
+		}
+	}
+
+	fmt.Fprintf(w, "%s\n", setup)
+	fmt.Fprintf(w, "%d pairs in %v: %d errors, %d unparseable answers, %d implications rejected by checks, %d tokens, %v mean latency\n\n",
+		len(cases), elapsed.Round(time.Millisecond), errors, stats.ParseFailures, stats.Rejected, tokens,
+		(latency / time.Duration(len(cases))).Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Implication\tLabeled\tPredicted\tCorrect\tPrecision\tRecall\t")
+	for j, t := range implicationTypes {
+		s := scores[j]
+		fmt.Fprintf(tw, "%s -> %s\t%d\t%d\t%d\t%s\t%s\t\n", t.field, t.outcome, s.labeled, s.predicted, s.correct, s.precision(), s.recall())
+	}
+	fmt.Fprintf(tw, "all\t%d\t%d\t%d\t%s\t%s\t\n", total.labeled, total.predicted, total.correct, total.precision(), total.recall())
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nBackend usage:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, b := range stats.Backends {
+		fmt.Fprintf(tw, "  %s\t%s\t%d requests\t%d failed\t%d tokens\t%v\n", b.Name, b.Model, b.Requests, b.Failures, b.Tokens, b.Latency.Round(time.Millisecond))
+	}
+	tw.Flush()
+	if stats.Escalations > 0 {
+		fmt.Fprintf(w, "  %d comparisons escalated\n", stats.Escalations)
+	}
+
+	fmt.Fprintln(w, "\nPairs fully right by kind:")
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, k := range names {
+		fmt.Fprintf(tw, "  %s\t%d/%d\t%s\n", k, kinds[k].correct, kinds[k].labeled, percent(kinds[k].correct, kinds[k].labeled))
+	}
+	tw.Flush()
+}
+
+// fakeServer is an OpenAI-compatible chat endpoint that answers every request with
+// the labels of the dataset pair found in the conversation, so the harness can be
+// checked end to end without a model
+func fakeServer(cases []evalCase) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Messages []struct {
+				Content any `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var conversation strings.Builder
+		for _, m := range req.Messages {
+			text, _ := json.Marshal(m.Content)
+			var s string
+			if json.Unmarshal(text, &s) != nil {
+				s = string(text)
+			}
+			conversation.WriteString(s)
+			conversation.WriteString("\n")
+		}
+
+		// The pair asked about, preferring the most specific match and the pair's own
+		// direction over the reverse comparison the consistency checks make
+		text := conversation.String()
+		var match *evalCase
+		reverse := false
+		best := 0
+		for i := range cases {
+			c := &cases[i]
+			src, tgt := c.Source.description(), c.Target.description()
+			score := 2 * (len(src) + len(tgt))
+			if si, ti := strings.Index(text, src), strings.LastIndex(text, tgt); si >= 0 && ti > si && score+1 > best {
+				match, reverse, best = c, false, score+1
+			}
+			if ti, si := strings.Index(text, tgt), strings.LastIndex(text, src); ti >= 0 && si > ti && score > best {
+				match, reverse, best = c, true, score
+			}
+		}
+
+		answer := "{}"
+		if match != nil {
+			yes, no := outcomeOf(match.SourceYes), outcomeOf(match.SourceNo)
+			if reverse {
+				yes, no = match.reversed()
+			}
+			b, _ := json.Marshal(map[string]string{
+				"analysis":   "Answered by the fake server from the dataset labels.",
+				"reason":     "Label of " + match.ID,
+				"source_yes": yes,
+				"source_no":  no,
+			})
+			answer = string(b)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      "fake",
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   "fake",
+			"choices": []any{map[string]any{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": answer},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]int{"prompt_tokens": len(text) / 4, "completion_tokens": len(answer) / 4, "total_tokens": (len(text) + len(answer)) / 4},
+		})
+	})
+}
+
+func promptName(version, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	return version
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
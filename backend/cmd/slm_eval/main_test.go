@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/slm"
+)
+
+// fixture has one pair per implication shape
+const fixture = `
+# A comment
+{"id": "me", "kind": "mutual_exclusion", "source": {"ticker": "SB-KC", "title": "Super Bowl champion?", "yes_sub_title": "Kansas City"}, "target": {"ticker": "SB-PHI", "title": "Super Bowl champion?", "yes_sub_title": "Philadelphia"}, "source_yes": "target_no", "source_no": null}
+{"id": "ladder-up", "kind": "numeric_ladder", "source": {"ticker": "CPI-4", "title": "CPI above 4%?"}, "target": {"ticker": "CPI-3", "title": "CPI above 3%?"}, "source_yes": "target_yes", "source_no": null}
+{"id": "ladder-down", "kind": "numeric_ladder", "source": {"ticker": "GDP-2", "title": "GDP growth above 2%?"}, "target": {"ticker": "GDP-3", "title": "GDP growth above 3%?"}, "source_yes": null, "source_no": "target_no"}
+{"id": "corr", "kind": "correlation", "source": {"ticker": "OIL", "title": "Oil above $100?"}, "target": {"ticker": "GAS", "title": "Gas above $5?"}, "source_yes": null, "source_no": null}
+`
+
+func loadFixture(t *testing.T) []evalCase {
+	t.Helper()
+	cases, err := parseDataset([]byte(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cases
+}
+
+func TestParseDataset(t *testing.T) {
+	if cases := loadFixture(t); len(cases) != 4 {
+		t.Fatalf("%d cases, want 4", len(cases))
+	}
+
+	for name, data := range map[string]string{
+		"empty":           "# nothing\n\n",
+		"invalid json":    `{"id": `,
+		"invalid outcome": `{"id": "x", "source_yes": "yes"}`,
+	} {
+		if _, err := parseDataset([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReversed(t *testing.T) {
+	ref := func(s string) *string { return &s }
+	tests := []struct {
+		sourceYes, sourceNo *string
+		wantYes, wantNo     string
+	}{
+		// A=YES => B=NO becomes B=YES => A=NO
+		{ref("target_no"), nil, "target_no", none},
+		// A=YES => B=YES becomes B=NO => A=NO
+		{ref("target_yes"), nil, none, "target_no"},
+		// A=NO => B=NO becomes B=YES => A=YES
+		{nil, ref("target_no"), "target_yes", none},
+		// A=NO => B=YES becomes B=NO => A=YES
+		{nil, ref("target_yes"), none, "target_yes"},
+		{nil, nil, none, none},
+	}
+	for _, tt := range tests {
+		c := evalCase{SourceYes: tt.sourceYes, SourceNo: tt.sourceNo}
+		if yes, no := c.reversed(); yes != tt.wantYes || no != tt.wantNo {
+			t.Errorf("reversed(%s, %s) = %s, %s, want %s, %s",
+				outcomeOf(tt.sourceYes), outcomeOf(tt.sourceNo), yes, no, tt.wantYes, tt.wantNo)
+		}
+	}
+}
+
+// evaluateAgainst runs the harness with a fake server answering from answers'
+// labels, and scores the results against the fixture's
+func evaluateAgainst(t *testing.T, answers []evalCase, reverse bool) string {
+	t.Helper()
+	srv := httptest.NewServer(fakeServer(answers))
+	defer srv.Close()
+
+	service, err := slm.NewServiceWithConfig(slm.Config{
+		BaseURL:  srv.URL + "/v1",
+		Model:    "fake",
+		LogLevel: slm.LogOff,
+		Checks:   slm.Checks{Reverse: reverse},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := loadFixture(t)
+	outcomes := evaluate(service, cases, 2, false)
+	var out bytes.Buffer
+	report(&out, cases, outcomes, service.Stats(), 0, "test")
+	return out.String()
+}
+
+// row returns the report's columns for an implication type
+func row(t *testing.T, report, label string) []string {
+	t.Helper()
+	for _, line := range strings.Split(report, "\n") {
+		fields := strings.Fields(line)
+		if strings.HasPrefix(strings.TrimSpace(line), label) {
+			return fields[len(strings.Fields(label)):]
+		}
+	}
+	t.Fatalf("no %q row in report:\n%s", label, report)
+	return nil
+}
+
+func TestEvaluatePerfectModel(t *testing.T) {
+	for _, reverse := range []bool{false, true} {
+		report := evaluateAgainst(t, loadFixture(t), reverse)
+		if got := row(t, report, "all"); strings.Join(got, " ") != "3 3 3 100.0% 100.0%" {
+			t.Errorf("reverse %t: all = %v\n%s", reverse, got, report)
+		}
+		if !strings.Contains(report, "0 errors") {
+			t.Errorf("reverse %t: errors reported\n%s", reverse, report)
+		}
+	}
+}
+
+func TestEvaluateScoresMistakes(t *testing.T) {
+	// The model gets the ladder's direction wrong and sees necessity in a correlation
+	answers := loadFixture(t)
+	targetYes, targetNo := "target_yes", "target_no"
+	answers[1].SourceYes = &targetNo
+	answers[3].SourceYes = &targetYes
+
+	report := evaluateAgainst(t, answers, false)
+	tests := []struct {
+		label string
+		want  string // Labeled, predicted, correct, precision, recall
+	}{
+		{"source_yes -> target_yes", "1 1 0 0.0% 0.0%"},
+		{"source_yes -> target_no", "1 2 1 50.0% 100.0%"},
+		{"source_no -> target_yes", "0 0 0 - -"},
+		{"source_no -> target_no", "1 1 1 100.0% 100.0%"},
+		{"all", "3 4 2 50.0% 66.7%"},
+		{"correlation", "0/1 0.0%"},
+		{"mutual_exclusion", "1/1 100.0%"},
+		{"numeric_ladder", "1/2 50.0%"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(row(t, report, tt.label), " "); got != tt.want {
+			t.Errorf("%s: %s, want %s", tt.label, got, tt.want)
+		}
+	}
+}
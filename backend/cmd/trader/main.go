@@ -5,21 +5,49 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"backend/internal/db"
+	"backend/internal/kalshi"
+	"backend/internal/trader"
 )
 
 func main() {
 	log.Println("Starting Trader Service...")
 
 	// 1. Initialize DB (to log trade executions)
-	_, err := db.Connect()
+	database, err := db.Connect()
 	if err != nil {
 		log.Fatalf("Could not connect to DB: %v", err)
 	}
 
-	// 2. Setup Context
+	// 2. Initialize Kalshi Client
+	kClient, err := kalshi.NewClient(
+		os.Getenv("KALSHI_BASE_URL"),
+		os.Getenv("KALSHI_API_KEY"),
+		os.Getenv("KALSHI_KEY_PATH"),
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to init Kalshi client: %v", err)
+	}
+
+	// 3. Route orders to the paper matching engine so nothing trades real money
+	latency := 250 * time.Millisecond
+	if ms, err := strconv.Atoi(os.Getenv("PAPER_LATENCY_MS")); err == nil && ms >= 0 {
+		latency = time.Duration(ms) * time.Millisecond
+	}
+	fillRatio := 1.0
+	if r, err := strconv.ParseFloat(os.Getenv("PAPER_FILL_RATIO"), 64); err == nil {
+		fillRatio = r
+	}
+	exchange := trader.NewPaperExchange(database, kClient, latency, fillRatio)
+	log.Printf("Paper trading enabled (latency %v, fill ratio %.2f)", latency, fillRatio)
+
+	t := trader.NewTrader(database, exchange)
+
+	// 4. Setup Context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -32,16 +60,12 @@ func main() {
 		cancel()
 	}()
 
-	// 3. Listen for Trade Signals
-	log.Println("Waiting for trade signals from Pub/Sub...")
-
-	// TODO: Initialize Redis/NATS Subscriber here
+	// 5. Keep the ledger in line with the exchange after crashes or dropped responses
+	reconciler := trader.NewReconciler(database, kClient, exchange.Ledger())
+	go reconciler.Run(ctx)
 
-	// Keep the service alive
-	<-ctx.Done()
+	// 6. Execute detected opportunities until shutdown
+	log.Printf("Trader polling for opportunities every %v", t.PollInterval)
+	t.Run(ctx)
 	log.Println("Trader service gracefully stopped.")
 }
-
-func executeTrade(opportunityID uint) {
-	// TODO: Implement the algorithm to commit the trade to Kalshi
-}
@@ -0,0 +1,74 @@
+package bff
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs proxies the manager's background jobs with their schedules and latest runs
+func (h *Handler) ListJobs(c *gin.Context) {
+	h.proxyToManager(c, http.MethodGet, "/jobs")
+}
+
+// GetJobRuns proxies a job's recent runs
+func (h *Handler) GetJobRuns(c *gin.Context) {
+	h.proxyToManager(c, http.MethodGet, "/jobs/"+url.PathEscape(c.Param("name"))+"/runs")
+}
+
+// RunJob asks the manager to start a job now
+func (h *Handler) RunJob(c *gin.Context) {
+	h.proxyToManager(c, http.MethodPost, "/jobs/"+url.PathEscape(c.Param("name"))+"/run")
+}
+
+// CancelJob asks the manager to cancel a job's run in progress
+func (h *Handler) CancelJob(c *gin.Context) {
+	h.proxyToManager(c, http.MethodPost, "/jobs/"+url.PathEscape(c.Param("name"))+"/cancel")
+}
+
+// ListSLMCalls proxies the recorded SLM calls, filtered by the query string
+func (h *Handler) ListSLMCalls(c *gin.Context) {
+	h.proxyToManager(c, http.MethodGet, "/slm/calls")
+}
+
+// GetSLMCall proxies one recorded SLM call with the model's replies
+func (h *Handler) GetSLMCall(c *gin.Context) {
+	h.proxyToManager(c, http.MethodGet, "/slm/calls/"+url.PathEscape(c.Param("id")))
+}
+
+// GetSLMStats proxies the SLM service's usage and escalation counts
+func (h *Handler) GetSLMStats(c *gin.Context) {
+	h.proxyToManager(c, http.MethodGet, "/slm/stats")
+}
+
+// proxyToManager forwards the request's query string to a manager endpoint and
+// relays the manager's status and JSON body unchanged
+func (h *Handler) proxyToManager(c *gin.Context, method, path string) {
+	target := h.ManagerURL + path
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, target, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build manager request"})
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to contact manager for %s %s: %v", method, path, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to contact manager"})
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read manager response"})
+		return
+	}
+	c.Data(resp.StatusCode, "application/json", body)
+}
@@ -0,0 +1,219 @@
+package bff
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ledgerFromQuery selects the live or paper ledger from the "ledger" query parameter
+func ledgerFromQuery(c *gin.Context) (string, trader.Ledger, bool) {
+	switch name := c.DefaultQuery("ledger", "live"); name {
+	case "live":
+		return name, trader.LiveLedger, true
+	case "paper":
+		return name, trader.PaperLedger, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ledger must be live or paper"})
+		return "", trader.Ledger{}, false
+	}
+}
+
+// GetEquityCurve returns the balance snapshots for a ledger over the last N days
+func (h *Handler) GetEquityCurve(c *gin.Context) {
+	name, _, ok := ledgerFromQuery(c)
+	if !ok {
+		return
+	}
+
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		if parsed, err := strconv.Atoi(daysStr); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+
+	var snapshots []db.BalanceSnapshot
+	if err := h.DB.Where("ledger = ? AND captured_at >= ?", name, time.Now().AddDate(0, 0, -days)).
+		Order("captured_at ASC").
+		Find(&snapshots).Error; err != nil {
+		log.Println("Error fetching balance snapshots:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch equity curve"})
+		return
+	}
+
+	points := make([]gin.H, len(snapshots))
+	for i, s := range snapshots {
+		points[i] = gin.H{
+			"captured_at":     s.CapturedAt,
+			"provider":        s.Provider,
+			"cash":            s.Cash,
+			"positions_value": s.PositionsValue,
+			"equity":          s.Equity,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ledger": name, "points": points})
+}
+
+// GetPnL returns realized and unrealized P&L for a ledger, in cents
+func (h *Handler) GetPnL(c *gin.Context) {
+	name, ledger, ok := ledgerFromQuery(c)
+	if !ok {
+		return
+	}
+
+	var totals struct {
+		Realized float64
+		Fees     int64
+	}
+	if err := h.DB.Table(ledger.Positions).
+		Select("COALESCE(SUM(realized_pnl), 0) AS realized, COALESCE(SUM(fees_paid), 0) AS fees").
+		Scan(&totals).Error; err != nil {
+		log.Println("Error summing realized P&L:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute P&L"})
+		return
+	}
+
+	positions, err := ledger.MarkPositions(h.DB)
+	if err != nil {
+		log.Println("Error marking positions:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute P&L"})
+		return
+	}
+
+	var unrealized float64
+	for _, p := range positions {
+		unrealized += p.UnrealizedPnL()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ledger":         name,
+		"realized":       totals.Realized,
+		"unrealized":     unrealized,
+		"total":          totals.Realized + unrealized,
+		"fees_paid":      totals.Fees,
+		"open_positions": len(positions),
+		"currency":       "USD", // Amounts in cents
+	})
+}
+
+// GetExposure returns the market value of open positions grouped by category and by provider
+func (h *Handler) GetExposure(c *gin.Context) {
+	name, ledger, ok := ledgerFromQuery(c)
+	if !ok {
+		return
+	}
+
+	positions, err := ledger.MarkPositions(h.DB)
+	if err != nil {
+		log.Println("Error marking positions:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute exposure"})
+		return
+	}
+
+	// Look up the category and provider of every held market
+	tickers := make([]string, len(positions))
+	for i, p := range positions {
+		tickers[i] = p.Ticker
+	}
+
+	var markets []db.Market
+	var providers []db.Provider
+	if len(tickers) > 0 {
+		if err := h.DB.Where("ticker IN ?", tickers).Find(&markets).Error; err != nil {
+			log.Println("Error fetching position markets:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute exposure"})
+			return
+		}
+	}
+	if err := h.DB.Find(&providers).Error; err != nil {
+		log.Println("Error fetching providers:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute exposure"})
+		return
+	}
+
+	providerNames := make(map[uint]string)
+	for _, p := range providers {
+		providerNames[p.ID] = p.Name
+	}
+	marketByTicker := make(map[string]db.Market)
+	for _, m := range markets {
+		marketByTicker[m.Ticker] = m
+	}
+
+	byCategory := make(map[string]float64)
+	byProvider := make(map[string]float64)
+	var total float64
+	for _, p := range positions {
+		category, provider := "Unknown", "kalshi"
+		if m, exists := marketByTicker[p.Ticker]; exists {
+			if m.Category != "" {
+				category = m.Category
+			}
+			if n, found := providerNames[m.ProviderID]; found {
+				provider = n
+			}
+		}
+		byCategory[category] += p.Value()
+		byProvider[provider] += p.Value()
+		total += p.Value()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ledger":      name,
+		"total":       total,
+		"by_category": exposureList(byCategory),
+		"by_provider": exposureList(byProvider),
+	})
+}
+
+func exposureList(values map[string]float64) []gin.H {
+	list := make([]gin.H, 0, len(values))
+	for name, value := range values {
+		list = append(list, gin.H{"name": name, "value": value})
+	}
+	return list
+}
+
+// GetStrategyPerformance returns the win rate and realized P&L of settled opportunities per strategy
+func (h *Handler) GetStrategyPerformance(c *gin.Context) {
+	var rows []struct {
+		StrategyType string
+		Settled      int64
+		Wins         int64
+		RealizedPnL  float64 `gorm:"column:realized_pnl"`
+	}
+	if err := h.DB.Model(&db.ArbitrageOpportunity{}).
+		Select("strategy_type, COUNT(*) AS settled, SUM(CASE WHEN realized_pnl > 0 THEN 1 ELSE 0 END) AS wins, COALESCE(SUM(realized_pnl), 0) AS realized_pnl").
+		Where("settled_at IS NOT NULL").
+		Group("strategy_type").
+		Scan(&rows).Error; err != nil {
+		log.Println("Error computing strategy performance:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute strategy performance"})
+		return
+	}
+
+	strategies := make([]gin.H, len(rows))
+	for i, r := range rows {
+		winRate := 0.0
+		if r.Settled > 0 {
+			winRate = float64(r.Wins) / float64(r.Settled)
+		}
+		strategies[i] = gin.H{
+			"strategy_type": r.StrategyType,
+			"settled":       r.Settled,
+			"wins":          r.Wins,
+			"win_rate":      winRate,
+			"realized_pnl":  r.RealizedPnL,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"strategies": strategies})
+}
@@ -1,16 +1,44 @@
 package db
 
 import (
-	"log"
 	"os"
 	"strings"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// Connect now reads directly from the environment and loads the local vector extension
+// Connect opens the database and verifies its schema is up to date.
+// Schema changes are applied separately by the migrate command.
 func Connect() (*gorm.DB, error) {
+	db, err := Open()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkMigrations(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Supported database dialects, as reported by the gorm dialector
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// Dialect returns which database backend a connection uses
+func Dialect(database *gorm.DB) string {
+	return database.Dialector.Name()
+}
+
+// Open reads the connection string directly from the environment and opens the database
+// without checking its schema. A postgres:// or postgresql:// URL selects Postgres,
+// anything else is treated as a SQLite file.
+func Open() (*gorm.DB, error) {
 	// 1. Get the connection string from the .env (via os.Getenv)
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
@@ -20,30 +48,13 @@ func Connect() (*gorm.DB, error) {
 		// Clean up if needed, though usually fine
 	}
 
-	// 2. Open the connection using standard gorm sqlite driver (CGO)
-	// The vector extension is already registered globally via sqlite_vec.Auto() in main.go
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return nil, err
-	}
-
-	// 3. Create the virtual table for embeddings if it doesn't exist
-	// We use `vec0` from sqlite-vec.
-	// `id` is an INTEGER PRIMARY KEY to map 1:1 with our `markets` table.
-	// `embedding` is a float array of size 384 (for all-MiniLM-L6-v2).
-	if err := db.Exec(`
-		CREATE VIRTUAL TABLE IF NOT EXISTS vec_markets USING vec0(
-			embedding FLOAT[384]
-		);
-	`).Error; err != nil {
-		log.Printf("Warning: Failed to create virtual table vec_markets: %v", err)
+	// 2. Postgres (pgvector provides vec_markets)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	}
 
-	// 4. Auto-migrate the schemas
-	err = db.AutoMigrate(&Provider{}, &Market{}, &Event{}, &ArbitrageOpportunity{})
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
+	// 3. Open the connection using standard gorm sqlite driver (CGO)
+	// The vector extension is registered globally via sqlite_vec.Auto() in main.go
+	// by services that query vec_markets
+	return gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 }
@@ -0,0 +1,11 @@
+//go:build !sqlite_fts5
+
+package db
+
+// The SQLite migrations create an FTS5 table for market search, and go-sqlite3 only
+// compiles FTS5 in with the sqlite_fts5 build tag. Without it every SQLite database
+// fails to migrate with "no such module: fts5", so refuse to build at all. Build and
+// test with the tag, as dev.sh and the Dockerfile do:
+//
+//	export GOFLAGS=-tags=sqlite_fts5
+var _ = build_with_tag_sqlite_fts5
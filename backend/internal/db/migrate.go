@@ -0,0 +1,298 @@
+package db
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations are embedded SQL files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, with one directory per dialect. Versions are applied
+// in ascending order and an applied migration must never be edited: its checksum
+// is verified on every run. Both dialects should keep the same version numbers.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationState pairs a known migration with when it was applied, if ever
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations parses the embedded migration files for a dialect, ordered by version
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", file)
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", file)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", file, err)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d used by both %s and %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrationStatus reports every known migration and whether it has been applied.
+// It fails if an applied migration was modified or is unknown to this build.
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	migrations, err := LoadMigrations(Dialect(db))
+	if err != nil {
+		return nil, err
+	}
+
+	// Postgres has no datetime type
+	timestampType := "datetime"
+	if Dialect(db) == DialectPostgres {
+		timestampType = "timestamptz"
+	}
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text,
+			checksum text,
+			applied_at ` + timestampType + `
+		)
+	`).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	var applied []SchemaMigration
+	if err := db.Order("version").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+
+	appliedByVersion := make(map[int]SchemaMigration, len(applied))
+	for _, a := range applied {
+		appliedByVersion[a.Version] = a
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i] = MigrationState{Migration: m}
+		a, ok := appliedByVersion[m.Version]
+		if !ok {
+			continue
+		}
+		if a.Checksum != m.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was modified after being applied (checksum %s, expected %s)",
+				m.Version, m.Name, m.Checksum, a.Checksum)
+		}
+		appliedAt := a.AppliedAt
+		states[i].AppliedAt = &appliedAt
+		delete(appliedByVersion, m.Version)
+	}
+
+	for version, a := range appliedByVersion {
+		return nil, fmt.Errorf("database has migration %d_%s applied which this build does not know about", version, a.Name)
+	}
+
+	return states, nil
+}
+
+// MigrateUp applies every pending migration in order, each in its own transaction.
+// Returns the migrations that were applied.
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, s := range states {
+		if s.AppliedAt != nil {
+			continue
+		}
+		m := s.Migration
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if m.Version == 1 {
+				if err := adoptBaseline(tx); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				Checksum:  m.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// baselineColumns are the columns the initial schema has beyond those gorm
+// AutoMigrate created before migrations existed. CREATE TABLE IF NOT EXISTS leaves
+// such a database's tables alone, so adopting it adds them first.
+var baselineColumns = []struct {
+	table, column    string
+	sqlite, postgres string
+}{
+	{"providers", "last_settlement_sync", "datetime", "timestamptz"},
+	{"markets", "series_ticker", "text", "text"},
+	{"markets", "fee_waiver_expires_at", "datetime", "timestamptz"},
+	{"markets", "close_time", "datetime", "timestamptz"},
+	{"markets", "result", "text", "text"},
+	{"markets", "settlement_value", "integer", "integer"},
+	{"markets", "settled_at", "datetime", "timestamptz"},
+	{"arbitrage_opportunities", "realized_pnl", "real", "double precision"},
+	{"arbitrage_opportunities", "settled_at", "datetime", "timestamptz"},
+}
+
+// adoptBaseline adds any baseline column missing from a table that already exists
+func adoptBaseline(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	for _, c := range baselineColumns {
+		if !migrator.HasTable(c.table) || migrator.HasColumn(c.table, c.column) {
+			continue
+		}
+		columnType := c.sqlite
+		if Dialect(tx) == DialectPostgres {
+			columnType = c.postgres
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, columnType)).Error; err != nil {
+			return fmt.Errorf("failed to add %s.%s to the existing schema: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown rolls back the most recently applied migrations, newest first.
+// Returns the migrations that were rolled back.
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	for i := len(states) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		if states[i].AppliedAt == nil {
+			continue
+		}
+		m := states[i].Migration
+		if m.Down == "" {
+			return rolledBack, fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		rolledBack = append(rolledBack, m)
+	}
+	return rolledBack, nil
+}
+
+// checkMigrations fails unless every known migration has been applied unmodified
+func checkMigrations(db *gorm.DB) error {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("database schema is out of date (%d pending migrations); run the migrate command", pending)
+	}
+	return nil
+}
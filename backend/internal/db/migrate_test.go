@@ -0,0 +1,197 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	sqlite_vec.Auto()
+	os.Exit(m.Run())
+}
+
+// forEachDialect runs a test against a fresh SQLite database, and against Postgres
+// when TEST_POSTGRES_URL points at a scratch database with pgvector available. The
+// Postgres database is expected to be empty and is left empty.
+func forEachDialect(t *testing.T, test func(t *testing.T, database *gorm.DB)) {
+	dialects := []struct {
+		name string
+		dsn  string
+	}{
+		{DialectSQLite, filepath.Join(t.TempDir(), "migrate.db")},
+		{DialectPostgres, os.Getenv("TEST_POSTGRES_URL")},
+	}
+
+	for _, d := range dialects {
+		t.Run(d.name, func(t *testing.T) {
+			if d.dsn == "" {
+				t.Skip("TEST_POSTGRES_URL not set")
+			}
+			t.Setenv("DATABASE_URL", d.dsn)
+			database, err := Open()
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if got := Dialect(database); got != d.name {
+				t.Fatalf("dialect = %s, want %s", got, d.name)
+			}
+			if d.name == DialectPostgres {
+				t.Cleanup(func() { resetPostgres(t, database) })
+			}
+			test(t, database)
+		})
+	}
+}
+
+// resetPostgres drops everything the tests created in the public schema
+func resetPostgres(t *testing.T, database *gorm.DB) {
+	if err := database.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public").Error; err != nil {
+		t.Errorf("cleanup: %v", err)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, database *gorm.DB) {
+		migrations, err := LoadMigrations(Dialect(database))
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+
+		// 1. Everything applies to an empty database
+		applied, err := MigrateUp(database)
+		if err != nil {
+			t.Fatalf("up: %v", err)
+		}
+		if len(applied) != len(migrations) {
+			t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+		}
+		if err := checkMigrations(database); err != nil {
+			t.Fatalf("check after up: %v", err)
+		}
+
+		// 2. A second run is a no-op
+		applied, err = MigrateUp(database)
+		if err != nil {
+			t.Fatalf("second up: %v", err)
+		}
+		if len(applied) != 0 {
+			t.Fatalf("second up applied %d migrations", len(applied))
+		}
+
+		// 3. Everything rolls back, and applies again
+		rolledBack, err := MigrateDown(database, len(migrations))
+		if err != nil {
+			t.Fatalf("down: %v", err)
+		}
+		if len(rolledBack) != len(migrations) {
+			t.Fatalf("rolled back %d migrations, want %d", len(rolledBack), len(migrations))
+		}
+		if err := checkMigrations(database); err == nil {
+			t.Fatal("check passed with every migration rolled back")
+		}
+		if _, err := MigrateUp(database); err != nil {
+			t.Fatalf("up after down: %v", err)
+		}
+	})
+}
+
+// The tables as gorm AutoMigrate created them before migrations existed
+type baselineProvider struct {
+	ID            uint   `gorm:"primaryKey"`
+	Name          string `gorm:"uniqueIndex;not null"`
+	IsActive      bool   `gorm:"default:true"`
+	LastEventSync time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type baselineMarket struct {
+	ID             uint   `gorm:"primaryKey"`
+	ProviderID     uint   `gorm:"not null;uniqueIndex:idx_provider_market"`
+	ExternalID     string `gorm:"uniqueIndex:idx_provider_market"`
+	Ticker         string `gorm:"index"`
+	EventTicker    string `gorm:"index"`
+	Title          string
+	Description    string
+	YesSubTitle    string
+	NoSubTitle     string
+	Status         string `gorm:"default:'active'"`
+	Category       string
+	LastDataUpdate time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type baselineOpportunity struct {
+	ID              uint   `gorm:"primaryKey"`
+	MarketID        uint   `gorm:"index"`
+	StrategyType    string `gorm:"index"`
+	BuyPrice        float64
+	SellPrice       float64
+	ExpectedYield   float64 `gorm:"index"`
+	PotentialProfit float64
+	RequiredCapital float64
+	Status          string    `gorm:"default:'detected'"`
+	DetectedAt      time.Time `gorm:"autoCreateTime"`
+	ExpiresAt       time.Time
+}
+
+func (baselineProvider) TableName() string    { return "providers" }
+func (baselineMarket) TableName() string      { return "markets" }
+func (baselineOpportunity) TableName() string { return "arbitrage_opportunities" }
+
+func TestMigrateUpAdoptsBaseline(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, database *gorm.DB) {
+		if err := database.AutoMigrate(&baselineProvider{}, &baselineMarket{}, &baselineOpportunity{}); err != nil {
+			t.Fatalf("baseline: %v", err)
+		}
+		provider := baselineProvider{Name: "kalshi"}
+		database.Create(&provider)
+		database.Create(&baselineMarket{ProviderID: provider.ID, ExternalID: "KXFED-26DEC-T4.00", Ticker: "KXFED-26DEC-T4.00"})
+
+		if _, err := MigrateUp(database); err != nil {
+			t.Fatalf("up: %v", err)
+		}
+
+		migrator := database.Migrator()
+		for _, c := range baselineColumns {
+			if !migrator.HasColumn(c.table, c.column) {
+				t.Errorf("%s.%s was not added", c.table, c.column)
+			}
+		}
+
+		// Existing rows survive and the full model can be read back
+		var market Market
+		if err := database.Where("ticker = ?", "KXFED-26DEC-T4.00").First(&market).Error; err != nil {
+			t.Fatalf("read market: %v", err)
+		}
+		if market.SeriesTicker != "" || market.SettlementValue != 0 {
+			t.Errorf("new columns should be empty: %+v", market)
+		}
+	})
+}
+
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	sqlite, err := LoadMigrations(DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := LoadMigrations(DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("%d sqlite migrations, %d postgres", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d: sqlite %d_%s, postgres %d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+-- Baseline schema, mirroring migrations/sqlite/0001_initial_schema.up.sql.
+
+CREATE TABLE IF NOT EXISTS providers (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    is_active boolean DEFAULT true,
+    last_event_sync timestamptz,
+    last_settlement_sync timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_providers_name ON providers(name);
+
+CREATE TABLE IF NOT EXISTS markets (
+    id bigserial PRIMARY KEY,
+    provider_id bigint NOT NULL,
+    external_id text,
+    ticker text,
+    event_ticker text,
+    title text,
+    description text,
+    yes_sub_title text,
+    no_sub_title text,
+    status text DEFAULT 'active',
+    category text,
+    series_ticker text,
+    fee_waiver_expires_at timestamptz,
+    close_time timestamptz,
+    result text,
+    settlement_value integer,
+    settled_at timestamptz,
+    last_data_update timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_providers_markets FOREIGN KEY (provider_id) REFERENCES providers(id)
+);
+CREATE INDEX IF NOT EXISTS idx_markets_event_ticker ON markets(event_ticker);
+CREATE INDEX IF NOT EXISTS idx_markets_ticker ON markets(ticker);
+
+CREATE TABLE IF NOT EXISTS events (
+    provider_id bigint NOT NULL,
+    external_id text NOT NULL,
+    title text,
+    subtitle text,
+    category text,
+    mutually_exclusive boolean,
+    series_ticker text,
+    strike_period text,
+    expiration_time timestamptz,
+    closest_market_close_time timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_event ON events(provider_id, external_id);
+
+CREATE TABLE IF NOT EXISTS arbitrage_opportunities (
+    id bigserial PRIMARY KEY,
+    market_id bigint,
+    strategy_type text,
+    buy_price double precision,
+    sell_price double precision,
+    expected_yield double precision,
+    potential_profit double precision,
+    required_capital double precision,
+    status text DEFAULT 'detected',
+    detected_at timestamptz,
+    expires_at timestamptz,
+    realized_pnl double precision,
+    settled_at timestamptz,
+    CONSTRAINT fk_arbitrage_opportunities_market FOREIGN KEY (market_id) REFERENCES markets(id)
+);
+CREATE INDEX IF NOT EXISTS idx_arbitrage_opportunities_expected_yield ON arbitrage_opportunities(expected_yield);
+CREATE INDEX IF NOT EXISTS idx_arbitrage_opportunities_strategy_type ON arbitrage_opportunities(strategy_type);
+CREATE INDEX IF NOT EXISTS idx_arbitrage_opportunities_market_id ON arbitrage_opportunities(market_id);
+
+CREATE TABLE IF NOT EXISTS opportunity_legs (
+    id bigserial PRIMARY KEY,
+    opportunity_id bigint NOT NULL,
+    market_id bigint,
+    ticker text NOT NULL,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    CONSTRAINT fk_arbitrage_opportunities_legs FOREIGN KEY (opportunity_id) REFERENCES arbitrage_opportunities(id)
+);
+CREATE INDEX IF NOT EXISTS idx_opportunity_legs_market_id ON opportunity_legs(market_id);
+CREATE INDEX IF NOT EXISTS idx_opportunity_legs_opportunity_id ON opportunity_legs(opportunity_id);
+
+CREATE TABLE IF NOT EXISTS orderbook_snapshots (
+    id bigserial PRIMARY KEY,
+    ticker text,
+    yes text,
+    no text,
+    captured_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_orderbook_ticker_time ON orderbook_snapshots(ticker, captured_at);
+
+-- Live and paper ledgers share a layout
+CREATE TABLE IF NOT EXISTS orders (
+    id bigserial PRIMARY KEY,
+    opportunity_id bigint,
+    client_order_id text,
+    external_id text,
+    ticker text NOT NULL,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    filled_count integer,
+    status text DEFAULT 'pending',
+    error text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_orders_ticker ON orders(ticker);
+CREATE INDEX IF NOT EXISTS idx_orders_external_id ON orders(external_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_client_order_id ON orders(client_order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_opportunity_id ON orders(opportunity_id);
+
+CREATE TABLE IF NOT EXISTS fills (
+    id bigserial PRIMARY KEY,
+    order_id bigint,
+    external_id text,
+    ticker text,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    is_taker boolean,
+    fee integer,
+    filled_at timestamptz,
+    realized_pnl double precision,
+    settled boolean
+);
+CREATE INDEX IF NOT EXISTS idx_fills_settled ON fills(settled);
+CREATE INDEX IF NOT EXISTS idx_fills_ticker ON fills(ticker);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fills_external_id ON fills(external_id);
+CREATE INDEX IF NOT EXISTS idx_fills_order_id ON fills(order_id);
+
+CREATE TABLE IF NOT EXISTS positions (
+    id bigserial PRIMARY KEY,
+    ticker text NOT NULL,
+    side text NOT NULL,
+    count integer,
+    avg_price double precision,
+    realized_pnl double precision,
+    fees_paid integer,
+    status text DEFAULT 'open',
+    opened_at timestamptz,
+    closed_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_positions_ticker_side ON positions(ticker, side);
+
+CREATE TABLE IF NOT EXISTS paper_orders (
+    id bigserial PRIMARY KEY,
+    opportunity_id bigint,
+    client_order_id text,
+    external_id text,
+    ticker text NOT NULL,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    filled_count integer,
+    status text DEFAULT 'pending',
+    error text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_paper_orders_ticker ON paper_orders(ticker);
+CREATE INDEX IF NOT EXISTS idx_paper_orders_external_id ON paper_orders(external_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_orders_client_order_id ON paper_orders(client_order_id);
+CREATE INDEX IF NOT EXISTS idx_paper_orders_opportunity_id ON paper_orders(opportunity_id);
+
+CREATE TABLE IF NOT EXISTS paper_fills (
+    id bigserial PRIMARY KEY,
+    order_id bigint,
+    external_id text,
+    ticker text,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    is_taker boolean,
+    fee integer,
+    filled_at timestamptz,
+    realized_pnl double precision,
+    settled boolean
+);
+CREATE INDEX IF NOT EXISTS idx_paper_fills_settled ON paper_fills(settled);
+CREATE INDEX IF NOT EXISTS idx_paper_fills_ticker ON paper_fills(ticker);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_fills_external_id ON paper_fills(external_id);
+CREATE INDEX IF NOT EXISTS idx_paper_fills_order_id ON paper_fills(order_id);
+
+CREATE TABLE IF NOT EXISTS paper_positions (
+    id bigserial PRIMARY KEY,
+    ticker text NOT NULL,
+    side text NOT NULL,
+    count integer,
+    avg_price double precision,
+    realized_pnl double precision,
+    fees_paid integer,
+    status text DEFAULT 'open',
+    opened_at timestamptz,
+    closed_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_positions_ticker_side ON paper_positions(ticker, side);
+
+CREATE TABLE IF NOT EXISTS settlements (
+    id bigserial PRIMARY KEY,
+    ticker text NOT NULL,
+    market_result text,
+    yes_count integer,
+    no_count integer,
+    revenue integer,
+    settled_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_settlements_ticker ON settlements(ticker);
+
+CREATE TABLE IF NOT EXISTS market_implications (
+    id bigserial PRIMARY KEY,
+    source_ticker text NOT NULL,
+    target_ticker text NOT NULL,
+    source_outcome text NOT NULL,
+    target_outcome text,
+    reason text,
+    verdict text,
+    evaluated_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_market_implications_verdict ON market_implications(verdict);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_implication_pair ON market_implications(source_ticker, target_ticker, source_outcome);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    id bigserial PRIMARY KEY,
+    provider text,
+    ledger text,
+    cash bigint,
+    positions_value bigint,
+    equity bigint,
+    captured_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_balance_snapshot ON balance_snapshots(provider, ledger, captured_at);
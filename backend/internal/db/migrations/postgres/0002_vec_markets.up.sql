@@ -0,0 +1,10 @@
+-- Market embeddings for semantic search (pgvector).
+-- market_id maps 1:1 to markets.id; 384 dimensions matches all-MiniLM-L6-v2.
+-- Distances use L2 to match the sqlite-vec default.
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS vec_markets (
+    market_id bigint PRIMARY KEY,
+    embedding vector(384) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vec_markets_embedding ON vec_markets USING hnsw (embedding vector_l2_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_provider_market;
+CREATE INDEX idx_provider_market ON markets(provider_id, external_id);
@@ -0,0 +1,3 @@
+-- Markets are upserted on (provider_id, external_id), which requires a unique index.
+DROP INDEX IF EXISTS idx_provider_market;
+CREATE UNIQUE INDEX idx_provider_market ON markets(provider_id, external_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_markets_category;
+DROP INDEX IF EXISTS idx_markets_status_close_time;
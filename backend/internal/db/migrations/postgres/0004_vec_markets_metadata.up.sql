@@ -0,0 +1,3 @@
+-- pgvector filters by joining markets, so index the columns filtered searches use
+CREATE INDEX IF NOT EXISTS idx_markets_status_close_time ON markets(status, close_time);
+CREATE INDEX IF NOT EXISTS idx_markets_category ON markets(category);
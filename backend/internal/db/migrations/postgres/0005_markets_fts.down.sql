@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_markets_search_vector;
+ALTER TABLE markets DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE markets DROP COLUMN IF EXISTS rules;
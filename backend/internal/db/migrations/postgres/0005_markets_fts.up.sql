@@ -0,0 +1,14 @@
+-- Resolution rules are searchable text too
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS rules text;
+
+-- Full-text index for lexical market search, weighted like the SQLite BM25 columns.
+-- Tickers use the 'simple' configuration so they are matched verbatim.
+ALTER TABLE markets ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(ticker, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(yes_sub_title, '') || ' ' || coalesce(no_sub_title, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(rules, '')), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_markets_search_vector ON markets USING gin (search_vector);
@@ -0,0 +1,3 @@
+-- Vector tables of indexes other than vec_markets are left in place.
+DROP TABLE IF EXISTS market_embeddings;
+DROP TABLE IF EXISTS embedding_indexes;
@@ -0,0 +1,29 @@
+-- Version the embedding index. Each version has its own vector table and records the
+-- model, dimension and text template version its vectors were built with. A new
+-- version is built in the background ('building') and swapped in by marking it
+-- 'active' and the previous one 'retired', so searches keep working during a re-index.
+CREATE TABLE embedding_indexes (
+    id bigserial PRIMARY KEY,
+    vector_table text NOT NULL,
+    model text NOT NULL,
+    dimension bigint NOT NULL,
+    template_version bigint NOT NULL,
+    status text NOT NULL,
+    created_at timestamptz,
+    activated_at timestamptz
+);
+CREATE INDEX idx_embedding_indexes_status ON embedding_indexes(status);
+
+-- vec_markets was created for all-MiniLM-L6-v2
+INSERT INTO embedding_indexes(vector_table, model, dimension, template_version, status, created_at, activated_at)
+VALUES ('vec_markets', 'sentence-transformers/all-MiniLM-L6-v2', 384, 1, 'active', now(), now());
+
+-- Hash of the text each vector was built from, per index, so unchanged markets
+-- are not re-embedded on every sync
+CREATE TABLE market_embeddings (
+    index_id bigint NOT NULL,
+    market_id bigint NOT NULL,
+    text_hash text NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY (index_id, market_id)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE markets DROP COLUMN IF EXISTS liquidity;
+ALTER TABLE markets DROP COLUMN IF EXISTS open_interest;
+ALTER TABLE markets DROP COLUMN IF EXISTS volume24h;
+ALTER TABLE markets DROP COLUMN IF EXISTS last_price;
+ALTER TABLE markets DROP COLUMN IF EXISTS no_ask;
+ALTER TABLE markets DROP COLUMN IF EXISTS no_bid;
+ALTER TABLE markets DROP COLUMN IF EXISTS yes_ask;
+ALTER TABLE markets DROP COLUMN IF EXISTS yes_bid;
+
+ALTER TABLE providers DROP COLUMN IF EXISTS market_watermark;
+ALTER TABLE providers DROP COLUMN IF EXISTS last_full_event_sync;
+ALTER TABLE providers DROP COLUMN IF EXISTS event_sync_cursor;
@@ -0,0 +1,16 @@
+-- Incremental event sync state. event_sync_cursor checkpoints an unfinished full crawl
+-- so it resumes where it stopped; market_watermark is the time up to which changed
+-- markets have been pulled.
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS event_sync_cursor text NOT NULL DEFAULT '';
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS last_full_event_sync timestamptz;
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS market_watermark timestamptz;
+
+-- Latest quotes and activity from the market refresh loop. Prices are cents.
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS yes_bid bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS yes_ask bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS no_bid bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS no_ask bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS last_price bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS volume24h bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS open_interest bigint NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS liquidity bigint NOT NULL DEFAULT 0;
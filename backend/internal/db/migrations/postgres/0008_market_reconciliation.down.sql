@@ -0,0 +1,2 @@
+ALTER TABLE events DROP COLUMN IF EXISTS status;
+ALTER TABLE providers DROP COLUMN IF EXISTS event_sync_started_at;
@@ -0,0 +1,2 @@
+DROP TABLE comparison_pairs;
+DROP TABLE comparison_cycles;
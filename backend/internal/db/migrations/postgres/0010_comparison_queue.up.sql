@@ -0,0 +1,32 @@
+-- Related market analysis runs in cycles. A cycle first plans the market pairs worth
+-- comparing, then works through them in priority order until it runs out of pairs
+-- or budget. Both are persisted so a cycle interrupted by a restart resumes.
+CREATE TABLE comparison_cycles (
+    id bigserial PRIMARY KEY,
+    status text NOT NULL,
+    pairs bigint NOT NULL DEFAULT 0,
+    compared bigint NOT NULL DEFAULT 0,
+    failed bigint NOT NULL DEFAULT 0,
+    tokens_used bigint NOT NULL DEFAULT 0,
+    elapsed_ms bigint NOT NULL DEFAULT 0,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz
+);
+CREATE INDEX idx_comparison_cycles_status ON comparison_cycles(status);
+
+CREATE TABLE comparison_pairs (
+    id bigserial PRIMARY KEY,
+    cycle_id bigint NOT NULL,
+    source_ticker text NOT NULL,
+    target_ticker text NOT NULL,
+    source_close_time timestamptz,
+    target_close_time timestamptz,
+    search_rank bigint NOT NULL DEFAULT 0,
+    priority double precision NOT NULL,
+    status text NOT NULL,
+    error text NOT NULL DEFAULT '',
+    tokens bigint NOT NULL DEFAULT 0,
+    processed_at timestamptz
+);
+CREATE UNIQUE INDEX idx_comparison_pairs_pair ON comparison_pairs(cycle_id, source_ticker, target_ticker);
+CREATE INDEX idx_comparison_pairs_queue ON comparison_pairs(cycle_id, status, priority);
@@ -0,0 +1,5 @@
+ALTER TABLE comparison_pairs DROP COLUMN engine;
+ALTER TABLE market_implications DROP COLUMN engine;
+ALTER TABLE markets DROP COLUMN cap_strike;
+ALTER TABLE markets DROP COLUMN floor_strike;
+ALTER TABLE markets DROP COLUMN strike_type;
@@ -0,0 +1,9 @@
+-- Structured strikes let ladders of numeric markets be related without the SLM:
+-- strike_type is greater, greater_or_equal, less, less_or_equal, between, ...
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS strike_type text NOT NULL DEFAULT '';
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS floor_strike double precision;
+ALTER TABLE markets ADD COLUMN IF NOT EXISTS cap_strike double precision;
+
+-- Which engine found an implication or compared a pair: rules or slm
+ALTER TABLE market_implications ADD COLUMN IF NOT EXISTS engine text NOT NULL DEFAULT 'slm';
+ALTER TABLE comparison_pairs ADD COLUMN IF NOT EXISTS engine text NOT NULL DEFAULT '';
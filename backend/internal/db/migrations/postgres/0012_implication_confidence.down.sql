@@ -0,0 +1 @@
+ALTER TABLE market_implications DROP COLUMN confidence;
@@ -0,0 +1 @@
+DROP TABLE slm_calls;
@@ -0,0 +1,25 @@
+-- One row per comparison asked of an SLM backend, every turn included, so any
+-- implication can be traced to the answers behind it. Prompts are re-rendered from
+-- prompt_version and markets, a snapshot of the two markets' text, and checked
+-- against prompt_hash; the text itself is only kept with SLM_LOG=full.
+CREATE TABLE slm_calls (
+    id bigserial PRIMARY KEY,
+    source_ticker text NOT NULL,
+    target_ticker text NOT NULL,
+    prompt_version text NOT NULL DEFAULT '',
+    prompt_hash text NOT NULL DEFAULT '',
+    prompt text NOT NULL DEFAULT '',
+    markets text NOT NULL DEFAULT '',
+    backend text NOT NULL DEFAULT '',
+    model text NOT NULL DEFAULT '',
+    temperature double precision NOT NULL DEFAULT 0,
+    latency_ms bigint NOT NULL DEFAULT 0,
+    tokens bigint NOT NULL DEFAULT 0,
+    attempts bigint NOT NULL DEFAULT 0,
+    output text NOT NULL DEFAULT '[]',
+    result text NOT NULL DEFAULT '',
+    error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL
+);
+CREATE INDEX idx_slm_calls_pair ON slm_calls(source_ticker, target_ticker);
+CREATE INDEX idx_slm_calls_created_at ON slm_calls(created_at);
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS balance_snapshots;
+DROP TABLE IF EXISTS market_implications;
+DROP TABLE IF EXISTS settlements;
+DROP TABLE IF EXISTS paper_positions;
+DROP TABLE IF EXISTS paper_fills;
+DROP TABLE IF EXISTS paper_orders;
+DROP TABLE IF EXISTS positions;
+DROP TABLE IF EXISTS fills;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS orderbook_snapshots;
+DROP TABLE IF EXISTS opportunity_legs;
+DROP TABLE IF EXISTS arbitrage_opportunities;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS markets;
+DROP TABLE IF EXISTS providers;
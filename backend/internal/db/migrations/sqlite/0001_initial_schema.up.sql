@@ -0,0 +1,249 @@
+-- Baseline schema. Every statement is idempotent so databases previously
+-- created by gorm AutoMigrate can adopt the migration history in place.
+
+CREATE TABLE IF NOT EXISTS providers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    is_active numeric DEFAULT true,
+    last_event_sync datetime,
+    last_settlement_sync datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_providers_name ON providers(name);
+
+CREATE TABLE IF NOT EXISTS markets (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    provider_id integer NOT NULL,
+    external_id text,
+    ticker text,
+    event_ticker text,
+    title text,
+    description text,
+    yes_sub_title text,
+    no_sub_title text,
+    status text DEFAULT 'active',
+    category text,
+    series_ticker text,
+    fee_waiver_expires_at datetime,
+    close_time datetime,
+    result text,
+    settlement_value integer,
+    settled_at datetime,
+    last_data_update datetime,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_providers_markets FOREIGN KEY (provider_id) REFERENCES providers(id)
+);
+CREATE INDEX IF NOT EXISTS idx_markets_event_ticker ON markets(event_ticker);
+CREATE INDEX IF NOT EXISTS idx_markets_ticker ON markets(ticker);
+
+CREATE TABLE IF NOT EXISTS events (
+    provider_id integer NOT NULL,
+    external_id text NOT NULL,
+    title text,
+    subtitle text,
+    category text,
+    mutually_exclusive numeric,
+    series_ticker text,
+    strike_period text,
+    expiration_time datetime,
+    closest_market_close_time datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_event ON events(provider_id, external_id);
+
+CREATE TABLE IF NOT EXISTS arbitrage_opportunities (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    market_id integer,
+    strategy_type text,
+    buy_price real,
+    sell_price real,
+    expected_yield real,
+    potential_profit real,
+    required_capital real,
+    status text DEFAULT 'detected',
+    detected_at datetime,
+    expires_at datetime,
+    realized_pnl real,
+    settled_at datetime,
+    CONSTRAINT fk_arbitrage_opportunities_market FOREIGN KEY (market_id) REFERENCES markets(id)
+);
+CREATE INDEX IF NOT EXISTS idx_arbitrage_opportunities_expected_yield ON arbitrage_opportunities(expected_yield);
+CREATE INDEX IF NOT EXISTS idx_arbitrage_opportunities_strategy_type ON arbitrage_opportunities(strategy_type);
+CREATE INDEX IF NOT EXISTS idx_arbitrage_opportunities_market_id ON arbitrage_opportunities(market_id);
+
+CREATE TABLE IF NOT EXISTS opportunity_legs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    opportunity_id integer NOT NULL,
+    market_id integer,
+    ticker text NOT NULL,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    CONSTRAINT fk_arbitrage_opportunities_legs FOREIGN KEY (opportunity_id) REFERENCES arbitrage_opportunities(id)
+);
+CREATE INDEX IF NOT EXISTS idx_opportunity_legs_market_id ON opportunity_legs(market_id);
+CREATE INDEX IF NOT EXISTS idx_opportunity_legs_opportunity_id ON opportunity_legs(opportunity_id);
+
+CREATE TABLE IF NOT EXISTS orderbook_snapshots (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ticker text,
+    yes text,
+    no text,
+    captured_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_orderbook_ticker_time ON orderbook_snapshots(ticker, captured_at);
+
+-- Live and paper ledgers share a layout
+CREATE TABLE IF NOT EXISTS orders (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    opportunity_id integer,
+    client_order_id text,
+    external_id text,
+    ticker text NOT NULL,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    filled_count integer,
+    status text DEFAULT 'pending',
+    error text,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_orders_ticker ON orders(ticker);
+CREATE INDEX IF NOT EXISTS idx_orders_external_id ON orders(external_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_client_order_id ON orders(client_order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_opportunity_id ON orders(opportunity_id);
+
+CREATE TABLE IF NOT EXISTS fills (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    order_id integer,
+    external_id text,
+    ticker text,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    is_taker numeric,
+    fee integer,
+    filled_at datetime,
+    realized_pnl real,
+    settled numeric
+);
+CREATE INDEX IF NOT EXISTS idx_fills_settled ON fills(settled);
+CREATE INDEX IF NOT EXISTS idx_fills_ticker ON fills(ticker);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fills_external_id ON fills(external_id);
+CREATE INDEX IF NOT EXISTS idx_fills_order_id ON fills(order_id);
+
+CREATE TABLE IF NOT EXISTS positions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ticker text NOT NULL,
+    side text NOT NULL,
+    count integer,
+    avg_price real,
+    realized_pnl real,
+    fees_paid integer,
+    status text DEFAULT 'open',
+    opened_at datetime,
+    closed_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_positions_ticker_side ON positions(ticker, side);
+
+CREATE TABLE IF NOT EXISTS paper_orders (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    opportunity_id integer,
+    client_order_id text,
+    external_id text,
+    ticker text NOT NULL,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    filled_count integer,
+    status text DEFAULT 'pending',
+    error text,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_paper_orders_ticker ON paper_orders(ticker);
+CREATE INDEX IF NOT EXISTS idx_paper_orders_external_id ON paper_orders(external_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_orders_client_order_id ON paper_orders(client_order_id);
+CREATE INDEX IF NOT EXISTS idx_paper_orders_opportunity_id ON paper_orders(opportunity_id);
+
+CREATE TABLE IF NOT EXISTS paper_fills (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    order_id integer,
+    external_id text,
+    ticker text,
+    side text,
+    action text,
+    price integer,
+    count integer,
+    is_taker numeric,
+    fee integer,
+    filled_at datetime,
+    realized_pnl real,
+    settled numeric
+);
+CREATE INDEX IF NOT EXISTS idx_paper_fills_settled ON paper_fills(settled);
+CREATE INDEX IF NOT EXISTS idx_paper_fills_ticker ON paper_fills(ticker);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_fills_external_id ON paper_fills(external_id);
+CREATE INDEX IF NOT EXISTS idx_paper_fills_order_id ON paper_fills(order_id);
+
+CREATE TABLE IF NOT EXISTS paper_positions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ticker text NOT NULL,
+    side text NOT NULL,
+    count integer,
+    avg_price real,
+    realized_pnl real,
+    fees_paid integer,
+    status text DEFAULT 'open',
+    opened_at datetime,
+    closed_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_positions_ticker_side ON paper_positions(ticker, side);
+
+CREATE TABLE IF NOT EXISTS settlements (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ticker text NOT NULL,
+    market_result text,
+    yes_count integer,
+    no_count integer,
+    revenue integer,
+    settled_at datetime,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_settlements_ticker ON settlements(ticker);
+
+CREATE TABLE IF NOT EXISTS market_implications (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    source_ticker text NOT NULL,
+    target_ticker text NOT NULL,
+    source_outcome text NOT NULL,
+    target_outcome text,
+    reason text,
+    verdict text,
+    evaluated_at datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_market_implications_verdict ON market_implications(verdict);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_implication_pair ON market_implications(source_ticker, target_ticker, source_outcome);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    provider text,
+    ledger text,
+    cash integer,
+    positions_value integer,
+    equity integer,
+    captured_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_balance_snapshot ON balance_snapshots(provider, ledger, captured_at);
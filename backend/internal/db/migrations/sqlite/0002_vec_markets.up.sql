@@ -0,0 +1,5 @@
+-- Market embeddings for semantic search (sqlite-vec).
+-- The rowid maps 1:1 to markets.id; 384 dimensions matches all-MiniLM-L6-v2.
+CREATE VIRTUAL TABLE IF NOT EXISTS vec_markets USING vec0(
+    embedding FLOAT[384]
+);
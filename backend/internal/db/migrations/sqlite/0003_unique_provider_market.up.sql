@@ -0,0 +1,4 @@
+-- Markets are upserted on (provider_id, external_id), which requires a unique index.
+-- Older databases were created with a plain index here.
+DROP INDEX IF EXISTS idx_provider_market;
+CREATE UNIQUE INDEX idx_provider_market ON markets(provider_id, external_id);
@@ -0,0 +1,12 @@
+CREATE TEMP TABLE vec_markets_backup AS SELECT rowid AS id, embedding FROM vec_markets;
+
+DROP TABLE vec_markets;
+
+CREATE VIRTUAL TABLE vec_markets USING vec0(
+    embedding FLOAT[384]
+);
+
+INSERT INTO vec_markets(rowid, embedding)
+SELECT id, embedding FROM vec_markets_backup;
+
+DROP TABLE vec_markets_backup;
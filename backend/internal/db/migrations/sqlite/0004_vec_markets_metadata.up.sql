@@ -0,0 +1,30 @@
+-- Rebuild vec_markets with metadata so KNN queries filter before ranking instead of
+-- discarding neighbours afterwards. provider_id is a partition key; the other columns
+-- are sqlite-vec metadata columns. close_time is unix seconds. vec0 tables can't be
+-- altered, so embeddings are copied out and back in.
+CREATE TEMP TABLE vec_markets_backup AS SELECT rowid AS id, embedding FROM vec_markets;
+
+DROP TABLE vec_markets;
+
+CREATE VIRTUAL TABLE vec_markets USING vec0(
+    provider_id integer partition key,
+    embedding FLOAT[384],
+    status text,
+    category text,
+    event_ticker text,
+    close_time integer
+);
+
+INSERT INTO vec_markets(rowid, provider_id, embedding, status, category, event_ticker, close_time)
+SELECT
+    b.id,
+    m.provider_id,
+    b.embedding,
+    COALESCE(m.status, ''),
+    COALESCE(m.category, ''),
+    COALESCE(m.event_ticker, ''),
+    COALESCE(CAST(strftime('%s', m.close_time) AS INTEGER), 0)
+FROM vec_markets_backup b
+JOIN markets m ON m.id = b.id;
+
+DROP TABLE vec_markets_backup;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS markets_fts_update;
+DROP TRIGGER IF EXISTS markets_fts_delete;
+DROP TRIGGER IF EXISTS markets_fts_insert;
+DROP TABLE IF EXISTS markets_fts;
+ALTER TABLE markets DROP COLUMN rules;
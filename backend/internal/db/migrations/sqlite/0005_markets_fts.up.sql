@@ -0,0 +1,36 @@
+-- Resolution rules are searchable text too
+ALTER TABLE markets ADD COLUMN rules text;
+
+-- Full-text index for lexical (BM25) market search. It is an external content table,
+-- so the text lives only in markets and the triggers below keep the index in step.
+-- Requires go-sqlite3 built with the sqlite_fts5 tag.
+CREATE VIRTUAL TABLE markets_fts USING fts5(
+    ticker,
+    title,
+    yes_sub_title,
+    no_sub_title,
+    description,
+    rules,
+    content='markets',
+    content_rowid='id'
+);
+
+CREATE TRIGGER markets_fts_insert AFTER INSERT ON markets BEGIN
+    INSERT INTO markets_fts(rowid, ticker, title, yes_sub_title, no_sub_title, description, rules)
+    VALUES (new.id, new.ticker, new.title, new.yes_sub_title, new.no_sub_title, new.description, new.rules);
+END;
+
+CREATE TRIGGER markets_fts_delete AFTER DELETE ON markets BEGIN
+    INSERT INTO markets_fts(markets_fts, rowid, ticker, title, yes_sub_title, no_sub_title, description, rules)
+    VALUES ('delete', old.id, old.ticker, old.title, old.yes_sub_title, old.no_sub_title, old.description, old.rules);
+END;
+
+CREATE TRIGGER markets_fts_update AFTER UPDATE OF ticker, title, yes_sub_title, no_sub_title, description, rules ON markets BEGIN
+    INSERT INTO markets_fts(markets_fts, rowid, ticker, title, yes_sub_title, no_sub_title, description, rules)
+    VALUES ('delete', old.id, old.ticker, old.title, old.yes_sub_title, old.no_sub_title, old.description, old.rules);
+    INSERT INTO markets_fts(rowid, ticker, title, yes_sub_title, no_sub_title, description, rules)
+    VALUES (new.id, new.ticker, new.title, new.yes_sub_title, new.no_sub_title, new.description, new.rules);
+END;
+
+-- Index the markets we already have
+INSERT INTO markets_fts(markets_fts) VALUES ('rebuild');
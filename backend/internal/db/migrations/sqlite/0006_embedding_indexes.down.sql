@@ -0,0 +1,3 @@
+-- Vector tables of indexes other than vec_markets are left in place.
+DROP TABLE market_embeddings;
+DROP TABLE embedding_indexes;
@@ -0,0 +1,29 @@
+-- Version the embedding index. Each version has its own vector table and records the
+-- model, dimension and text template version its vectors were built with. A new
+-- version is built in the background ('building') and swapped in by marking it
+-- 'active' and the previous one 'retired', so searches keep working during a re-index.
+CREATE TABLE embedding_indexes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    vector_table text NOT NULL,
+    model text NOT NULL,
+    dimension integer NOT NULL,
+    template_version integer NOT NULL,
+    status text NOT NULL,
+    created_at datetime,
+    activated_at datetime
+);
+CREATE INDEX idx_embedding_indexes_status ON embedding_indexes(status);
+
+-- vec_markets was created for all-MiniLM-L6-v2
+INSERT INTO embedding_indexes(id, vector_table, model, dimension, template_version, status, created_at, activated_at)
+VALUES (1, 'vec_markets', 'sentence-transformers/all-MiniLM-L6-v2', 384, 1, 'active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- Hash of the text each vector was built from, per index, so unchanged markets
+-- are not re-embedded on every sync
+CREATE TABLE market_embeddings (
+    index_id integer NOT NULL,
+    market_id integer NOT NULL,
+    text_hash text NOT NULL,
+    updated_at datetime,
+    PRIMARY KEY (index_id, market_id)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE markets DROP COLUMN liquidity;
+ALTER TABLE markets DROP COLUMN open_interest;
+ALTER TABLE markets DROP COLUMN volume24h;
+ALTER TABLE markets DROP COLUMN last_price;
+ALTER TABLE markets DROP COLUMN no_ask;
+ALTER TABLE markets DROP COLUMN no_bid;
+ALTER TABLE markets DROP COLUMN yes_ask;
+ALTER TABLE markets DROP COLUMN yes_bid;
+
+ALTER TABLE providers DROP COLUMN market_watermark;
+ALTER TABLE providers DROP COLUMN last_full_event_sync;
+ALTER TABLE providers DROP COLUMN event_sync_cursor;
@@ -0,0 +1,16 @@
+-- Incremental event sync state. event_sync_cursor checkpoints an unfinished full crawl
+-- so it resumes where it stopped; market_watermark is the time up to which changed
+-- markets have been pulled.
+ALTER TABLE providers ADD COLUMN event_sync_cursor text NOT NULL DEFAULT '';
+ALTER TABLE providers ADD COLUMN last_full_event_sync datetime;
+ALTER TABLE providers ADD COLUMN market_watermark datetime;
+
+-- Latest quotes and activity from the market refresh loop. Prices are cents.
+ALTER TABLE markets ADD COLUMN yes_bid integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN yes_ask integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN no_bid integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN no_ask integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN last_price integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN volume24h integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN open_interest integer NOT NULL DEFAULT 0;
+ALTER TABLE markets ADD COLUMN liquidity integer NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE events DROP COLUMN status;
+ALTER TABLE providers DROP COLUMN event_sync_started_at;
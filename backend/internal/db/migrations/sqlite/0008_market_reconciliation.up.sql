@@ -0,0 +1,10 @@
+-- When the full crawl in progress started, kept across restarts so a resumed crawl
+-- knows which markets it has already seen
+ALTER TABLE providers ADD COLUMN event_sync_started_at datetime;
+
+-- Events are active while any of their markets is, settled once all of them are
+ALTER TABLE events ADD COLUMN status text NOT NULL DEFAULT 'active';
+
+-- Market statuses were stored as reported by Kalshi; fold them into active, closed, settled
+UPDATE markets SET status = 'settled' WHERE status = 'finalized';
+UPDATE markets SET status = 'closed' WHERE status NOT IN ('active', 'closed', 'settled');
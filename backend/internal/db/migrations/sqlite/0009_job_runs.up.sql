@@ -0,0 +1,15 @@
+-- One row per execution of a scheduled job. Running jobs bump heartbeat_at while
+-- they hold their lock, so a run whose process died can be told apart from a slow one.
+CREATE TABLE job_runs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    job text NOT NULL,
+    triggered_by text NOT NULL,
+    status text NOT NULL,
+    counts text NOT NULL DEFAULT '{}',
+    error text NOT NULL DEFAULT '',
+    started_at datetime NOT NULL,
+    finished_at datetime,
+    heartbeat_at datetime
+);
+CREATE INDEX idx_job_runs_job_started_at ON job_runs(job, started_at);
+CREATE INDEX idx_job_runs_status ON job_runs(status);
@@ -0,0 +1,32 @@
+-- Related market analysis runs in cycles. A cycle first plans the market pairs worth
+-- comparing, then works through them in priority order until it runs out of pairs
+-- or budget. Both are persisted so a cycle interrupted by a restart resumes.
+CREATE TABLE comparison_cycles (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    status text NOT NULL,
+    pairs integer NOT NULL DEFAULT 0,
+    compared integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    tokens_used integer NOT NULL DEFAULT 0,
+    elapsed_ms integer NOT NULL DEFAULT 0,
+    started_at datetime NOT NULL,
+    finished_at datetime
+);
+CREATE INDEX idx_comparison_cycles_status ON comparison_cycles(status);
+
+CREATE TABLE comparison_pairs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    cycle_id integer NOT NULL,
+    source_ticker text NOT NULL,
+    target_ticker text NOT NULL,
+    source_close_time datetime,
+    target_close_time datetime,
+    search_rank integer NOT NULL DEFAULT 0,
+    priority real NOT NULL,
+    status text NOT NULL,
+    error text NOT NULL DEFAULT '',
+    tokens integer NOT NULL DEFAULT 0,
+    processed_at datetime
+);
+CREATE UNIQUE INDEX idx_comparison_pairs_pair ON comparison_pairs(cycle_id, source_ticker, target_ticker);
+CREATE INDEX idx_comparison_pairs_queue ON comparison_pairs(cycle_id, status, priority);
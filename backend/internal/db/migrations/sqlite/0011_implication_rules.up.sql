@@ -0,0 +1,9 @@
+-- Structured strikes let ladders of numeric markets be related without the SLM:
+-- strike_type is greater, greater_or_equal, less, less_or_equal, between, ...
+ALTER TABLE markets ADD COLUMN strike_type text NOT NULL DEFAULT '';
+ALTER TABLE markets ADD COLUMN floor_strike real;
+ALTER TABLE markets ADD COLUMN cap_strike real;
+
+-- Which engine found an implication or compared a pair: rules or slm
+ALTER TABLE market_implications ADD COLUMN engine text NOT NULL DEFAULT 'slm';
+ALTER TABLE comparison_pairs ADD COLUMN engine text NOT NULL DEFAULT '';
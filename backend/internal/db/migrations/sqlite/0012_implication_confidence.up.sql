@@ -0,0 +1,3 @@
+-- Share of SLM answers, across samples and both directions, that agreed on an
+-- implication: 1 for rules, NULL for implications stored before consistency checks
+ALTER TABLE market_implications ADD COLUMN confidence real;
@@ -10,27 +10,54 @@ type Provider struct {
 	Name          string    `gorm:"uniqueIndex;not null"` // e.g., "kalshi"
 	IsActive      bool      `gorm:"default:true"`
 	LastEventSync time.Time // Last time we successfully synced events
-	Markets       []Market  `gorm:"foreignKey:ProviderID"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// LastSettlementSync is the newest settlement time we have reconciled
+	LastSettlementSync time.Time
+	// EventSyncCursor checkpoints an unfinished full event crawl (empty when none is in progress)
+	EventSyncCursor   string
+	LastFullEventSync time.Time // When the last completed full crawl started
+	// EventSyncStartedAt is when the full crawl in progress started
+	EventSyncStartedAt time.Time
+	// MarketWatermark is the time up to which changed markets have been pulled
+	MarketWatermark time.Time
+	Markets         []Market `gorm:"foreignKey:ProviderID"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 // Market represents a specific betting contract or event
 type Market struct {
-	ID             uint   `gorm:"primaryKey"`
-	ProviderID     uint   `gorm:"not null;uniqueIndex:idx_provider_market"`
-	ExternalID     string `gorm:"uniqueIndex:idx_provider_market"` // The ID from Kalshi
-	Ticker         string `gorm:"index"`                           // e.g., "FED-24DEC-T25"
-	EventTicker    string `gorm:"index"`                           // The EventTicker from Kalshi
-	Title          string
-	Description    string
-	YesSubTitle    string
-	NoSubTitle     string
-	Status         string    `gorm:"default:'active'"` // active, closed, settled
-	Category       string    // e.g., "Economics", "Politics"
-	LastDataUpdate time.Time // Last time we pulled orderbook data
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID                 uint   `gorm:"primaryKey"`
+	ProviderID         uint   `gorm:"not null;uniqueIndex:idx_provider_market"`
+	ExternalID         string `gorm:"uniqueIndex:idx_provider_market"` // The ID from Kalshi
+	Ticker             string `gorm:"index"`                           // e.g., "FED-24DEC-T25"
+	EventTicker        string `gorm:"index"`                           // The EventTicker from Kalshi
+	Title              string
+	Description        string
+	YesSubTitle        string
+	NoSubTitle         string
+	Rules              string    // Resolution rules, primary then secondary
+	Status             string    `gorm:"default:'active'"` // active, closed, settled
+	Category           string    // e.g., "Economics", "Politics"
+	SeriesTicker       string    // The series the market's event belongs to, e.g. "INXD"
+	StrikeType         string    // greater, greater_or_equal, less, less_or_equal, between, ...
+	FloorStrike        *float64  // Lower strike, when the strike type has one
+	CapStrike          *float64  // Upper strike, when the strike type has one
+	FeeWaiverExpiresAt time.Time // Trading fees are waived until this time (zero if none)
+	CloseTime          time.Time // When trading on the market stops
+	Result             string    // yes, no once the market has resolved
+	SettlementValue    int       // Cents paid per YES contract at settlement
+	SettledAt          *time.Time
+	LastDataUpdate     time.Time // Last time we pulled orderbook data
+	YesBid             int       // Cents, from the market refresh loop
+	YesAsk             int
+	NoBid              int
+	NoAsk              int
+	LastPrice          int
+	Volume24h          int // Contracts traded in the last 24 hours
+	OpenInterest       int
+	Liquidity          int // Cents
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 // Event represents a Kalshi event (group of markets)
@@ -45,6 +72,7 @@ type Event struct {
 	StrikePeriod           string
 	ExpirationTime         time.Time // When the event generally expires
 	ClosestMarketCloseTime time.Time // Calculated field: nearest market close time
+	Status                 string    `gorm:"default:'active'"` // active, closed, settled; derived from its markets
 	CreatedAt              time.Time
 	UpdatedAt              time.Time
 }
@@ -60,7 +88,221 @@ type ArbitrageOpportunity struct {
 	ExpectedYield   float64 `gorm:"index"` // Calculated ROI
 	PotentialProfit float64
 	RequiredCapital float64
-	Status          string    `gorm:"default:'detected'"` // detected, pending, executed, ignored
+	Status          string    `gorm:"default:'detected'"` // detected, pending, executed, partial, failed, ignored
 	DetectedAt      time.Time `gorm:"autoCreateTime"`
 	ExpiresAt       time.Time
+	RealizedPnL     float64          `gorm:"column:realized_pnl"` // Cents, net of fees, once every leg has settled
+	SettledAt       *time.Time       // When every leg had settled and RealizedPnL was computed
+	Legs            []OpportunityLeg `gorm:"foreignKey:OpportunityID"`
+}
+
+// OpportunityLeg is a single order required to enter an ArbitrageOpportunity
+type OpportunityLeg struct {
+	ID            uint   `gorm:"primaryKey"`
+	OpportunityID uint   `gorm:"index;not null"`
+	MarketID      uint   `gorm:"index"`
+	Ticker        string `gorm:"not null"`
+	Side          string // yes, no
+	Action        string // buy, sell
+	Price         int    // Limit price in cents
+	Count         int
+}
+
+// OrderbookSnapshot is a point-in-time capture of a market's resting bids
+type OrderbookSnapshot struct {
+	ID         uint      `gorm:"primaryKey"`
+	Ticker     string    `gorm:"index:idx_orderbook_ticker_time"`
+	Yes        string    // JSON [[price, qty], ...] of YES bids
+	No         string    // JSON [[price, qty], ...] of NO bids
+	CapturedAt time.Time `gorm:"index:idx_orderbook_ticker_time"`
+}
+
+// Order is an order we submitted to an exchange
+type Order struct {
+	ID            uint   `gorm:"primaryKey"`
+	OpportunityID uint   `gorm:"index"`
+	ClientOrderID string `gorm:"uniqueIndex"`
+	ExternalID    string `gorm:"index"` // The order_id assigned by the exchange
+	Ticker        string `gorm:"index;not null"`
+	Side          string // yes, no
+	Action        string // buy, sell
+	Price         int    // Limit price in cents
+	Count         int
+	FilledCount   int
+	Status        string `gorm:"default:'pending'"` // pending, resting, executed, canceled, failed
+	Error         string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Fill is a single execution against one of our orders
+type Fill struct {
+	ID         uint   `gorm:"primaryKey"`
+	OrderID    uint   `gorm:"index"`
+	ExternalID string `gorm:"uniqueIndex"` // The fill_id from the exchange
+	Ticker     string `gorm:"index"`
+	Side       string
+	Action     string
+	Price      int // Cents per contract
+	Count      int
+	IsTaker    bool
+	Fee        int // Cents
+	FilledAt   time.Time
+	// RealizedPnL is this execution's profit against the settlement value, net of fees
+	RealizedPnL float64 `gorm:"column:realized_pnl"`
+	Settled     bool    `gorm:"index"`
+}
+
+// Position is our net holding in one side of a market
+type Position struct {
+	ID          uint    `gorm:"primaryKey"`
+	Ticker      string  `gorm:"not null;uniqueIndex:,composite:ticker_side"`
+	Side        string  `gorm:"not null;uniqueIndex:,composite:ticker_side"`
+	Count       int     // Contracts currently held
+	AvgPrice    float64 // Average entry price in cents
+	RealizedPnL float64 `gorm:"column:realized_pnl"` // Realized profit in cents, net of fees
+	FeesPaid    int     // Cents
+	Status      string  `gorm:"default:'open'"` // open, closed
+	OpenedAt    time.Time
+	ClosedAt    *time.Time
+	UpdatedAt   time.Time
+}
+
+// PaperOrder, PaperFill and PaperPosition mirror the live trading tables for the
+// simulated exchange, so virtual activity never mixes with real books.
+type PaperOrder Order
+type PaperFill Fill
+type PaperPosition Position
+
+// Settlement records a payout reported by the exchange for a market we held
+type Settlement struct {
+	ID           uint   `gorm:"primaryKey"`
+	Ticker       string `gorm:"uniqueIndex;not null"`
+	MarketResult string // yes, no, void
+	YesCount     int
+	NoCount      int
+	Revenue      int // Cents
+	SettledAt    time.Time
+	CreatedAt    time.Time
+}
+
+// MarketImplication is a logical implication between two markets found by analysis:
+// if the source resolves SourceOutcome, the target must resolve TargetOutcome
+type MarketImplication struct {
+	ID            uint   `gorm:"primaryKey"`
+	SourceTicker  string `gorm:"not null;uniqueIndex:idx_implication_pair"`
+	TargetTicker  string `gorm:"not null;uniqueIndex:idx_implication_pair"`
+	SourceOutcome string `gorm:"not null;uniqueIndex:idx_implication_pair"` // yes, no
+	TargetOutcome string // yes, no
+	Reason        string
+	Engine        string   // rules or slm, whichever found the implication
+	Confidence    *float64 // Share of SLM answers that agreed on it, 1 for rules; nil if stored before consistency checks
+	Verdict       string   `gorm:"index"` // Empty until both markets resolve, then correct, incorrect or untested
+	EvaluatedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// BalanceSnapshot is a periodic capture of account equity for one ledger
+type BalanceSnapshot struct {
+	ID             uint      `gorm:"primaryKey"`
+	Provider       string    `gorm:"index:idx_balance_snapshot"` // e.g., "kalshi"
+	Ledger         string    `gorm:"index:idx_balance_snapshot"` // live, paper
+	Cash           int64     // Cents
+	PositionsValue int64     // Cents, open positions marked to market
+	Equity         int64     // Cents, Cash + PositionsValue
+	CapturedAt     time.Time `gorm:"index:idx_balance_snapshot"`
+}
+
+// EmbeddingIndex is one version of the market vector index. Every vector in its
+// table was built with the same model, dimension and embedding text template.
+type EmbeddingIndex struct {
+	ID              uint   `gorm:"primaryKey"`
+	VectorTable     string // vec_markets for the original index, vec_markets_<id> after
+	Model           string
+	Dimension       int
+	TemplateVersion int
+	Status          string `gorm:"index"` // building, active, retired
+	CreatedAt       time.Time
+	ActivatedAt     *time.Time
+}
+
+func (EmbeddingIndex) TableName() string {
+	return "embedding_indexes"
+}
+
+// MarketEmbedding records the text a market's vector in an index was built from,
+// so markets whose text hasn't changed are not embedded again
+type MarketEmbedding struct {
+	IndexID   uint   `gorm:"primaryKey;autoIncrement:false"`
+	MarketID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	TextHash  string // SHA-256 of the embedding text
+	UpdatedAt time.Time
+}
+
+// JobRun records one execution of a scheduled job
+type JobRun struct {
+	ID          uint   `gorm:"primaryKey"`
+	Job         string `gorm:"index"`
+	TriggeredBy string // schedule or manual
+	Status      string `gorm:"index"` // running, succeeded, failed, skipped
+	Counts      string // JSON object of what the run processed, e.g. {"events": 120}
+	Error       string
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	HeartbeatAt time.Time // Bumped while the run holds its lock
+}
+
+// ComparisonCycle is one pass of related market analysis: the market pairs it
+// planned to compare and what it has spent on them so far
+type ComparisonCycle struct {
+	ID         uint   `gorm:"primaryKey"`
+	Status     string `gorm:"index"` // running, completed, exhausted, expired
+	Pairs      int    // Pairs queued
+	Compared   int    // Pairs sent to the SLM
+	Failed     int
+	TokensUsed int
+	ElapsedMs  int64 // Time spent comparing, summed over resumes
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// ComparisonPair is a market pair queued for comparison in a cycle
+type ComparisonPair struct {
+	ID              uint   `gorm:"primaryKey"`
+	CycleID         uint   `gorm:"uniqueIndex:idx_comparison_pairs_pair"`
+	SourceTicker    string `gorm:"uniqueIndex:idx_comparison_pairs_pair"`
+	TargetTicker    string `gorm:"uniqueIndex:idx_comparison_pairs_pair"`
+	SourceCloseTime time.Time
+	TargetCloseTime time.Time
+	SearchRank      int     // Position of the target in the source's related markets, from 0
+	Priority        float64 // Higher is compared first
+	Status          string  // pending, done, failed, skipped
+	Engine          string  // rules, slm or cache, whichever answered
+	Error           string
+	Tokens          int
+	ProcessedAt     *time.Time
+}
+
+// SLMCall is one comparison asked of an SLM backend, kept so any decision can be
+// inspected later. Prompts are rendered from PromptVersion and Markets, the text
+// of the two markets at the time; PromptHash checks a re-render matches.
+type SLMCall struct {
+	ID            uint   `gorm:"primaryKey"`
+	SourceTicker  string `gorm:"index:idx_slm_calls_pair"`
+	TargetTicker  string `gorm:"index:idx_slm_calls_pair"`
+	PromptVersion string
+	PromptHash    string // SHA-256 of the prompts as sent
+	Prompt        string // JSON array of the prompts as sent, only kept with SLM_LOG=full
+	Markets       string // JSON snapshot of the market text the prompts were rendered from
+	Backend       string
+	Model         string
+	Temperature   float64
+	LatencyMs     int64
+	Tokens        int
+	Attempts      int    // JSON answers asked for, counting parse retries
+	Output        string // JSON array of the model's replies in order, reasoning first
+	Result        string // The parsed comparison as JSON; empty when none parsed
+	Error         string
+	CreatedAt     time.Time `gorm:"index"`
 }
@@ -59,3 +59,35 @@ func (r *Redis) Remove(key string) error {
 func (r *Redis) Get(key string) (string, error) {
 	return r.client.Get(r.ctx, key).Result()
 }
+
+// releaseLockScript deletes a lock only if it is still held by the given token
+var releaseLockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+// extendLockScript pushes back a lock's expiry only if it is still held by the given token
+var extendLockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// AcquireLock takes the lock at key for token unless someone else holds it.
+// The lock expires after ttl unless it is extended or released first.
+func (r *Redis) AcquireLock(key, token string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(r.ctx, key, token, ttl).Result()
+}
+
+// ExtendLock resets the expiry of a lock held by token. Returns false if the lock
+// was lost, e.g. because it expired and another holder took it.
+func (r *Redis) ExtendLock(key, token string, ttl time.Duration) (bool, error) {
+	n, err := extendLockScript.Run(r.ctx, r.client, []string{key}, token, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// ReleaseLock releases a lock held by token. Locks held by others are left alone.
+func (r *Redis) ReleaseLock(key, token string) error {
+	return releaseLockScript.Run(r.ctx, r.client, []string{key}, token).Err()
+}
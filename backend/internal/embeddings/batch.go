@@ -0,0 +1,65 @@
+package embeddings
+
+import (
+	"fmt"
+	"sync"
+)
+
+// generateConcurrently embeds texts with at most workers calls to generate in flight.
+// Vectors are returned in input order; the first error aborts the remaining work.
+func generateConcurrently(texts []string, workers int, generate func(string) ([]float32, error)) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	if len(texts) == 0 {
+		return vectors, nil
+	}
+	workers = max(1, min(workers, len(texts)))
+
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				vec, err := generate(texts[i])
+				if err != nil {
+					errs <- fmt.Errorf("text %d: %w", i, err)
+					return
+				}
+				vectors[i] = vec
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Feed work until it runs out or a worker fails
+	var err error
+feed:
+	for i := range texts {
+		select {
+		case jobs <- i:
+		case err = <-errs:
+			break feed
+		}
+	}
+	close(jobs)
+	<-done
+
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
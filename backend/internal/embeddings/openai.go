@@ -0,0 +1,83 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// openAIService calls an OpenAI-compatible /v1/embeddings endpoint, such as
+// the llama.cpp server (started with --embeddings) or ollama
+type openAIService struct {
+	client    *openai.LLM
+	modelName string
+	dimension int
+}
+
+func newOpenAIService(cfg Config) (*openAIService, error) {
+	// 1. Validate config
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("EMBEDDING_URL is required for the %s embedding backend", BackendOpenAI)
+	}
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid EMBEDDING_URL: %w", err)
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("EMBEDDING_MODEL is required for the %s embedding backend", BackendOpenAI)
+	}
+	log.Printf("Initializing embedding service with model: %s at %s", cfg.Model, cfg.URL)
+
+	// 2. Initialize the client. Local servers ignore the token but the client requires one.
+	token := cfg.APIKey
+	if token == "" {
+		token = "dummy-token"
+	}
+	client, err := openai.New(
+		openai.WithBaseURL(cfg.URL),
+		openai.WithToken(token),
+		openai.WithEmbeddingModel(cfg.Model),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding client: %w", err)
+	}
+
+	// 3. Learn the dimension, which also checks the endpoint is reachable
+	s := &openAIService{client: client, modelName: cfg.Model}
+	if s.dimension, err = probeDimension(s.Generate); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *openAIService) Generate(text string) ([]float32, error) {
+	vectors, err := s.GenerateBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// GenerateBatch sends all texts in a single request
+func (s *openAIService) GenerateBatch(texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	vectors, err := s.client.CreateEmbedding(context.Background(), texts)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding endpoint returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+func (s *openAIService) Model() string  { return s.modelName }
+func (s *openAIService) Dimension() int { return s.dimension }
+
+func (s *openAIService) Close() error {
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"runtime"
+	"strconv"
 
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks"
@@ -13,29 +16,115 @@ import (
 // Service defines the interface for generating embeddings
 type Service interface {
 	Generate(text string) ([]float32, error)
+	// GenerateBatch embeds several texts, returning vectors in the same order
+	GenerateBatch(texts []string) ([][]float32, error)
+	// Model names the embedding model, e.g. "sentence-transformers/all-MiniLM-L6-v2"
+	Model() string
+	// Dimension is the length of the vectors the model produces
+	Dimension() int
 	Close() error
 }
 
-type localService struct {
-	model textencoding.Interface
+const (
+	BackendLocal  = "local"  // cybertron models run in-process
+	BackendOpenAI = "openai" // an OpenAI-compatible /v1/embeddings endpoint
+
+	DefaultLocalModel = "sentence-transformers/all-MiniLM-L6-v2"
+)
+
+// Config selects the embedding backend and model
+type Config struct {
+	Backend string // local (default) or openai
+	Model   string
+	URL     string // Base URL of the OpenAI-compatible API, e.g. http://slm:8080/v1
+	APIKey  string
+	Workers int // Concurrent encodes for the local backend
+}
+
+// ConfigFromEnv reads the embedding configuration from EMBEDDING_* environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Backend: os.Getenv("EMBEDDING_BACKEND"),
+		Model:   os.Getenv("EMBEDDING_MODEL"),
+		URL:     os.Getenv("EMBEDDING_URL"),
+		APIKey:  os.Getenv("EMBEDDING_API_KEY"),
+		Workers: runtime.NumCPU(),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendLocal
+	}
+	if n, err := strconv.Atoi(os.Getenv("EMBEDDING_WORKERS")); err == nil && n > 0 {
+		cfg.Workers = n
+	}
+	return cfg
 }
 
-// NewService initializes the local embedding model (all-MiniLM-L6-v2)
+// NewService initializes the embedding backend selected by the environment
 func NewService() (Service, error) {
-	log.Println("Initializing local embedding model (all-MiniLM-L6-v2)...")
+	return New(ConfigFromEnv())
+}
+
+// New initializes the embedding backend described by cfg
+func New(cfg Config) (Service, error) {
+	switch cfg.Backend {
+	case BackendLocal:
+		return newLocalService(cfg)
+	case BackendOpenAI:
+		return newOpenAIService(cfg)
+	default:
+		return nil, fmt.Errorf("unknown embedding backend %q (expected %s or %s)", cfg.Backend, BackendLocal, BackendOpenAI)
+	}
+}
+
+// probeDimension embeds a short text to learn the model's output dimension
+func probeDimension(generate func(string) ([]float32, error)) (int, error) {
+	vec, err := generate("dimension probe")
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe embedding dimension: %w", err)
+	}
+	if len(vec) == 0 {
+		return 0, fmt.Errorf("embedding model returned an empty vector")
+	}
+	return len(vec), nil
+}
+
+type localService struct {
+	model     textencoding.Interface
+	modelName string
+	dimension int
+	workers   int
+}
+
+// newLocalService loads a cybertron text encoding model, by default all-MiniLM-L6-v2
+func newLocalService(cfg Config) (*localService, error) {
+	modelName := cfg.Model
+	if modelName == "" {
+		modelName = DefaultLocalModel
+	}
+	log.Printf("Initializing local embedding model (%s)...", modelName)
 
 	// Load the model. Cybertron handles downloading/caching automatically.
-	// We use the default configuration which usually targets all-MiniLM-L6-v2.
-	// Ideally, we explicitly specify the model to be safe.
 	model, err := tasks.Load[textencoding.Interface](&tasks.Config{
 		ModelsDir: "models", // Store models in a local "models" directory
-		ModelName: "sentence-transformers/all-MiniLM-L6-v2",
+		ModelName: modelName,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to load embedding model: %w", err)
 	}
 
-	return &localService{model: model}, nil
+	s := &localService{model: model, modelName: modelName, workers: max(1, cfg.Workers)}
+	if s.dimension, err = probeDimension(s.Generate); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *localService) Model() string  { return s.modelName }
+func (s *localService) Dimension() int { return s.dimension }
+
+// GenerateBatch encodes texts concurrently on a bounded pool of workers
+func (s *localService) GenerateBatch(texts []string) ([][]float32, error) {
+	return generateConcurrently(texts, s.workers, s.Generate)
 }
 
 // Generate creates a vector embedding for the given text
@@ -0,0 +1,177 @@
+package fees
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Liquidity is whether an execution took or provided liquidity
+type Liquidity int
+
+const (
+	Taker Liquidity = iota
+	Maker
+)
+
+// Schedule holds the fee coefficients for a market.
+// Kalshi charges ceil(rate × C × P × (1 − P)) dollars per order, where C is the
+// number of contracts and P the price in dollars, rounded up to the next cent.
+type Schedule struct {
+	TakerRate float64
+	MakerRate float64
+}
+
+// DefaultSchedule applies to every series without an override.
+// Most markets charge no maker fee.
+var DefaultSchedule = Schedule{TakerRate: 0.07, MakerRate: 0}
+
+// DefaultSeriesSchedules lists the series Kalshi prices below the standard rate
+var DefaultSeriesSchedules = map[string]Schedule{
+	"INX":        {TakerRate: 0.035, MakerRate: 0},
+	"INXD":       {TakerRate: 0.035, MakerRate: 0},
+	"INXU":       {TakerRate: 0.035, MakerRate: 0},
+	"NASDAQ100":  {TakerRate: 0.035, MakerRate: 0},
+	"NASDAQ100D": {TakerRate: 0.035, MakerRate: 0},
+	"NASDAQ100U": {TakerRate: 0.035, MakerRate: 0},
+}
+
+// Market carries the market attributes that determine its fees
+type Market struct {
+	Ticker             string
+	SeriesTicker       string
+	FeeWaiverExpiresAt time.Time
+}
+
+// Model computes trading fees
+type Model struct {
+	Default Schedule
+	Series  map[string]Schedule
+	Now     func() time.Time
+}
+
+// NewModel creates a fee model with Kalshi's published schedule.
+// Series overrides can be supplied via KALSHI_FEE_OVERRIDES as a comma separated
+// list of SERIES:taker_rate:maker_rate, e.g. "INX:0.035:0.0175,FED:0:0".
+func NewModel() *Model {
+	series := make(map[string]Schedule, len(DefaultSeriesSchedules))
+	for k, v := range DefaultSeriesSchedules {
+		series[k] = v
+	}
+
+	if raw := os.Getenv("KALSHI_FEE_OVERRIDES"); raw != "" {
+		overrides, err := ParseOverrides(raw)
+		if err != nil {
+			log.Printf("Warning: Ignoring invalid KALSHI_FEE_OVERRIDES: %v", err)
+		}
+		for k, v := range overrides {
+			series[k] = v
+		}
+	}
+
+	return &Model{
+		Default: DefaultSchedule,
+		Series:  series,
+		Now:     time.Now,
+	}
+}
+
+// ParseOverrides parses a SERIES:taker_rate:maker_rate list
+func ParseOverrides(raw string) (map[string]Schedule, error) {
+	overrides := make(map[string]Schedule)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return overrides, fmt.Errorf("expected SERIES:taker:maker, got %q", entry)
+		}
+		taker, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return overrides, fmt.Errorf("invalid taker rate in %q: %w", entry, err)
+		}
+		maker, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return overrides, fmt.Errorf("invalid maker rate in %q: %w", entry, err)
+		}
+		overrides[strings.ToUpper(parts[0])] = Schedule{TakerRate: taker, MakerRate: maker}
+	}
+	return overrides, nil
+}
+
+// SeriesFromTicker derives the series ticker from a market or event ticker
+// (e.g. "INXD-24DEC31-B5000" -> "INXD")
+func SeriesFromTicker(ticker string) string {
+	series, _, _ := strings.Cut(ticker, "-")
+	return strings.ToUpper(series)
+}
+
+// ScheduleFor returns the fee schedule for a market
+func (m *Model) ScheduleFor(market Market) Schedule {
+	series := market.SeriesTicker
+	if series == "" {
+		series = SeriesFromTicker(market.Ticker)
+	}
+	if s, ok := m.Series[strings.ToUpper(series)]; ok {
+		return s
+	}
+	return m.Default
+}
+
+// Fee returns the fee in cents for a single order of count contracts at priceCents
+func (m *Model) Fee(market Market, count, priceCents int, liquidity Liquidity) int {
+	return m.NewOrder(market, liquidity).Add(count, priceCents)
+}
+
+// NewOrder starts fee accounting for an order that may fill in several pieces
+func (m *Model) NewOrder(market Market, liquidity Liquidity) *OrderFees {
+	schedule := m.ScheduleFor(market)
+	rate := schedule.TakerRate
+	if liquidity == Maker {
+		rate = schedule.MakerRate
+	}
+
+	waived := !market.FeeWaiverExpiresAt.IsZero() && m.Now().Before(market.FeeWaiverExpiresAt)
+	if waived {
+		rate = 0
+	}
+
+	return &OrderFees{
+		// Rates are held in parts per million so fees are computed in exact integer math
+		ratePPM: int64(math.Round(rate * 1e6)),
+	}
+}
+
+// OrderFees accumulates the fee for one order. Kalshi rounds once per order, so each
+// fill is charged the increase in the rounded order total rather than its own rounded fee.
+type OrderFees struct {
+	ratePPM int64
+	raw     int64 // Unrounded fee in units of 1e-8 cents (ppm × cents²)
+	charged int   // Cents already attributed to earlier fills
+}
+
+// Add records a fill and returns the fee in cents attributable to it
+func (o *OrderFees) Add(count, priceCents int) int {
+	p := int64(priceCents)
+	o.raw += o.ratePPM * int64(count) * p * (100 - p)
+
+	// raw is in ppm × cents × cents; one cent of fee is 1e6 × 100 of those units
+	const unitsPerCent = 100_000_000
+	total := int((o.raw + unitsPerCent - 1) / unitsPerCent)
+
+	fee := total - o.charged
+	o.charged = total
+	return fee
+}
+
+// Total returns the rounded fee for the order so far in cents
+func (o *OrderFees) Total() int {
+	return o.charged
+}
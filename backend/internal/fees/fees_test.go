@@ -0,0 +1,170 @@
+package fees
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFee(t *testing.T) {
+	model := &Model{Default: DefaultSchedule, Series: DefaultSeriesSchedules, Now: time.Now}
+	standard := Market{Ticker: "KXFED-26DEC-T4.00"}
+	index := Market{Ticker: "INXD-24DEC31-B5000"}
+
+	tests := []struct {
+		name       string
+		market     Market
+		count      int
+		priceCents int
+		liquidity  Liquidity
+		want       int
+	}{
+		// 0.07 × 1 × 0.5 × 0.5 = $0.0175, rounded up to 2¢
+		{"one contract at 50", standard, 1, 50, Taker, 2},
+		{"ten contracts at 50", standard, 10, 50, Taker, 18},
+		{"exact cents", standard, 100, 50, Taker, 175},
+		// 0.07 × 0.01 × 0.99 = 0.0693¢ still costs a cent
+		{"tiny fee rounds up", standard, 1, 1, Taker, 1},
+		{"price near one", standard, 100, 99, Taker, 7},
+		{"symmetric in price", standard, 100, 1, Taker, 7},
+		{"no contracts", standard, 0, 50, Taker, 0},
+		{"no maker fee", standard, 100, 50, Maker, 0},
+		{"reduced series rate", index, 100, 50, Taker, 88},
+		{"series from explicit ticker", Market{Ticker: "X", SeriesTicker: "nasdaq100"}, 100, 50, Taker, 88},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := model.Fee(tt.market, tt.count, tt.priceCents, tt.liquidity); got != tt.want {
+				t.Errorf("Fee(%d @ %d¢) = %d¢, want %d¢", tt.count, tt.priceCents, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderFeesRoundOncePerOrder(t *testing.T) {
+	model := &Model{Default: DefaultSchedule, Now: time.Now}
+	order := model.NewOrder(Market{Ticker: "KXFED-26DEC-T4.00"}, Taker)
+
+	// Ten single-contract fills would cost 2¢ each if rounded separately, but the
+	// order as a whole is 17.5¢, so 18¢
+	want := []int{2, 2, 2, 1, 2, 2, 2, 1, 2, 2}
+	sum := 0
+	for i, w := range want {
+		fee := order.Add(1, 50)
+		if fee != w {
+			t.Errorf("fill %d charged %d¢, want %d¢", i+1, fee, w)
+		}
+		sum += fee
+	}
+	if sum != 18 || order.Total() != 18 {
+		t.Errorf("fills sum to %d¢ with total %d¢, want 18¢", sum, order.Total())
+	}
+	if whole := model.Fee(Market{Ticker: "KXFED-26DEC-T4.00"}, 10, 50, Taker); whole != order.Total() {
+		t.Errorf("single fill of 10 costs %d¢, split fills %d¢", whole, order.Total())
+	}
+}
+
+func TestMakerAndTakerRates(t *testing.T) {
+	model := &Model{
+		Default: DefaultSchedule,
+		Series:  map[string]Schedule{"INX": {TakerRate: 0.035, MakerRate: 0.0175}},
+		Now:     time.Now,
+	}
+	market := Market{Ticker: "INX-24DEC31-B5000"}
+
+	if got := model.Fee(market, 100, 50, Taker); got != 88 {
+		t.Errorf("taker fee = %d¢, want 88¢", got)
+	}
+	// 0.0175 × 100 × 0.25 = 43.75¢
+	if got := model.Fee(market, 100, 50, Maker); got != 44 {
+		t.Errorf("maker fee = %d¢, want 44¢", got)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]Schedule
+		wantErr bool
+	}{
+		{"empty", "", map[string]Schedule{}, false},
+		{"one series", "INX:0.035:0.0175", map[string]Schedule{"INX": {0.035, 0.0175}}, false},
+		{"several with spaces", " inx:0.035:0 , FED:0:0,", map[string]Schedule{"INX": {0.035, 0}, "FED": {0, 0}}, false},
+		{"missing maker rate", "INX:0.035", map[string]Schedule{}, true},
+		{"bad taker rate", "INX:abc:0", map[string]Schedule{}, true},
+		{"bad maker rate", "INX:0.035:abc", map[string]Schedule{}, true},
+		{"keeps entries before an error", "FED:0:0,INX", map[string]Schedule{"FED": {0, 0}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOverrides(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for series, schedule := range tt.want {
+				if got[series] != schedule {
+					t.Errorf("%s = %+v, want %+v", series, got[series], schedule)
+				}
+			}
+		})
+	}
+}
+
+func TestNewModelOverrides(t *testing.T) {
+	t.Setenv("KALSHI_FEE_OVERRIDES", "INX:0:0,FED:0.1:0.05")
+	model := NewModel()
+
+	tests := []struct {
+		ticker string
+		want   Schedule
+	}{
+		{"INX-24DEC31-B5000", Schedule{0, 0}},                  // Default series rate overridden
+		{"INXD-24DEC31-B5000", DefaultSeriesSchedules["INXD"]}, // Other defaults kept
+		{"FED-26DEC-T4.00", Schedule{0.1, 0.05}},               // New series
+		{"KXBTC-26DEC-B100000", DefaultSchedule},
+	}
+	for _, tt := range tests {
+		if got := model.ScheduleFor(Market{Ticker: tt.ticker}); got != tt.want {
+			t.Errorf("%s: schedule = %+v, want %+v", tt.ticker, got, tt.want)
+		}
+	}
+
+	// The package defaults are left untouched
+	if DefaultSeriesSchedules["INX"].TakerRate != 0.035 {
+		t.Errorf("override leaked into DefaultSeriesSchedules")
+	}
+}
+
+func TestFeeWaiver(t *testing.T) {
+	expires := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	market := Market{Ticker: "KXFED-26DEC-T4.00", FeeWaiverExpiresAt: expires}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"during waiver", expires.Add(-time.Hour), 0},
+		{"just before expiry", expires.Add(-time.Nanosecond), 0},
+		{"at expiry", expires, 175},
+		{"after expiry", expires.Add(time.Hour), 175},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &Model{Default: DefaultSchedule, Now: func() time.Time { return tt.now }}
+			if got := model.Fee(market, 100, 50, Taker); got != tt.want {
+				t.Errorf("fee = %d¢, want %d¢", got, tt.want)
+			}
+		})
+	}
+
+	model := &Model{Default: DefaultSchedule, Now: func() time.Time { return expires }}
+	if got := model.Fee(Market{Ticker: "KXFED-26DEC-T4.00"}, 100, 50, Taker); got != 175 {
+		t.Errorf("market without a waiver: fee = %d¢, want 175¢", got)
+	}
+}
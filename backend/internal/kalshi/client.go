@@ -1,11 +1,12 @@
 package kalshi
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	// "log"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
@@ -19,6 +20,24 @@ type Client struct {
 	BaseURL     string
 	HTTPClient  *http.Client
 	Credentials AuthCredentials
+	// Limiter paces requests; nil sends them as fast as callers make them
+	Limiter *RateLimiter
+}
+
+// APIError is a non-2xx response from the Kalshi API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kalshi api error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// IsRateLimited reports whether err is Kalshi refusing a request for exceeding the rate limit
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
 }
 
 // NewClient initializes a new Kalshi API client
@@ -53,11 +72,45 @@ func NewClient(baseURL, accessKey, keyPath string) (*Client, error) {
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		Limiter: NewRateLimiter(DefaultRequestsPerSecond),
 	}, nil
 }
 
-// DoRequest performs an authenticated request to Kalshi
+// DoRequest performs an authenticated request to Kalshi. With a Limiter,
+// requests are paced and those refused with 429 are retried after backing off.
 func (c *Client) DoRequest(method, path string, body io.Reader) ([]byte, error) {
+	if c.Limiter == nil {
+		data, _, err := c.doRequest(method, path, body)
+		return data, err
+	}
+
+	// Keep the body so a rate limited request can be sent again
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.Limiter.Wait()
+
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		data, resp, err := c.doRequest(method, path, reqBody)
+		if !IsRateLimited(err) || attempt == maxRateLimitRetries {
+			return data, err
+		}
+		wait := retryAfter(resp)
+		log.Printf("Kalshi rate limit hit on %s %s; backing off %v", method, strings.Split(path, "?")[0], wait)
+		c.Limiter.Backoff(wait)
+	}
+}
+
+func (c *Client) doRequest(method, path string, body io.Reader) ([]byte, *http.Response, error) {
 	// 1. Prepare Timestamp
 	timestamp := fmt.Sprintf("%d", time.Now().UnixMilli())
 
@@ -68,7 +121,7 @@ func (c *Client) DoRequest(method, path string, body io.Reader) ([]byte, error)
 
 	sig, err := c.Credentials.SignMessage(method, pathWithoutQuery, timestamp)
 	if err != nil {
-		return nil, fmt.Errorf("signing error: %w", err)
+		return nil, nil, fmt.Errorf("signing error: %w", err)
 	}
 
 	// 3. Create Request
@@ -77,7 +130,7 @@ func (c *Client) DoRequest(method, path string, body io.Reader) ([]byte, error)
 
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 4. Set Headers
@@ -89,24 +142,25 @@ func (c *Client) DoRequest(method, path string, body io.Reader) ([]byte, error)
 	// 5. Execute
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp, err
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("kalshi api error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, resp, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
-	return respBody, nil
+	return respBody, resp, nil
 }
 
 type BalanceResponse struct {
-	Balance int64 `json:"balance"`
+	Balance        int64 `json:"balance"`         // Available cash in cents
+	PortfolioValue int64 `json:"portfolio_value"` // Market value of open positions in cents
 }
 
 type APIResponse struct {
@@ -131,17 +185,27 @@ type EventsResponse struct {
 }
 
 func (c *Client) GetBalance() (int64, error) {
-	data, err := c.DoRequest("GET", "/trade-api/v2/portfolio/balance", nil)
+	res, err := c.GetPortfolioBalance()
 	if err != nil {
 		return 0, err
 	}
 
+	return res.Balance, nil
+}
+
+// GetPortfolioBalance returns both available cash and the value of open positions
+func (c *Client) GetPortfolioBalance() (*BalanceResponse, error) {
+	data, err := c.DoRequest("GET", "/trade-api/v2/portfolio/balance", nil)
+	if err != nil {
+		return nil, err
+	}
+
 	var res BalanceResponse
 	if err := json.Unmarshal(data, &res); err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return res.Balance, nil
+	return &res, nil
 }
 
 func (c *Client) GetMarkets(limit int, cursor string, mveFilter string, minCloseTs int64, maxCloseTs int64) (*MarketsResponse, error) {
@@ -203,20 +267,26 @@ func (c *Client) GetMarkets(limit int, cursor string, mveFilter string, minClose
 
 	for i, m := range fullResponse.Markets {
 		simplified[i] = types.SimplifiedMarket{
-			Ticker:        m.Ticker,
-			EventTicker:   m.EventTicker,
-			Title:         m.Title,
-			Subtitle:      m.Subtitle,
-			NoBidDollars:  m.YesBidDollars,
-			YesBidDollars: m.NoBidDollars,
-			YesAsk:        m.YesAsk,
-			NoAsk:         m.NoAsk,
-			YesSubTitle:   m.YesSubTitle,
-			NoSubTitle:    m.NoSubTitle,
-			Status:        m.Status,
-			CloseTime:     m.CloseTime,
-			YesAskDollars: m.YesAskDollars,
-			NoAskDollars:  m.NoAskDollars,
+			Ticker:                  m.Ticker,
+			EventTicker:             m.EventTicker,
+			Title:                   m.Title,
+			Subtitle:                m.Subtitle,
+			NoBidDollars:            m.YesBidDollars,
+			YesBidDollars:           m.NoBidDollars,
+			YesAsk:                  m.YesAsk,
+			NoAsk:                   m.NoAsk,
+			YesSubTitle:             m.YesSubTitle,
+			NoSubTitle:              m.NoSubTitle,
+			Status:                  m.Status,
+			CloseTime:               m.CloseTime,
+			YesAskDollars:           m.YesAskDollars,
+			NoAskDollars:            m.NoAskDollars,
+			FeeWaiverExpirationTime: m.FeeWaiverExpirationTime,
+			RulesPrimary:            m.RulesPrimary,
+			RulesSecondary:          m.RulesSecondary,
+			StrikeType:              m.StrikeType,
+			FloorStrike:             m.FloorStrike,
+			CapStrike:               m.CapStrike,
 		}
 	}
 
@@ -226,6 +296,57 @@ func (c *Client) GetMarkets(limit int, cursor string, mveFilter string, minClose
 	}, nil
 }
 
+// MarketQuery filters a market listing. Zero values are left out of the request.
+type MarketQuery struct {
+	Limit     int
+	Cursor    string
+	Status    string   // unopened, open, closed, settled
+	Tickers   []string // Specific markets, up to a page's worth
+	MveFilter string   // only, exclude
+	// MinUpdatedTs returns only markets whose metadata changed after this unix time.
+	// Kalshi doesn't allow combining it with other filters.
+	MinUpdatedTs int64
+}
+
+// ListMarkets returns a page of full market records matching the query
+func (c *Client) ListMarkets(q MarketQuery) (*APIResponse, error) {
+	params := url.Values{}
+	if q.Limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", q.Limit))
+	}
+	if q.Cursor != "" {
+		params.Set("cursor", q.Cursor)
+	}
+	if q.Status != "" {
+		params.Set("status", q.Status)
+	}
+	if len(q.Tickers) > 0 {
+		params.Set("tickers", strings.Join(q.Tickers, ","))
+	}
+	if q.MveFilter != "" {
+		params.Set("mve_filter", q.MveFilter)
+	}
+	if q.MinUpdatedTs > 0 {
+		params.Set("min_updated_ts", fmt.Sprintf("%d", q.MinUpdatedTs))
+	}
+
+	path := "/trade-api/v2/markets"
+	if len(params) > 0 {
+		path = path + "?" + params.Encode()
+	}
+
+	data, err := c.DoRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp APIResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 func (c *Client) GetEvent(eventTicker string) (*types.SimplifiedEvent, error) {
 	path := fmt.Sprintf("/trade-api/v2/events/%s?with_nested_markets=true", url.PathEscape(eventTicker))
 
@@ -247,20 +368,26 @@ func (c *Client) GetEvent(eventTicker string) (*types.SimplifiedEvent, error) {
 	simplifiedMarkets := make([]types.SimplifiedMarket, len(e.Markets))
 	for j, m := range e.Markets {
 		simplifiedMarkets[j] = types.SimplifiedMarket{
-			Ticker:        m.Ticker,
-			EventTicker:   m.EventTicker,
-			Title:         m.Title,
-			Subtitle:      m.Subtitle,
-			NoBidDollars:  m.YesBidDollars,
-			YesBidDollars: m.NoBidDollars,
-			YesAsk:        m.YesAsk,
-			NoAsk:         m.NoAsk,
-			YesSubTitle:   m.YesSubTitle,
-			NoSubTitle:    m.NoSubTitle,
-			Status:        m.Status,
-			CloseTime:     m.CloseTime,
-			YesAskDollars: m.YesAskDollars,
-			NoAskDollars:  m.NoAskDollars,
+			Ticker:                  m.Ticker,
+			EventTicker:             m.EventTicker,
+			Title:                   m.Title,
+			Subtitle:                m.Subtitle,
+			NoBidDollars:            m.YesBidDollars,
+			YesBidDollars:           m.NoBidDollars,
+			YesAsk:                  m.YesAsk,
+			NoAsk:                   m.NoAsk,
+			YesSubTitle:             m.YesSubTitle,
+			NoSubTitle:              m.NoSubTitle,
+			Status:                  m.Status,
+			CloseTime:               m.CloseTime,
+			YesAskDollars:           m.YesAskDollars,
+			NoAskDollars:            m.NoAskDollars,
+			FeeWaiverExpirationTime: m.FeeWaiverExpirationTime,
+			RulesPrimary:            m.RulesPrimary,
+			RulesSecondary:          m.RulesSecondary,
+			StrikeType:              m.StrikeType,
+			FloorStrike:             m.FloorStrike,
+			CapStrike:               m.CapStrike,
 		}
 	}
 
@@ -331,19 +458,25 @@ func (c *Client) GetEvents(limit int, cursor string) (*EventsResponse, error) {
 		simplifiedMarkets := make([]types.SimplifiedMarket, len(e.Markets))
 		for j, m := range e.Markets {
 			simplifiedMarkets[j] = types.SimplifiedMarket{
-				Ticker:        m.Ticker,
-				EventTicker:   m.EventTicker,
-				Title:         m.Title,
-				NoBidDollars:  m.YesBidDollars,
-				YesBidDollars: m.NoBidDollars,
-				YesAsk:        m.YesAsk,
-				NoAsk:         m.NoAsk,
-				YesSubTitle:   m.YesSubTitle,
-				NoSubTitle:    m.NoSubTitle,
-				Status:        m.Status,
-				CloseTime:     m.CloseTime,
-				YesAskDollars: m.YesAskDollars,
-				NoAskDollars:  m.NoAskDollars,
+				Ticker:                  m.Ticker,
+				EventTicker:             m.EventTicker,
+				Title:                   m.Title,
+				NoBidDollars:            m.YesBidDollars,
+				YesBidDollars:           m.NoBidDollars,
+				YesAsk:                  m.YesAsk,
+				NoAsk:                   m.NoAsk,
+				YesSubTitle:             m.YesSubTitle,
+				NoSubTitle:              m.NoSubTitle,
+				Status:                  m.Status,
+				CloseTime:               m.CloseTime,
+				YesAskDollars:           m.YesAskDollars,
+				NoAskDollars:            m.NoAskDollars,
+				FeeWaiverExpirationTime: m.FeeWaiverExpirationTime,
+				RulesPrimary:            m.RulesPrimary,
+				RulesSecondary:          m.RulesSecondary,
+				StrikeType:              m.StrikeType,
+				FloorStrike:             m.FloorStrike,
+				CapStrike:               m.CapStrike,
 			}
 		}
 
@@ -426,20 +559,26 @@ func (c *Client) GetMarketsByEvent(eventTicker string, limit int, cursor string,
 
 	for i, m := range fullResponse.Markets {
 		simplified[i] = types.SimplifiedMarket{
-			Ticker:        m.Ticker,
-			EventTicker:   m.EventTicker,
-			Title:         m.Title,
-			Subtitle:      m.Subtitle,
-			NoBidDollars:  m.YesBidDollars,
-			YesBidDollars: m.NoBidDollars,
-			YesAsk:        m.YesAsk,
-			NoAsk:         m.NoAsk,
-			YesSubTitle:   m.YesSubTitle,
-			NoSubTitle:    m.NoSubTitle,
-			Status:        m.Status,
-			CloseTime:     m.CloseTime,
-			YesAskDollars: m.YesAskDollars,
-			NoAskDollars:  m.NoAskDollars,
+			Ticker:                  m.Ticker,
+			EventTicker:             m.EventTicker,
+			Title:                   m.Title,
+			Subtitle:                m.Subtitle,
+			NoBidDollars:            m.YesBidDollars,
+			YesBidDollars:           m.NoBidDollars,
+			YesAsk:                  m.YesAsk,
+			NoAsk:                   m.NoAsk,
+			YesSubTitle:             m.YesSubTitle,
+			NoSubTitle:              m.NoSubTitle,
+			Status:                  m.Status,
+			CloseTime:               m.CloseTime,
+			YesAskDollars:           m.YesAskDollars,
+			NoAskDollars:            m.NoAskDollars,
+			FeeWaiverExpirationTime: m.FeeWaiverExpirationTime,
+			RulesPrimary:            m.RulesPrimary,
+			RulesSecondary:          m.RulesSecondary,
+			StrikeType:              m.StrikeType,
+			FloorStrike:             m.FloorStrike,
+			CapStrike:               m.CapStrike,
 		}
 	}
 
@@ -481,3 +620,45 @@ func (c *Client) GetMarketsForEventNextMonth(eventTicker string) ([]types.Simpli
 
 	return allMarkets, nil
 }
+
+// GetMarket retrieves the full details for a single market, including live quotes
+func (c *Client) GetMarket(ticker string) (*types.MarketData, error) {
+	path := fmt.Sprintf("/trade-api/v2/markets/%s", url.PathEscape(ticker))
+
+	data, err := c.DoRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var fullResponse struct {
+		Market types.MarketData `json:"market"`
+	}
+	if err := json.Unmarshal(data, &fullResponse); err != nil {
+		return nil, err
+	}
+
+	return &fullResponse.Market, nil
+}
+
+// GetOrderbook retrieves the current resting bids for a market.
+// A depth of 0 returns all levels.
+func (c *Client) GetOrderbook(ticker string, depth int) (*types.Orderbook, error) {
+	path := fmt.Sprintf("/trade-api/v2/markets/%s/orderbook", url.PathEscape(ticker))
+	if depth > 0 {
+		path = fmt.Sprintf("%s?depth=%d", path, depth)
+	}
+
+	data, err := c.DoRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var fullResponse struct {
+		Orderbook types.Orderbook `json:"orderbook"`
+	}
+	if err := json.Unmarshal(data, &fullResponse); err != nil {
+		return nil, err
+	}
+
+	return &fullResponse.Orderbook, nil
+}
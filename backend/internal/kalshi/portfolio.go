@@ -0,0 +1,149 @@
+package kalshi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"backend/internal/kalshi/types"
+)
+
+type OrdersResponse struct {
+	Orders []types.Order `json:"orders"`
+	Cursor string        `json:"cursor"`
+}
+
+type FillsResponse struct {
+	Fills  []types.Fill `json:"fills"`
+	Cursor string       `json:"cursor"`
+}
+
+// CancelOrder cancels a resting order by its exchange order ID
+func (c *Client) CancelOrder(orderID string) (*types.Order, error) {
+	path := fmt.Sprintf("/trade-api/v2/portfolio/orders/%s", url.PathEscape(orderID))
+
+	data, err := c.DoRequest("DELETE", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Order types.Order `json:"order"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Order, nil
+}
+
+// GetOrders retrieves a page of our orders, optionally filtered by status and creation time
+func (c *Client) GetOrders(status string, limit int, cursor string, minTs int64) (*OrdersResponse, error) {
+	path := "/trade-api/v2/portfolio/orders"
+	params := []string{}
+
+	if status != "" {
+		params = append(params, fmt.Sprintf("status=%s", url.QueryEscape(status)))
+	}
+	if limit > 0 {
+		params = append(params, fmt.Sprintf("limit=%d", limit))
+	}
+	if cursor != "" {
+		params = append(params, fmt.Sprintf("cursor=%s", url.QueryEscape(cursor)))
+	}
+	if minTs > 0 {
+		params = append(params, fmt.Sprintf("min_ts=%d", minTs))
+	}
+
+	if len(params) > 0 {
+		path = path + "?" + strings.Join(params, "&")
+	}
+
+	data, err := c.DoRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res OrdersResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GetFills retrieves a page of fills, optionally filtered by ticker, order ID and fill time
+func (c *Client) GetFills(ticker, orderID string, limit int, cursor string, minTs int64) (*FillsResponse, error) {
+	path := "/trade-api/v2/portfolio/fills"
+	params := []string{}
+
+	if ticker != "" {
+		params = append(params, fmt.Sprintf("ticker=%s", url.QueryEscape(ticker)))
+	}
+	if orderID != "" {
+		params = append(params, fmt.Sprintf("order_id=%s", url.QueryEscape(orderID)))
+	}
+	if limit > 0 {
+		params = append(params, fmt.Sprintf("limit=%d", limit))
+	}
+	if cursor != "" {
+		params = append(params, fmt.Sprintf("cursor=%s", url.QueryEscape(cursor)))
+	}
+	if minTs > 0 {
+		params = append(params, fmt.Sprintf("min_ts=%d", minTs))
+	}
+
+	if len(params) > 0 {
+		path = path + "?" + strings.Join(params, "&")
+	}
+
+	data, err := c.DoRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res FillsResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type SettlementsResponse struct {
+	Settlements []types.Settlement `json:"settlements"`
+	Cursor      string             `json:"cursor"`
+}
+
+// GetSettlements retrieves a page of our settled positions, optionally only those settled after minTs
+func (c *Client) GetSettlements(limit int, cursor string, minTs int64) (*SettlementsResponse, error) {
+	path := "/trade-api/v2/portfolio/settlements"
+	params := []string{}
+
+	if limit > 0 {
+		params = append(params, fmt.Sprintf("limit=%d", limit))
+	}
+	if cursor != "" {
+		params = append(params, fmt.Sprintf("cursor=%s", url.QueryEscape(cursor)))
+	}
+	if minTs > 0 {
+		params = append(params, fmt.Sprintf("min_ts=%d", minTs))
+	}
+
+	if len(params) > 0 {
+		path = path + "?" + strings.Join(params, "&")
+	}
+
+	data, err := c.DoRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res SettlementsResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,62 @@
+package kalshi
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRequestsPerSecond keeps a client comfortably inside Kalshi's basic tier read limit
+const DefaultRequestsPerSecond = 10
+
+const (
+	// maxRateLimitRetries is how often a rate limited request is retried
+	maxRateLimitRetries = 3
+	// defaultRetryAfter is the backoff when a 429 response doesn't say how long to wait
+	defaultRetryAfter = time.Second
+)
+
+// RateLimiter spaces out requests so concurrent callers sharing a client stay
+// under the exchange's request budget, and holds everyone back after a 429
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // Earliest time the next request may start
+}
+
+// NewRateLimiter allows up to perSecond requests per second
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the caller may send a request
+func (l *RateLimiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// Backoff holds every request back for d, e.g. after the exchange rate limited us
+func (l *RateLimiter) Backoff(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// retryAfter reads how long a 429 response asks us to wait
+func retryAfter(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return defaultRetryAfter
+}
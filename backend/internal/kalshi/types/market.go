@@ -60,8 +60,8 @@ type MarketData struct {
 	FeeWaiverExpirationTime time.Time    `json:"fee_waiver_expiration_time"`
 	EarlyCloseCondition     string       `json:"early_close_condition"`
 	StrikeType              string       `json:"strike_type"`
-	FloorStrike             float64      `json:"floor_strike"`
-	CapStrike               float64      `json:"cap_strike"`
+	FloorStrike             *float64     `json:"floor_strike"` // Absent unless the strike type uses it
+	CapStrike               *float64     `json:"cap_strike"`
 	FunctionalStrike        string       `json:"functional_strike"`
 	CustomStrike            interface{}  `json:"custom_strike"`
 	MveCollectionTicker     string       `json:"mve_collection_ticker"`
@@ -97,4 +97,12 @@ type SimplifiedMarket struct {
 	Status        string    `json:"status"`
 	Category      string    `json:"category"`
 	CloseTime     time.Time `json:"close_time"`
+	// FeeWaiverExpirationTime is when a promotional fee waiver ends (zero if none)
+	FeeWaiverExpirationTime time.Time `json:"fee_waiver_expiration_time"`
+	RulesPrimary            string    `json:"rules_primary"`
+	RulesSecondary          string    `json:"rules_secondary"`
+	// StrikeType says how the market's outcome depends on the strikes, e.g. greater
+	StrikeType  string   `json:"strike_type"`
+	FloorStrike *float64 `json:"floor_strike"`
+	CapStrike   *float64 `json:"cap_strike"`
 }
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"time"
+)
+
+// Orderbook holds the resting bids on both sides of a market.
+// Each level is a [price_in_cents, quantity] pair sorted by ascending price.
+// Kalshi only publishes bids: a YES ask at price p is a NO bid at 100-p.
+type Orderbook struct {
+	Yes [][2]int `json:"yes"`
+	No  [][2]int `json:"no"`
+}
+
+// Order represents an order as reported by the exchange
+type Order struct {
+	OrderID        string    `json:"order_id"`
+	ClientOrderID  string    `json:"client_order_id"`
+	Ticker         string    `json:"ticker"`
+	Side           string    `json:"side"`
+	Action         string    `json:"action"`
+	Type           string    `json:"type"`
+	Status         string    `json:"status"` // resting, canceled, executed, pending
+	YesPrice       int       `json:"yes_price"`
+	NoPrice        int       `json:"no_price"`
+	InitialCount   int       `json:"initial_count"`
+	FillCount      int       `json:"fill_count"`
+	RemainingCount int       `json:"remaining_count"`
+	TakerFees      int       `json:"taker_fees"`
+	MakerFees      int       `json:"maker_fees"`
+	CreatedTime    time.Time `json:"created_time"`
+}
+
+// Fill represents a single execution against one of our orders
+type Fill struct {
+	FillID      string    `json:"fill_id"`
+	TradeID     string    `json:"trade_id"`
+	OrderID     string    `json:"order_id"`
+	Ticker      string    `json:"ticker"`
+	Side        string    `json:"side"`
+	Action      string    `json:"action"`
+	Count       int       `json:"count"`
+	YesPrice    int       `json:"yes_price"`
+	NoPrice     int       `json:"no_price"`
+	IsTaker     bool      `json:"is_taker"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// Settlement is the payout we received when a market we held resolved
+type Settlement struct {
+	Ticker       string    `json:"ticker"`
+	MarketResult string    `json:"market_result"` // yes, no, void
+	YesCount     int       `json:"yes_count"`
+	YesTotalCost int       `json:"yes_total_cost"`
+	NoCount      int       `json:"no_count"`
+	NoTotalCost  int       `json:"no_total_cost"`
+	Revenue      int       `json:"revenue"` // Cents
+	SettledTime  time.Time `json:"settled_time"`
+}
@@ -1,14 +1,17 @@
 package manager
 
 import (
-	"encoding/json"
 	"log"
 	"strconv"
+	"time"
 
 	"backend/internal/db"
 	"backend/internal/embeddings"
 	"backend/internal/kalshi"
+	"backend/internal/scheduler"
+	"backend/internal/slm"
 	"backend/internal/sync"
+	"backend/internal/vectorstore"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,11 +20,20 @@ import (
 type SearchRequest struct {
 	Query string `json:"query" binding:"required"`
 	Limit int    `json:"limit"`
+	Mode  string `json:"mode"` // hybrid (default), semantic or keyword
+
+	// Optional filters, applied before ranking
+	Provider     string     `json:"provider"` // Provider name, e.g. "kalshi"
+	Category     string     `json:"category"`
+	Status       string     `json:"status"`
+	CloseAfter   *time.Time `json:"close_after"`
+	CloseBefore  *time.Time `json:"close_before"`
+	ExcludeEvent string     `json:"exclude_event"` // Event ticker whose markets are left out
 }
 
 type MarketWithScore struct {
 	db.Market
-	Score float32 `json:"score"`
+	Score float32 `json:"score"` // Lower is better; see vectorstore.Result
 }
 
 type Handler struct {
@@ -29,15 +41,21 @@ type Handler struct {
 	KClient          *kalshi.Client
 	EmbeddingService embeddings.Service
 	SyncService      *sync.Syncer
+	Scheduler        *scheduler.Scheduler
+	Vectors          vectorstore.Store
+	Traces           *slm.TraceStore
 }
 
 // NewHandler creates a new manager Handler instance
-func NewHandler(database *gorm.DB, kClient *kalshi.Client, embeddingService embeddings.Service, syncer *sync.Syncer) *Handler {
+func NewHandler(database *gorm.DB, kClient *kalshi.Client, embeddingService embeddings.Service, syncer *sync.Syncer, sched *scheduler.Scheduler) *Handler {
 	return &Handler{
 		DB:               database,
 		KClient:          kClient,
 		EmbeddingService: embeddingService,
 		SyncService:      syncer,
+		Scheduler:        sched,
+		Vectors:          vectorstore.New(database),
+		Traces:           slm.NewTraceStore(database),
 	}
 }
 
@@ -235,17 +253,38 @@ func (h *Handler) GetMarketsByEvent(c *gin.Context) {
 
 // SearchMarkets performs a vector similarity search
 func (h *Handler) SearchMarkets(c *gin.Context) {
-	if h.EmbeddingService == nil {
-		c.JSON(503, gin.H{"error": "Embedding service not available"})
-		return
-	}
-
 	var req SearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.Mode == "" {
+		req.Mode = "hybrid"
+	}
+	if req.Mode != "hybrid" && req.Mode != "semantic" && req.Mode != "keyword" {
+		c.JSON(400, gin.H{"error": "mode must be hybrid, semantic or keyword"})
+		return
+	}
+	// Without an embedding service there are no query vectors, and while the index is
+	// rebuilt for a new model they can't be compared with the active index's, so
+	// hybrid search falls back to keywords
+	if req.Mode != "keyword" {
+		unavailable := ""
+		if h.EmbeddingService == nil {
+			unavailable = "Embedding service not available"
+		} else if !vectorstore.Compatible(h.Vectors, h.EmbeddingService.Model(), h.EmbeddingService.Dimension()) {
+			unavailable = "Semantic index is being rebuilt"
+		}
+		if unavailable != "" {
+			if req.Mode == "semantic" {
+				c.JSON(503, gin.H{"error": unavailable})
+				return
+			}
+			req.Mode = "keyword"
+		}
+	}
+
 	if req.Limit <= 0 {
 		req.Limit = 10 // Default limit
 	}
@@ -253,39 +292,51 @@ func (h *Handler) SearchMarkets(c *gin.Context) {
 		req.Limit = 50 // Max limit
 	}
 
-	// 1. Generate embedding for query
-	queryVec, err := h.EmbeddingService.Generate(req.Query)
-	if err != nil {
-		log.Println("Error generating query embedding:", err)
-		c.JSON(500, gin.H{"error": "Failed to process query"})
-		return
+	filters := vectorstore.Filters{
+		Category:           req.Category,
+		Status:             req.Status,
+		ExcludeEventTicker: req.ExcludeEvent,
 	}
-
-	// Marshal query vector to JSON string
-	queryVecBytes, _ := json.Marshal(queryVec)
-	queryVecString := string(queryVecBytes)
-
-	// 2. Perform vector search
-	// We select rowid (which maps to market ID) and distance
-	type SearchResult struct {
-		ID       uint
-		Distance float64
+	if req.CloseAfter != nil {
+		filters.CloseAfter = *req.CloseAfter
+	}
+	if req.CloseBefore != nil {
+		filters.CloseBefore = *req.CloseBefore
+	}
+	if req.Provider != "" {
+		var provider db.Provider
+		if err := h.DB.Where("name = ?", req.Provider).Limit(1).Find(&provider).Error; err != nil || provider.ID == 0 {
+			c.JSON(400, gin.H{"error": "Unknown provider"})
+			return
+		}
+		filters.ProviderID = provider.ID
 	}
-	var results []SearchResult
 
-	// Note: sqlite-vec uses 'distance' as the column for the score in `vec0` queries.
-	// We scan directly into our struct.
-	// We select 'rowid' which is the primary key in vec0 tables and map it to our ID.
-	err = h.DB.Raw(`
-		SELECT rowid as id, distance 
-		FROM vec_markets 
-		WHERE embedding MATCH ? 
-		AND k = ?
-		ORDER BY distance
-	`, queryVecString, req.Limit).Scan(&results).Error
+	// 1. Generate embedding for query
+	var queryVec []float32
+	if req.Mode != "keyword" {
+		var err error
+		queryVec, err = h.EmbeddingService.Generate(req.Query)
+		if err != nil {
+			log.Println("Error generating query embedding:", err)
+			c.JSON(500, gin.H{"error": "Failed to process query"})
+			return
+		}
+	}
 
+	// 2. Perform the search
+	var results []vectorstore.Result
+	var err error
+	switch req.Mode {
+	case "semantic":
+		results, err = h.Vectors.Search(queryVec, req.Limit, filters)
+	case "keyword":
+		results, err = h.Vectors.SearchText(req.Query, req.Limit, filters)
+	default:
+		results, err = vectorstore.Hybrid(h.Vectors, queryVec, req.Query, req.Limit, filters)
+	}
 	if err != nil {
-		log.Println("Error performing vector search:", err)
+		log.Println("Error performing market search:", err)
 		c.JSON(500, gin.H{"error": "Search failed"})
 		return
 	}
@@ -300,7 +351,7 @@ func (h *Handler) SearchMarkets(c *gin.Context) {
 	scoreMap := make(map[uint]float64)
 	for _, r := range results {
 		marketIDs = append(marketIDs, r.ID)
-		scoreMap[r.ID] = r.Distance
+		scoreMap[r.ID] = r.Score
 	}
 
 	var dbMarkets []db.Market
@@ -323,7 +374,7 @@ func (h *Handler) SearchMarkets(c *gin.Context) {
 		if m, exists := marketMap[r.ID]; exists {
 			response = append(response, MarketWithScore{
 				Market: m,
-				Score:  float32(r.Distance),
+				Score:  float32(r.Score),
 			})
 		}
 	}
@@ -331,11 +382,14 @@ func (h *Handler) SearchMarkets(c *gin.Context) {
 	c.JSON(200, gin.H{"markets": response})
 }
 
-// RunSyncCycle performs the market sync and arbitrage calculation
-func (h *Handler) RunSyncCycle() {
-	if h.SyncService != nil {
-		h.SyncService.RunCycle()
-	} else {
-		log.Println("Sync service not available, skipping cycle.")
+// TriggerEventSync starts the event sync job in the background.
+// With ?full=true the whole event list is crawled from the start.
+func (h *Handler) TriggerEventSync(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(503, gin.H{"error": "Scheduler not available"})
+		return
 	}
+	full := c.Query("full") == "true"
+
+	h.startJob(c, JobEventSync, map[string]string{"full": strconv.FormatBool(full)})
 }
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/db"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	sqlite_vec.Auto()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func TestSearchMarketsWithoutEmbeddings(t *testing.T) {
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "manager.db"))
+	database, err := db.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.MigrateUp(database); err != nil {
+		t.Fatal(err)
+	}
+	provider := db.Provider{Name: "kalshi"}
+	database.Create(&provider)
+	database.Create(&db.Market{ProviderID: provider.ID, ExternalID: "KXFED-26DEC-T4.00", Ticker: "KXFED-26DEC-T4.00",
+		Title: "Will the Fed cut rates in December?", Status: "active"})
+
+	h := NewHandler(database, nil, nil, nil, nil)
+	router := gin.New()
+	router.POST("/search", h.SearchMarkets)
+
+	tests := []struct {
+		mode        string
+		wantStatus  int
+		wantMarkets int
+	}{
+		{"", 200, 1}, // Hybrid by default, falling back to keywords
+		{"hybrid", 200, 1},
+		{"keyword", 200, 1},
+		{"semantic", 503, 0},
+	}
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			body, _ := json.Marshal(SearchRequest{Query: "fed rates", Mode: tt.mode})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/search", bytes.NewReader(body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var resp struct {
+				Markets []MarketWithScore `json:"markets"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if len(resp.Markets) != tt.wantMarkets {
+				t.Errorf("%d markets, want %d: %s", len(resp.Markets), tt.wantMarkets, w.Body)
+			}
+		})
+	}
+}
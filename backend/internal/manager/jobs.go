@@ -0,0 +1,323 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/scheduler"
+	"backend/internal/slm"
+	"backend/internal/sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job names
+const (
+	JobEventSync                = "event_sync"
+	JobPriceRefresh             = "price_refresh"
+	JobAnalysis                 = "analysis"
+	JobSettlementReconciliation = "settlement_reconciliation"
+	JobEmbeddingPrune           = "embedding_prune"
+	JobBalanceSnapshot          = "balance_snapshot"
+	JobSLMCallPrune             = "slm_call_prune"
+)
+
+// defaultSchedules are used unless JOB_<NAME>_SCHEDULE overrides them,
+// e.g. JOB_EVENT_SYNC_SCHEDULE="*/30 * * * *"
+var defaultSchedules = map[string]string{
+	// Full crawl daily, incremental otherwise (see sync.FullSyncInterval)
+	JobEventSync: "0 * * * *",
+	// Market status and quotes change far faster than event metadata
+	JobPriceRefresh: "*/5 * * * *",
+	// Related market analysis is expensive; its results are cached for 3h and each
+	// cycle is capped by SLM_CYCLE_TIME_BUDGET (2h by default)
+	JobAnalysis:                 "20 */3 * * *",
+	JobSettlementReconciliation: "40 * * * *",
+	JobEmbeddingPrune:           "10 4 * * *",
+	// Balance snapshots feed the dashboard equity curve
+	JobBalanceSnapshot: "*/15 * * * *",
+	JobSLMCallPrune:    "30 4 * * *",
+}
+
+// RegisterJobs adds the manager's background jobs to the scheduler
+func RegisterJobs(sched *scheduler.Scheduler, syncer *sync.Syncer) error {
+	jobs := []scheduler.Job{
+		{
+			Name:       JobEventSync,
+			RunOnStart: true,
+			// Manual runs accept full=true to crawl every event from the start
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				_, err := syncer.SyncEvents(ctx, run.Arg("full") == "true", run)
+				return skipUnconfigured(err)
+			},
+		},
+		{
+			Name: JobPriceRefresh,
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				_, err := syncer.RefreshMarkets(ctx, run)
+				return skipUnconfigured(err)
+			},
+		},
+		{
+			Name: JobAnalysis,
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				if syncer.EmbeddingService == nil {
+					return scheduler.Skip("embedding service not available")
+				}
+				_, err := syncer.AnalyzeRelatedMarkets(ctx, run)
+				return skipUnconfigured(err)
+			},
+		},
+		{
+			Name: JobSettlementReconciliation,
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				_, err := syncer.ReconcileSettlements(ctx, run)
+				return skipUnconfigured(err)
+			},
+		},
+		{
+			Name: JobEmbeddingPrune,
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				if syncer.EmbeddingService == nil {
+					return scheduler.Skip("embedding service not available")
+				}
+				pruned, err := syncer.PruneStaleEmbeddings()
+				run.Set("embeddings_pruned", pruned)
+				return err
+			},
+		},
+		{
+			Name:       JobBalanceSnapshot,
+			RunOnStart: true,
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				syncer.SnapshotBalances()
+				return nil
+			},
+		},
+		{
+			Name: JobSLMCallPrune,
+			// Recorded SLM calls are kept for SLM_CALL_RETENTION (30 days by default)
+			Run: func(ctx context.Context, run *scheduler.Run) error {
+				retention := slm.DefaultTraceRetention
+				if d, err := time.ParseDuration(os.Getenv("SLM_CALL_RETENTION")); err == nil && d > 0 {
+					retention = d
+				}
+				pruned, err := slm.NewTraceStore(syncer.DB).Prune(retention)
+				run.Set("slm_calls_pruned", pruned)
+				return err
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		job.Spec = defaultSchedules[job.Name]
+		if override := os.Getenv("JOB_" + strings.ToUpper(job.Name) + "_SCHEDULE"); override != "" {
+			job.Spec = override
+		}
+		if err := sched.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipUnconfigured records work that needs the exchange as skipped rather than
+// failed when no Kalshi client is configured
+func skipUnconfigured(err error) error {
+	if errors.Is(err, sync.ErrNoKalshiClient) {
+		return scheduler.Skip(err.Error())
+	}
+	return err
+}
+
+// JobRunView is a job run as returned by the API, with its counters decoded
+type JobRunView struct {
+	ID          uint           `json:"id"`
+	Job         string         `json:"job"`
+	TriggeredBy string         `json:"triggered_by"`
+	Status      string         `json:"status"`
+	Counts      map[string]int `json:"counts"`
+	Error       string         `json:"error,omitempty"`
+	StartedAt   time.Time      `json:"started_at"`
+	FinishedAt  *time.Time     `json:"finished_at"`
+	HeartbeatAt time.Time      `json:"heartbeat_at"`
+}
+
+// JobView is a job with its schedule, any run in progress and its last finished run
+type JobView struct {
+	Name     string      `json:"name"`
+	Schedule string      `json:"schedule"`
+	NextRun  time.Time   `json:"next_run"`
+	Running  *JobRunView `json:"running"`
+	LastRun  *JobRunView `json:"last_run"`
+}
+
+// newJobRunView decodes a recorded run. Counters of a run in progress in this
+// process are taken from live, since the stored ones lag by up to a heartbeat.
+func newJobRunView(r db.JobRun, live *scheduler.Run) *JobRunView {
+	view := &JobRunView{
+		ID:          r.ID,
+		Job:         r.Job,
+		TriggeredBy: r.TriggeredBy,
+		Status:      r.Status,
+		Counts:      map[string]int{},
+		Error:       r.Error,
+		StartedAt:   r.StartedAt,
+		FinishedAt:  r.FinishedAt,
+		HeartbeatAt: r.HeartbeatAt,
+	}
+	if live != nil && live.ID == r.ID {
+		view.Counts = live.Counts()
+	} else if err := json.Unmarshal([]byte(r.Counts), &view.Counts); err != nil {
+		log.Printf("Failed to decode counts of job run %d: %v", r.ID, err)
+	}
+	return view
+}
+
+// ListJobs returns every job with its schedule and latest runs
+func (h *Handler) ListJobs(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(503, gin.H{"error": "Scheduler not available"})
+		return
+	}
+
+	now := time.Now()
+	var jobs []JobView
+	for _, job := range h.Scheduler.Jobs() {
+		view := JobView{Name: job.Name, Schedule: job.Spec, NextRun: job.Next(now)}
+		live := h.Scheduler.Running(job.Name)
+
+		// A run in progress may belong to another instance, so go by the recorded runs
+		var running db.JobRun
+		if err := h.DB.Where("job = ? AND status = ?", job.Name, scheduler.StatusRunning).
+			Order("started_at DESC").Limit(1).Find(&running).Error; err != nil {
+			log.Println("Error fetching running job:", err)
+			c.JSON(500, gin.H{"error": "Failed to fetch jobs"})
+			return
+		}
+		if running.ID != 0 {
+			view.Running = newJobRunView(running, live)
+		}
+
+		var last db.JobRun
+		if err := h.DB.Where("job = ? AND status != ?", job.Name, scheduler.StatusRunning).
+			Order("started_at DESC").Limit(1).Find(&last).Error; err != nil {
+			log.Println("Error fetching last job run:", err)
+			c.JSON(500, gin.H{"error": "Failed to fetch jobs"})
+			return
+		}
+		if last.ID != 0 {
+			view.LastRun = newJobRunView(last, nil)
+		}
+
+		jobs = append(jobs, view)
+	}
+
+	c.JSON(200, gin.H{"jobs": jobs})
+}
+
+// GetJobRuns returns a job's most recent runs, newest first
+func (h *Handler) GetJobRuns(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(503, gin.H{"error": "Scheduler not available"})
+		return
+	}
+	name := c.Param("name")
+	if _, ok := h.Scheduler.Job(name); !ok {
+		c.JSON(404, gin.H{"error": "Unknown job"})
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = min(l, 100)
+	}
+
+	var runs []db.JobRun
+	if err := h.DB.Where("job = ?", name).Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		log.Println("Error fetching job runs:", err)
+		c.JSON(500, gin.H{"error": "Failed to fetch job runs"})
+		return
+	}
+
+	live := h.Scheduler.Running(name)
+	views := make([]*JobRunView, len(runs))
+	for i, r := range runs {
+		views[i] = newJobRunView(r, live)
+	}
+	c.JSON(200, gin.H{"runs": views})
+}
+
+// RunJob starts a job in the background. Query parameters are passed to the
+// job as options, e.g. POST /jobs/event_sync/run?full=true.
+func (h *Handler) RunJob(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(503, gin.H{"error": "Scheduler not available"})
+		return
+	}
+
+	args := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		args[key] = values[0]
+	}
+	h.startJob(c, c.Param("name"), args)
+}
+
+func (h *Handler) startJob(c *gin.Context, name string, args map[string]string) {
+	run, err := h.Scheduler.Start(name, scheduler.TriggerManual, args)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		c.JSON(404, gin.H{"error": "Unknown job"})
+		return
+	case errors.Is(err, scheduler.ErrLocked):
+		c.JSON(409, gin.H{"error": "Job is already running"})
+		return
+	case err != nil:
+		log.Printf("Failed to start job %s: %v", name, err)
+		c.JSON(500, gin.H{"error": "Failed to start job"})
+		return
+	}
+
+	c.JSON(202, gin.H{"run": JobRunView{
+		ID:          run.ID,
+		Job:         run.Job,
+		TriggeredBy: run.TriggeredBy,
+		Status:      scheduler.StatusRunning,
+		Counts:      run.Counts(),
+		StartedAt:   run.StartedAt,
+		HeartbeatAt: run.StartedAt,
+	}})
+}
+
+// CancelJob cancels a job's run in progress. The job stops at its next
+// checkpoint, so the run may take a moment to be recorded as canceled.
+func (h *Handler) CancelJob(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(503, gin.H{"error": "Scheduler not available"})
+		return
+	}
+	name := c.Param("name")
+
+	run, err := h.Scheduler.Cancel(name)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		c.JSON(404, gin.H{"error": "Unknown job"})
+		return
+	case errors.Is(err, scheduler.ErrNotRunning):
+		c.JSON(409, gin.H{"error": "Job is not running on this instance"})
+		return
+	case err != nil:
+		log.Printf("Failed to cancel job %s: %v", name, err)
+		c.JSON(500, gin.H{"error": "Failed to cancel job"})
+		return
+	}
+
+	c.JSON(202, gin.H{"status": "canceling", "run_id": run.ID})
+}
@@ -0,0 +1,171 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SLMCallView is a recorded SLM call as returned by the API, with its prompts,
+// replies and parsed result decoded
+type SLMCallView struct {
+	ID            uint            `json:"id"`
+	SourceTicker  string          `json:"source_ticker"`
+	TargetTicker  string          `json:"target_ticker"`
+	PromptVersion string          `json:"prompt_version"`
+	PromptHash    string          `json:"prompt_hash"`
+	Prompt        []string        `json:"prompt,omitempty"`  // Only when fetched by ID, and kept with SLM_LOG=full
+	Markets       json.RawMessage `json:"markets,omitempty"` // Only when fetched by ID
+	Backend       string          `json:"backend"`
+	Model         string          `json:"model"`
+	Temperature   float64         `json:"temperature"`
+	LatencyMs     int64           `json:"latency_ms"`
+	Tokens        int             `json:"tokens"`
+	Attempts      int             `json:"attempts"`
+	Output        []string        `json:"output,omitempty"` // Only when fetched by ID
+	Result        json.RawMessage `json:"result"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func newSLMCallView(call db.SLMCall, withOutput bool) *SLMCallView {
+	view := &SLMCallView{
+		ID:            call.ID,
+		SourceTicker:  call.SourceTicker,
+		TargetTicker:  call.TargetTicker,
+		PromptVersion: call.PromptVersion,
+		PromptHash:    call.PromptHash,
+		Backend:       call.Backend,
+		Model:         call.Model,
+		Temperature:   call.Temperature,
+		LatencyMs:     call.LatencyMs,
+		Tokens:        call.Tokens,
+		Attempts:      call.Attempts,
+		Result:        json.RawMessage("null"),
+		Error:         call.Error,
+		CreatedAt:     call.CreatedAt,
+	}
+	if call.Result != "" {
+		view.Result = json.RawMessage(call.Result)
+	}
+	if withOutput {
+		if call.Markets != "" {
+			view.Markets = json.RawMessage(call.Markets)
+		}
+		if call.Prompt != "" {
+			if err := json.Unmarshal([]byte(call.Prompt), &view.Prompt); err != nil {
+				log.Printf("Failed to decode prompts of SLM call %d: %v", call.ID, err)
+			}
+		}
+		if err := json.Unmarshal([]byte(call.Output), &view.Output); err != nil {
+			log.Printf("Failed to decode output of SLM call %d: %v", call.ID, err)
+		}
+	}
+	return view
+}
+
+// ListSLMCalls returns recorded SLM calls, newest first. Filters: ticker (either
+// market), backend, failed=true, and before=<id> to page back.
+func (h *Handler) ListSLMCalls(c *gin.Context) {
+	filter := slm.TraceFilter{
+		Ticker:  c.Query("ticker"),
+		Backend: c.Query("backend"),
+		Failed:  c.Query("failed") == "true",
+		Limit:   50,
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		filter.Limit = min(l, 500)
+	}
+	if b, err := strconv.ParseUint(c.Query("before"), 10, 64); err == nil {
+		filter.Before = uint(b)
+	}
+
+	calls, err := h.Traces.List(filter)
+	if err != nil {
+		log.Println("Error fetching SLM calls:", err)
+		c.JSON(500, gin.H{"error": "Failed to fetch SLM calls"})
+		return
+	}
+	views := make([]*SLMCallView, len(calls))
+	for i, call := range calls {
+		views[i] = newSLMCallView(call, false)
+	}
+	c.JSON(200, gin.H{"calls": views})
+}
+
+// GetSLMCall returns one recorded SLM call with the model's replies
+func (h *Handler) GetSLMCall(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid call ID"})
+		return
+	}
+	call, err := h.Traces.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": "SLM call not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching SLM call:", err)
+		c.JSON(500, gin.H{"error": "Failed to fetch SLM call"})
+		return
+	}
+	c.JSON(200, newSLMCallView(*call, true))
+}
+
+// SLMStatsView is the SLM service's usage since the manager started
+type SLMStatsView struct {
+	Comparisons   int64                 `json:"comparisons"`
+	ParseFailures int64                 `json:"parse_failures"`
+	Unparseable   int64                 `json:"unparseable"`
+	Rejected      int64                 `json:"rejected"`
+	Escalations   int64                 `json:"escalations"`
+	Backends      []SLMBackendStatsView `json:"backends"`
+}
+
+// SLMBackendStatsView is the usage of one SLM backend
+type SLMBackendStatsView struct {
+	Name      string `json:"name"`
+	Model     string `json:"model"`
+	Requests  int64  `json:"requests"`
+	Failures  int64  `json:"failures"`
+	Tokens    int64  `json:"tokens"`
+	LatencyMs int64  `json:"latency_ms"` // Total time spent in requests
+}
+
+// GetSLMStats returns comparison, escalation and per-backend usage counts
+func (h *Handler) GetSLMStats(c *gin.Context) {
+	if h.SyncService == nil || h.SyncService.SLMService == nil {
+		c.JSON(503, gin.H{"error": "SLM service not configured"})
+		return
+	}
+
+	stats := h.SyncService.SLMService.Stats()
+	view := SLMStatsView{
+		Comparisons:   stats.Comparisons,
+		ParseFailures: stats.ParseFailures,
+		Unparseable:   stats.Unparseable,
+		Rejected:      stats.Rejected,
+		Escalations:   stats.Escalations,
+		Backends:      make([]SLMBackendStatsView, len(stats.Backends)),
+	}
+	for i, b := range stats.Backends {
+		view.Backends[i] = SLMBackendStatsView{
+			Name:      b.Name,
+			Model:     b.Model,
+			Requests:  b.Requests,
+			Failures:  b.Failures,
+			Tokens:    b.Tokens,
+			LatencyMs: b.Latency.Milliseconds(),
+		}
+	}
+	c.JSON(200, view)
+}
@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+	"backend/internal/sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsService is an SLM service that only reports fixed usage
+type statsService struct {
+	stats slm.Stats
+}
+
+func (s statsService) CompareMarkets(source, target db.Market) (*slm.ComparisonResult, error) {
+	return nil, nil
+}
+
+func (s statsService) CompareMarketsAt(source, target db.Market, temperature float64) (*slm.ComparisonResult, error) {
+	return nil, nil
+}
+
+func (s statsService) Stats() slm.Stats {
+	return s.stats
+}
+
+func TestGetSLMStats(t *testing.T) {
+	service := statsService{slm.Stats{
+		Comparisons: 12, ParseFailures: 2, Unparseable: 1, Rejected: 3, Escalations: 4,
+		Backends: []slm.BackendStats{
+			{Name: "local", Model: "qwen3:14b", Requests: 12, Failures: 1, Tokens: 9000, Latency: 1500 * time.Millisecond},
+			{Name: "strong", Model: "gpt-4o", Requests: 4, Tokens: 2000, Latency: 800 * time.Millisecond},
+		},
+	}}
+
+	tests := []struct {
+		name       string
+		service    slm.Service
+		wantStatus int
+	}{
+		{"configured", service, 200},
+		{"not configured", nil, 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{SyncService: &sync.Syncer{SLMService: tt.service}}
+			router := gin.New()
+			router.GET("/slm/stats", h.GetSLMStats)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/slm/stats", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != 200 {
+				return
+			}
+
+			var got SLMStatsView
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Comparisons != 12 || got.ParseFailures != 2 || got.Unparseable != 1 || got.Rejected != 3 || got.Escalations != 4 {
+				t.Errorf("counts %+v, want the service's", got)
+			}
+			want := []SLMBackendStatsView{
+				{Name: "local", Model: "qwen3:14b", Requests: 12, Failures: 1, Tokens: 9000, LatencyMs: 1500},
+				{Name: "strong", Model: "gpt-4o", Requests: 4, Tokens: 2000, LatencyMs: 800},
+			}
+			if len(got.Backends) != len(want) {
+				t.Fatalf("backends %+v, want %+v", got.Backends, want)
+			}
+			for i := range want {
+				if got.Backends[i] != want[i] {
+					t.Errorf("backend %d: %+v, want %+v", i, got.Backends[i], want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// bitsOf is the bitset allowing values
+func bitsOf(values ...int) uint64 {
+	var bits uint64
+	for _, v := range values {
+		bits |= 1 << uint(v)
+	}
+	return bits
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		field   string
+		lo, hi  int
+		want    uint64
+		wantErr string
+	}{
+		{"*", 0, 6, bitsOf(0, 1, 2, 3, 4, 5, 6), ""},
+		{"5", 0, 59, bitsOf(5), ""},
+		{"1,15,30", 0, 59, bitsOf(1, 15, 30), ""},
+		{"9-12", 0, 23, bitsOf(9, 10, 11, 12), ""},
+		{"*/15", 0, 59, bitsOf(0, 15, 30, 45), ""},
+		{"*/5", 1, 12, bitsOf(1, 6, 11), ""},
+		{"9-17/4", 0, 23, bitsOf(9, 13, 17), ""},
+		{"10-20/7", 0, 59, bitsOf(10, 17), ""},
+		{"50/5", 0, 59, bitsOf(50, 55), ""}, // A start with a step runs to the end
+		{"1-5,0", 0, 7, bitsOf(0, 1, 2, 3, 4, 5), ""},
+		{"3-3", 0, 59, bitsOf(3), ""},
+		{"*/100", 0, 59, bitsOf(0), ""},
+		{"0", 0, 59, bitsOf(0), ""},
+		{"59", 0, 59, bitsOf(59), ""},
+		{"60", 0, 59, 0, "out of range"},
+		{"0", 1, 31, 0, "out of range"},
+		{"5-1", 0, 59, 0, "invalid range"},
+		{"1-60", 0, 59, 0, "out of range"},
+		{"*/0", 0, 59, 0, "invalid step"},
+		{"*/-1", 0, 59, 0, "invalid step"},
+		{"*/x", 0, 59, 0, "invalid step"},
+		{"", 0, 59, 0, "invalid value"},
+		{"1,", 0, 59, 0, "invalid value"},
+		{"-1", 0, 59, 0, "invalid value"},
+		{"1-", 0, 59, 0, "invalid value"},
+		{"MON", 0, 7, 0, "invalid value"},
+	}
+	for _, tt := range tests {
+		got, err := parseField(tt.field, tt.lo, tt.hi)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseField(%q, %d, %d): err = %v, want %q", tt.field, tt.lo, tt.hi, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseField(%q, %d, %d): %v", tt.field, tt.lo, tt.hi, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseField(%q, %d, %d) = %b, want %b", tt.field, tt.lo, tt.hi, got, tt.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"", "expected 5 fields, got 0"},
+		{"* * * *", "expected 5 fields, got 4"},
+		{"0 0 * * * *", "expected 5 fields, got 6"},
+		{"@yearly", "expected 5 fields"},
+		{"60 * * * *", "minute"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day of month"},
+		{"* * 32 * *", "day of month"},
+		{"* * * 13 *", "month"},
+		{"* * * * 8", "day of week"},
+		{"@every", "expected 5 fields"},
+		{"@every soon", "invalid schedule"},
+		{"@every 500ms", "at least 1s"},
+		{"@every -1m", "at least 1s"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSchedule(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseSchedule(%q): err = %v, want %q", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string // "" for never
+	}{
+		{"every minute", "* * * * *", "2026-10-16 10:07:30", "2026-10-16 10:08:00"},
+		{"strictly after", "* * * * *", "2026-10-16 10:07:00", "2026-10-16 10:08:00"},
+		{"minute step", "*/15 * * * *", "2026-10-16 10:07:30", "2026-10-16 10:15:00"},
+		{"minute step wraps the hour", "*/15 * * * *", "2026-10-16 10:45:00", "2026-10-16 11:00:00"},
+		{"hourly", "@hourly", "2026-10-16 10:00:00", "2026-10-16 11:00:00"},
+		{"hour range with step", "0 9-17/4 * * *", "2026-10-16 10:00:00", "2026-10-16 13:00:00"},
+		{"hour range ends for the day", "0 9-17/4 * * *", "2026-10-16 17:00:00", "2026-10-17 09:00:00"},
+		{"list", "0,30 6,18 * * *", "2026-10-16 06:30:00", "2026-10-16 18:00:00"},
+		{"daily wraps the month", "@daily", "2026-10-31 12:00:00", "2026-11-01 00:00:00"},
+		{"monthly wraps the year", "@monthly", "2026-12-15 00:00:00", "2027-01-01 00:00:00"},
+		{"weekdays skip the weekend", "30 2 * * 1-5", "2026-10-16 03:00:00", "2026-10-19 02:30:00"},
+		{"weekly is Sunday", "@weekly", "2026-10-14 08:00:00", "2026-10-18 00:00:00"},
+		{"7 is Sunday", "0 0 * * 7", "2026-10-14 08:00:00", "2026-10-18 00:00:00"},
+		{"range up to 7 includes Sunday", "0 0 * * 6-7", "2026-10-17 12:00:00", "2026-10-18 00:00:00"},
+		{"range from 0 is Sunday", "0 0 * * 0-1", "2026-10-17 12:00:00", "2026-10-18 00:00:00"},
+		{"day of month skips short months", "0 0 31 * *", "2026-04-01 00:00:00", "2026-05-31 00:00:00"},
+		{"leap day", "0 0 29 2 *", "2026-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"never", "0 0 31 2 *", "2026-01-01 00:00:00", ""},
+		// With both day fields restricted either one matching is enough
+		{"day of month or weekday, weekday first", "0 0 13 * 5", "2026-10-01 00:00:00", "2026-10-02 00:00:00"},
+		{"day of month or weekday, day first", "0 0 13 * 5", "2026-10-10 00:00:00", "2026-10-13 00:00:00"},
+		// With one restricted both must match, and * allows everything
+		{"day of month with any weekday", "0 0 13 * *", "2026-10-01 00:00:00", "2026-10-13 00:00:00"},
+		{"weekday with any day of month", "0 0 * * 5", "2026-10-10 00:00:00", "2026-10-16 00:00:00"},
+		// Every day counts as * however it's written
+		{"*/1 as any day of month", "0 0 */1 * 5", "2026-10-10 00:00:00", "2026-10-16 00:00:00"},
+		{"1-31 as any day of month", "0 0 1-31 * 5", "2026-10-10 00:00:00", "2026-10-16 00:00:00"},
+		{"0-6 as any weekday", "0 0 13 * 0-6", "2026-10-01 00:00:00", "2026-10-13 00:00:00"},
+		{"1-7 as any weekday", "0 0 13 * 1-7", "2026-10-01 00:00:00", "2026-10-13 00:00:00"},
+		{"month and weekday", "0 12 * 2 0", "2026-01-01 00:00:00", "2026-02-01 12:00:00"},
+		{"every interval", "@every 90s", "2026-10-16 10:07:30", "2026-10-16 10:09:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := schedule.Next(at(tt.from))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Next(%s) = %s, want never", tt.from, got)
+				}
+				return
+			}
+			if !got.Equal(at(tt.want)) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextInLocalTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"half-hour offset", "0 12 * * *",
+			time.Date(2026, 10, 16, 10, 0, 0, 0, time.FixedZone("IST", 5*3600+1800)),
+			time.Date(2026, 10, 16, 12, 0, 0, 0, time.FixedZone("IST", 5*3600+1800))},
+		{"quarter-hour offset", "30 * * * *",
+			time.Date(2026, 10, 16, 10, 40, 0, 0, time.FixedZone("NPT", 5*3600+2700)),
+			time.Date(2026, 10, 16, 11, 30, 0, 0, time.FixedZone("NPT", 5*3600+2700))},
+		{"clocks go back", "0 * * * *",
+			time.Date(2026, 11, 1, 1, 0, 0, 0, newYork).Add(-time.Minute), // 00:59 EDT
+			time.Date(2026, 11, 1, 1, 0, 0, 0, newYork)},
+		{"repeated hour runs again", "0 * * * *",
+			time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC),  // 01:00 EDT
+			time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC)}, // 01:00 EST
+		{"daily across clocks going forward", "0 9 * * *",
+			time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+			time.Date(2026, 3, 8, 9, 0, 0, 0, newYork)},
+		{"hourly across clocks going forward", "0 * * * *",
+			time.Date(2026, 3, 8, 1, 30, 0, 0, newYork),
+			time.Date(2026, 3, 8, 3, 0, 0, 0, newYork)},
+		{"hour skipped by clocks going forward", "30 2 * * *",
+			time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			time.Date(2026, 3, 9, 2, 30, 0, 0, newYork)},
+		{"midnight skipped by clocks going forward", "0 12 * * 0",
+			time.Date(2026, 9, 5, 13, 0, 0, 0, santiago),
+			time.Date(2026, 9, 6, 12, 0, 0, 0, santiago)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from := tt.from
+			if from.Location() == time.UTC {
+				from = from.In(newYork)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,475 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCanceled  = "canceled"
+
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+const (
+	// lockTTL is how long a job's lock outlives a process that stopped heartbeating
+	lockTTL = 2 * time.Minute
+	// heartbeatInterval is how often a running job extends its lock and saves its counts
+	heartbeatInterval = 30 * time.Second
+	// abandonedAfter is how stale a running job's heartbeat must be before the run
+	// is considered lost with its process
+	abandonedAfter = 5 * time.Minute
+)
+
+var (
+	// ErrUnknownJob is returned for a job name that was never registered
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrLocked is returned when a job is already running, here or on another instance
+	ErrLocked = errors.New("job is already running")
+	// ErrNotRunning is returned when canceling a job this process isn't running
+	ErrNotRunning = errors.New("job is not running on this instance")
+)
+
+// ErrSkipped marks a run that had nothing it could do, e.g. because a dependency
+// is not configured. Wrap it with Skip.
+var ErrSkipped = errors.New("skipped")
+
+// Skip returns an error that records the run as skipped rather than failed
+func Skip(reason string) error {
+	return fmt.Errorf("%w: %s", ErrSkipped, reason)
+}
+
+// JobFunc does a job's work, recording what it processed on run
+type JobFunc func(ctx context.Context, run *Run) error
+
+// Job is a named unit of background work. Jobs should stop early when their
+// context is canceled, which happens when a run is canceled or on shutdown.
+type Job struct {
+	Name string
+	Spec string // Schedule, see ParseSchedule
+	// RunOnStart runs the job as soon as the scheduler starts, then on schedule
+	RunOnStart bool
+	Run        JobFunc
+
+	schedule Schedule
+}
+
+// Next returns when the job is next due after t
+func (j Job) Next(t time.Time) time.Time {
+	return j.schedule.Next(t)
+}
+
+// Run is a job execution in progress
+type Run struct {
+	ID          uint
+	Job         string
+	TriggeredBy string
+	StartedAt   time.Time
+	// Args are options for a manually triggered run, e.g. full=true for event_sync
+	Args map[string]string
+
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Arg returns one of the run's options, or "" if it wasn't given
+func (r *Run) Arg(key string) string {
+	return r.Args[key]
+}
+
+// Add increments one of the run's counters
+func (r *Run) Add(key string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[key] += n
+}
+
+// Set overwrites one of the run's counters
+func (r *Run) Set(key string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[key] = n
+}
+
+// Counts returns a copy of the run's counters
+func (r *Run) Counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int, len(r.counts))
+	for k, v := range r.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (r *Run) countsJSON() string {
+	data, err := json.Marshal(r.Counts())
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// Scheduler runs registered jobs on their schedules. Each run is recorded in
+// job_runs, and a lock per job keeps runs from overlapping: in Redis when it is
+// configured, so several manager instances can share the work, and always in-process.
+type Scheduler struct {
+	DB    *gorm.DB
+	Redis *db.Redis
+
+	jobs    map[string]*Job
+	mu      sync.Mutex
+	running map[string]*Run
+	ctx     context.Context // Parent of manually triggered runs
+	owner   string          // Identifies this process in lock tokens
+}
+
+// New creates a scheduler with no jobs. rdb may be nil, in which case runs are
+// only kept from overlapping within this process.
+func New(database *gorm.DB, rdb *db.Redis) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		DB:      database,
+		Redis:   rdb,
+		jobs:    make(map[string]*Job),
+		running: make(map[string]*Run),
+		ctx:     context.Background(),
+		owner:   fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Register adds a job. Names must be unique.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job %q needs a name and run function", job.Name)
+	}
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %q is already registered", job.Name)
+	}
+	schedule, err := ParseSchedule(job.Spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	job.schedule = schedule
+	s.jobs[job.Name] = &job
+	return nil
+}
+
+// Job returns a registered job by name
+func (s *Scheduler) Job(name string) (Job, bool) {
+	job, ok := s.jobs[name]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs returns the registered jobs ordered by name
+func (s *Scheduler) Jobs() []Job {
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Run starts every job on its schedule and blocks until the context is canceled.
+// Canceling the context also cancels manually triggered runs.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	s.failAbandonedRuns()
+
+	var wg sync.WaitGroup
+	for _, job := range s.Jobs() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	if job.RunOnStart {
+		s.runScheduled(ctx, job)
+	}
+
+	for {
+		next := job.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job %s has no upcoming run time; it will not run again", job.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.runScheduled(ctx, job)
+	}
+}
+
+func (s *Scheduler) runScheduled(ctx context.Context, job Job) {
+	if _, err := s.RunJob(ctx, job.Name, TriggerSchedule); errors.Is(err, ErrLocked) {
+		log.Printf("Skipping scheduled %s: already running", job.Name)
+	}
+}
+
+// RunJob runs a job now and waits for it to finish. It returns ErrLocked without
+// running anything if the job is already running; otherwise the job's own error.
+func (s *Scheduler) RunJob(ctx context.Context, name, trigger string) (*Run, error) {
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownJob, name)
+	}
+
+	runCtx, run, release, err := s.begin(ctx, job, trigger, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return run, s.execute(runCtx, job, run)
+}
+
+// Start runs a job in the background and returns as soon as it has started.
+// It returns ErrLocked if the job is already running.
+func (s *Scheduler) Start(name, trigger string, args map[string]string) (*Run, error) {
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownJob, name)
+	}
+
+	s.mu.Lock()
+	parent := s.ctx
+	s.mu.Unlock()
+
+	runCtx, run, release, err := s.begin(parent, job, trigger, args)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer release()
+		s.execute(runCtx, job, run)
+	}()
+	return run, nil
+}
+
+// Cancel cancels the job's run in progress. Only runs started by this process
+// can be canceled; ErrNotRunning is returned otherwise.
+func (s *Scheduler) Cancel(name string) (*Run, error) {
+	if _, ok := s.jobs[name]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownJob, name)
+	}
+
+	run := s.Running(name)
+	if run == nil {
+		return nil, ErrNotRunning
+	}
+	run.cancel()
+	log.Printf("Job %s run %d canceled", name, run.ID)
+	return run, nil
+}
+
+// Running returns the job's run in progress in this process, or nil
+func (s *Scheduler) Running(name string) *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[name]
+}
+
+// begin takes the job's locks and records the run as started. The returned
+// context is canceled when the run is canceled, and release must be called once
+// the run is over.
+func (s *Scheduler) begin(parent context.Context, job *Job, trigger string, args map[string]string) (context.Context, *Run, func(), error) {
+	// 1. In-process lock
+	s.mu.Lock()
+	if _, running := s.running[job.Name]; running {
+		s.mu.Unlock()
+		return nil, nil, nil, ErrLocked
+	}
+	ctx, cancel := context.WithCancel(parent)
+	run := &Run{
+		Job:         job.Name,
+		TriggeredBy: trigger,
+		StartedAt:   time.Now(),
+		Args:        args,
+		cancel:      cancel,
+		counts:      make(map[string]int),
+	}
+	s.running[job.Name] = run
+	s.mu.Unlock()
+
+	releaseLocal := func() {
+		cancel()
+		s.mu.Lock()
+		delete(s.running, job.Name)
+		s.mu.Unlock()
+	}
+
+	// 2. Distributed lock, so other instances don't run it at the same time
+	key := lockKey(job.Name)
+	token := fmt.Sprintf("%s-%d", s.owner, run.StartedAt.UnixNano())
+	if s.Redis != nil {
+		acquired, err := s.Redis.AcquireLock(key, token, lockTTL)
+		if err != nil {
+			releaseLocal()
+			return nil, nil, nil, fmt.Errorf("failed to lock job %s: %w", job.Name, err)
+		}
+		if !acquired {
+			releaseLocal()
+			return nil, nil, nil, ErrLocked
+		}
+	}
+
+	release := func() {
+		if s.Redis != nil {
+			if err := s.Redis.ReleaseLock(key, token); err != nil {
+				log.Printf("Failed to release lock for job %s: %v", job.Name, err)
+			}
+		}
+		releaseLocal()
+	}
+
+	// 3. Record the run
+	record := db.JobRun{
+		Job:         job.Name,
+		TriggeredBy: trigger,
+		Status:      StatusRunning,
+		Counts:      "{}",
+		StartedAt:   run.StartedAt,
+		HeartbeatAt: run.StartedAt,
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		release()
+		return nil, nil, nil, fmt.Errorf("failed to record run of job %s: %w", job.Name, err)
+	}
+	run.ID = record.ID
+
+	// 4. Keep the lock alive for as long as the job runs
+	done := make(chan struct{})
+	go s.heartbeat(run, key, token, done)
+
+	return ctx, run, func() {
+		close(done)
+		release()
+	}, nil
+}
+
+// execute runs the job and records how it ended
+func (s *Scheduler) execute(ctx context.Context, job *Job, run *Run) (err error) {
+	log.Printf("Job %s started (run %d)", job.Name, run.ID)
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+
+		status, message := StatusSucceeded, ""
+		switch {
+		case err == nil:
+			// A job that finished before noticing it was canceled still succeeded
+		case ctx.Err() != nil:
+			// Canceled on request or by shutdown
+			status, message = StatusCanceled, err.Error()
+		case errors.Is(err, ErrSkipped):
+			status, message = StatusSkipped, err.Error()
+		default:
+			status, message = StatusFailed, err.Error()
+		}
+
+		finished := time.Now()
+		if dbErr := s.DB.Model(&db.JobRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+			"status":       status,
+			"counts":       run.countsJSON(),
+			"error":        message,
+			"finished_at":  finished,
+			"heartbeat_at": finished,
+		}).Error; dbErr != nil {
+			log.Printf("Failed to record end of job %s run %d: %v", job.Name, run.ID, dbErr)
+		}
+
+		if message != "" {
+			log.Printf("Job %s %s after %v: %s", job.Name, status, finished.Sub(run.StartedAt).Round(time.Millisecond), message)
+		} else {
+			log.Printf("Job %s %s in %v %s", job.Name, status, finished.Sub(run.StartedAt).Round(time.Millisecond), run.countsJSON())
+		}
+	}()
+
+	return job.Run(ctx, run)
+}
+
+// heartbeat extends the run's lock and saves its counts until done is closed
+func (s *Scheduler) heartbeat(run *Run, key, token string, done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		if s.Redis != nil {
+			held, err := s.Redis.ExtendLock(key, token, lockTTL)
+			if err != nil {
+				log.Printf("Failed to extend lock for job %s: %v", run.Job, err)
+			} else if !held {
+				log.Printf("Job %s lost its lock; another instance may start it", run.Job)
+			}
+		}
+
+		if err := s.DB.Model(&db.JobRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+			"counts":       run.countsJSON(),
+			"heartbeat_at": time.Now(),
+		}).Error; err != nil {
+			log.Printf("Failed to record heartbeat of job %s run %d: %v", run.Job, run.ID, err)
+		}
+	}
+}
+
+// failAbandonedRuns closes out runs left running by a process that died
+func (s *Scheduler) failAbandonedRuns() {
+	result := s.DB.Model(&db.JobRun{}).
+		Where("status = ? AND heartbeat_at < ?", StatusRunning, time.Now().Add(-abandonedAfter)).
+		Updates(map[string]interface{}{
+			"status":      StatusFailed,
+			"error":       "abandoned: the process running it stopped",
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("Failed to close abandoned job runs: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Marked %d abandoned job runs as failed", result.RowsAffected)
+	}
+}
+
+func lockKey(job string) string {
+	return "job:lock:" + job
+}
@@ -0,0 +1,268 @@
+package slm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"backend/internal/db"
+)
+
+// Checks decides how much agreement an implication needs before it is trusted. A
+// false implication is a direct money-loser, so by default one answer isn't enough.
+type Checks struct {
+	// Reverse also compares the target against the source and requires the
+	// contrapositive: if A=YES => B=YES, then B=NO => A=NO
+	Reverse bool
+	// Samples is how many answers are asked for in each direction, the first at
+	// temperature 0 and the rest at Temperature
+	Samples     int
+	Temperature float64
+	// MinConfidence is the share of answers in each direction that must agree on
+	// an implication for it to be kept
+	MinConfidence float64
+}
+
+// Defaults for the consistency checks
+const (
+	DefaultSamples       = 1
+	DefaultTemperature   = 0.7
+	DefaultMinConfidence = 0.6
+)
+
+func (c Checks) enabled() bool {
+	return c.Reverse || c.Samples > 1
+}
+
+// checkedService asks the wrapped service several times, in both directions, and
+// keeps only the implications enough answers agree on
+type checkedService struct {
+	Service
+	checks   Checks
+	rejected atomic.Int64
+}
+
+// NewCheckedService wraps a service so its comparisons pass the given checks.
+// Confidence is recorded on every implication kept.
+func NewCheckedService(service Service, checks Checks) Service {
+	if checks.Samples < 1 {
+		checks.Samples = 1
+	}
+	if checks.MinConfidence <= 0 {
+		checks.MinConfidence = DefaultMinConfidence
+	}
+	return &checkedService{Service: service, checks: checks}
+}
+
+func (c *checkedService) Stats() Stats {
+	stats := c.Service.Stats()
+	stats.Rejected = c.rejected.Load()
+	return stats
+}
+
+func (c *checkedService) CompareMarkets(source, target db.Market) (*ComparisonResult, error) {
+	return c.CompareMarketsAt(source, target, 0)
+}
+
+// CompareMarketsAt runs the same checks with the first answer in each direction
+// asked at temperature rather than 0
+func (c *checkedService) CompareMarketsAt(source, target db.Market, temperature float64) (*ComparisonResult, error) {
+	// 1. Ask how target relates to source
+	forward, tokens, err := c.sample(source, target, temperature)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. And how source relates to target
+	var reverse []*ComparisonResult
+	if c.checks.Reverse {
+		var spent int
+		reverse, spent, err = c.sample(target, source, temperature)
+		tokens += spent
+		if err != nil {
+			return nil, fmt.Errorf("reverse comparison failed: %w", err)
+		}
+	}
+
+	// 3. Keep the implications enough answers agree on
+	var result ComparisonResult
+	for _, r := range forward {
+		if r != nil {
+			result = *r
+			break
+		}
+	}
+	result.SourceYes, result.SourceYesConfidence = c.vote(forward, reverse, "yes")
+	result.SourceNo, result.SourceNoConfidence = c.vote(forward, reverse, "no")
+	if result.SourceYes != nil && result.SourceNo != nil && *result.SourceYes == *result.SourceNo {
+		// Whatever the source does the target would resolve one way, which only
+		// holds for a target already decided: the answers are confused
+		result.SourceYes, result.SourceNo = nil, nil
+		result.SourceYesConfidence, result.SourceNoConfidence = 0, 0
+	}
+	result.TokensUsed = tokens
+	result.Disagreements = disagreements(forward, reverse, &result)
+
+	// 4. Count what a single answer would have stored but the checks dropped
+	if first := forward[0]; first != nil {
+		rejected := 0
+		if first.SourceYes != nil && !sameOutcome(first.SourceYes, result.SourceYes) {
+			rejected++
+		}
+		if first.SourceNo != nil && !sameOutcome(first.SourceNo, result.SourceNo) {
+			rejected++
+		}
+		if rejected > 0 {
+			c.rejected.Add(int64(rejected))
+			log.Printf("[SLM] Consistency checks dropped %d implication(s) of %s vs %s. Forward: %s. Reverse: %s",
+				rejected, source.ExternalID, target.ExternalID, summarize(forward), summarize(reverse))
+		}
+	}
+	return &result, nil
+}
+
+// sample asks for the configured number of answers, the first at temperature and the
+// rest at the checks' sampling temperature. A failed sample counts as an answer
+// implying nothing; only when all of them fail is the comparison failed.
+func (c *checkedService) sample(source, target db.Market, temperature float64) ([]*ComparisonResult, int, error) {
+	answers := make([]*ComparisonResult, c.checks.Samples)
+	tokens, failed := 0, 0
+	var lastErr error
+	for i := range answers {
+		var err error
+		if i == 0 {
+			answers[i], err = c.Service.CompareMarketsAt(source, target, temperature)
+		} else {
+			answers[i], err = c.Service.CompareMarketsAt(source, target, c.checks.Temperature)
+		}
+		if err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		tokens += answers[i].TokensUsed
+	}
+	if failed == len(answers) {
+		return nil, tokens, lastErr
+	}
+	return answers, tokens, nil
+}
+
+// vote picks the target outcome implied by the source resolving sourceOutcome
+// (yes or no), if enough answers agree on it in both directions, with its
+// confidence: the smaller share of agreeing answers of the two directions
+func (c *checkedService) vote(forward, reverse []*ComparisonResult, sourceOutcome string) (*string, float64) {
+	var best *string
+	bestConfidence := 0.0
+	for _, targetOutcome := range []string{"target_yes", "target_no"} {
+		confidence := agreement(forward, sourceOutcome, targetOutcome)
+		if c.checks.Reverse {
+			// The contrapositive, seen from the target: target resolving the other way
+			// implies the source resolves the other way
+			reverseSource, reverseTarget := contrapositive(sourceOutcome, targetOutcome)
+			confidence = min(confidence, agreement(reverse, reverseSource, reverseTarget))
+		}
+		if confidence >= c.checks.MinConfidence && confidence > bestConfidence {
+			outcome := targetOutcome
+			best, bestConfidence = &outcome, confidence
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	return best, bestConfidence
+}
+
+// disagreements counts the distinct implications, read in the forward direction,
+// that some answer gave but the result doesn't hold
+func disagreements(forward, reverse []*ComparisonResult, result *ComparisonResult) int {
+	given := map[[2]string]bool{}
+	for _, r := range forward {
+		for _, sourceOutcome := range []string{"yes", "no"} {
+			if r != nil && r.implied(sourceOutcome) != nil {
+				given[[2]string{sourceOutcome, *r.implied(sourceOutcome)}] = true
+			}
+		}
+	}
+	for _, r := range reverse {
+		for _, sourceOutcome := range []string{"yes", "no"} {
+			if r != nil && r.implied(sourceOutcome) != nil {
+				// The contrapositive turns a reverse implication back into a forward one
+				forwardSource, forwardTarget := contrapositive(sourceOutcome, *r.implied(sourceOutcome))
+				given[[2]string{forwardSource, forwardTarget}] = true
+			}
+		}
+	}
+	count := 0
+	for implication := range given {
+		if kept := result.implied(implication[0]); kept == nil || *kept != implication[1] {
+			count++
+		}
+	}
+	return count
+}
+
+// agreement is the share of answers in which the source resolving sourceOutcome
+// implies targetOutcome
+func agreement(answers []*ComparisonResult, sourceOutcome, targetOutcome string) float64 {
+	if len(answers) == 0 {
+		return 0
+	}
+	agreeing := 0
+	for _, r := range answers {
+		if r != nil && sameOutcome(r.implied(sourceOutcome), &targetOutcome) {
+			agreeing++
+		}
+	}
+	return float64(agreeing) / float64(len(answers))
+}
+
+// contrapositive turns "source resolves sourceOutcome => target resolves
+// targetOutcome" into the equivalent implication with source and target swapped
+func contrapositive(sourceOutcome, targetOutcome string) (string, string) {
+	reverseSource := "yes"
+	if targetOutcome == "target_yes" {
+		reverseSource = "no"
+	}
+	reverseTarget := "target_yes"
+	if sourceOutcome == "yes" {
+		reverseTarget = "target_no"
+	}
+	return reverseSource, reverseTarget
+}
+
+// implied is the target outcome the source resolving sourceOutcome implies
+func (r *ComparisonResult) implied(sourceOutcome string) *string {
+	if sourceOutcome == "yes" {
+		return r.SourceYes
+	}
+	return r.SourceNo
+}
+
+func sameOutcome(a, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// summarize lists answers for the log, e.g. "yes->target_no no->null"
+func summarize(answers []*ComparisonResult) string {
+	if len(answers) == 0 {
+		return "not asked"
+	}
+	parts := make([]string, len(answers))
+	for i, r := range answers {
+		if r == nil {
+			parts[i] = "failed"
+			continue
+		}
+		parts[i] = fmt.Sprintf("yes->%s no->%s", outcomeName(r.SourceYes), outcomeName(r.SourceNo))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func outcomeName(outcome *string) string {
+	if outcome == nil {
+		return "null"
+	}
+	return *outcome
+}
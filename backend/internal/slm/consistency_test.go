@@ -0,0 +1,339 @@
+package slm
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"backend/internal/db"
+)
+
+var errStub = errors.New("stub backend failed")
+
+// stubService answers from a script per direction, keyed by the source ticker,
+// and records the temperature of every call
+type stubService struct {
+	name         string
+	answers      map[string][]*ComparisonResult // nil for a failed answer
+	calls        map[string]int
+	temperatures []float64
+}
+
+func newStubService(name string, answers map[string][]*ComparisonResult) *stubService {
+	return &stubService{name: name, answers: answers, calls: map[string]int{}}
+}
+
+func (s *stubService) CompareMarkets(source, target db.Market) (*ComparisonResult, error) {
+	return s.CompareMarketsAt(source, target, 0)
+}
+
+func (s *stubService) CompareMarketsAt(source, target db.Market, temperature float64) (*ComparisonResult, error) {
+	s.temperatures = append(s.temperatures, temperature)
+	script := s.answers[source.ExternalID]
+	i := s.calls[source.ExternalID]
+	s.calls[source.ExternalID]++
+	if i >= len(script) || script[i] == nil {
+		return nil, errStub
+	}
+	result := *script[i]
+	result.Backend = s.name
+	return &result, nil
+}
+
+func (s *stubService) Stats() Stats {
+	total := 0
+	for _, n := range s.calls {
+		total += n
+	}
+	return Stats{Comparisons: int64(total), Backends: []BackendStats{{Name: s.name}}}
+}
+
+// implies is an answer giving the target outcomes for source yes and no, with
+// "" for no implication
+func implies(yes, no string) *ComparisonResult {
+	outcome := func(o string) *string {
+		if o == "" {
+			return nil
+		}
+		return &o
+	}
+	return &ComparisonResult{Reason: "stub", SourceYes: outcome(yes), SourceNo: outcome(no), TokensUsed: 10}
+}
+
+// repeat is n copies of an answer
+func repeat(n int, answer *ComparisonResult) []*ComparisonResult {
+	answers := make([]*ComparisonResult, n)
+	for i := range answers {
+		answers[i] = answer
+	}
+	return answers
+}
+
+func TestContrapositive(t *testing.T) {
+	tests := []struct {
+		sourceOutcome, targetOutcome string
+		wantSource, wantTarget       string
+	}{
+		// A=YES => B=YES is B=NO => A=NO
+		{"yes", "target_yes", "no", "target_no"},
+		// A=YES => B=NO is B=YES => A=NO
+		{"yes", "target_no", "yes", "target_no"},
+		// A=NO => B=YES is B=NO => A=YES
+		{"no", "target_yes", "no", "target_yes"},
+		// A=NO => B=NO is B=YES => A=YES
+		{"no", "target_no", "yes", "target_yes"},
+	}
+	for _, tt := range tests {
+		source, target := contrapositive(tt.sourceOutcome, tt.targetOutcome)
+		if source != tt.wantSource || target != tt.wantTarget {
+			t.Errorf("contrapositive(%s, %s) = %s, %s, want %s, %s",
+				tt.sourceOutcome, tt.targetOutcome, source, target, tt.wantSource, tt.wantTarget)
+		}
+		// Taking it twice gets back where we started
+		if s, o := contrapositive(source, target); s != tt.sourceOutcome || o != tt.targetOutcome {
+			t.Errorf("contrapositive of contrapositive(%s, %s) = %s, %s", tt.sourceOutcome, tt.targetOutcome, s, o)
+		}
+	}
+}
+
+func TestCheckedService(t *testing.T) {
+	source, target := fedMarket.ExternalID, fedMarketLower.ExternalID
+	tests := []struct {
+		name    string
+		checks  Checks
+		forward []*ComparisonResult
+		reverse []*ComparisonResult
+
+		wantErr           string
+		yes, no           string // "" for no implication
+		yesConf, noConf   float64
+		wantDisagreements int
+		wantRejected      int64
+		wantTokens        int
+	}{
+		{
+			name:    "single answer",
+			checks:  Checks{},
+			forward: []*ComparisonResult{implies("target_yes", "")},
+			yes:     "target_yes", yesConf: 1, wantTokens: 10,
+		},
+		{
+			name:    "reverse gives the contrapositive",
+			checks:  Checks{Reverse: true},
+			forward: []*ComparisonResult{implies("target_yes", "")},
+			reverse: []*ComparisonResult{implies("", "target_no")},
+			yes:     "target_yes", yesConf: 1, wantTokens: 20,
+		},
+		{
+			name:    "reverse gives both contrapositives",
+			checks:  Checks{Reverse: true},
+			forward: []*ComparisonResult{implies("target_no", "target_yes")},
+			reverse: []*ComparisonResult{implies("target_no", "target_yes")},
+			yes:     "target_no", no: "target_yes", yesConf: 1, noConf: 1, wantTokens: 20,
+		},
+		{
+			name:              "reverse implies nothing",
+			checks:            Checks{Reverse: true},
+			forward:           []*ComparisonResult{implies("target_yes", "")},
+			reverse:           []*ComparisonResult{implies("", "")},
+			wantDisagreements: 1, wantRejected: 1, wantTokens: 20,
+		},
+		{
+			// B=YES => A=YES is the converse, which doesn't follow
+			name:              "reverse gives the converse",
+			checks:            Checks{Reverse: true},
+			forward:           []*ComparisonResult{implies("target_yes", "")},
+			reverse:           []*ComparisonResult{implies("target_yes", "")},
+			wantDisagreements: 2, wantRejected: 1, wantTokens: 20,
+		},
+		{
+			name:    "samples at the threshold",
+			checks:  Checks{Samples: 5, MinConfidence: 0.6},
+			forward: append(repeat(3, implies("target_yes", "")), repeat(2, implies("", ""))...),
+			yes:     "target_yes", yesConf: 0.6, wantTokens: 50,
+		},
+		{
+			name:              "samples below the threshold",
+			checks:            Checks{Samples: 5, MinConfidence: 0.6},
+			forward:           append(repeat(2, implies("target_yes", "")), repeat(3, implies("", ""))...),
+			wantDisagreements: 1, wantRejected: 1, wantTokens: 50,
+		},
+		{
+			name:              "default threshold",
+			checks:            Checks{Samples: 2},
+			forward:           []*ComparisonResult{implies("target_yes", ""), implies("", "")},
+			wantDisagreements: 1, wantRejected: 1, wantTokens: 20,
+		},
+		{
+			name:    "stricter threshold",
+			checks:  Checks{Samples: 5, MinConfidence: 0.8},
+			forward: append(repeat(3, implies("target_yes", "target_no")), repeat(2, implies("target_no", "target_no"))...),
+			no:      "target_no", noConf: 1, wantDisagreements: 2, wantRejected: 1, wantTokens: 50,
+		},
+		{
+			name:    "split answers keep the majority",
+			checks:  Checks{Samples: 5, MinConfidence: 0.6},
+			forward: append(repeat(2, implies("target_no", "")), repeat(3, implies("target_yes", ""))...),
+			yes:     "target_yes", yesConf: 0.6, wantDisagreements: 1, wantRejected: 1, wantTokens: 50,
+		},
+		{
+			name:    "confidence is the weaker direction",
+			checks:  Checks{Reverse: true, Samples: 2, MinConfidence: 0.5},
+			forward: repeat(2, implies("target_yes", "")),
+			reverse: []*ComparisonResult{implies("", "target_no"), implies("", "")},
+			yes:     "target_yes", yesConf: 0.5, wantTokens: 40,
+		},
+		{
+			name:              "weaker direction below the threshold",
+			checks:            Checks{Reverse: true, Samples: 2, MinConfidence: 0.6},
+			forward:           repeat(2, implies("target_yes", "")),
+			reverse:           []*ComparisonResult{implies("", "target_no"), implies("", "")},
+			wantDisagreements: 1, wantRejected: 1, wantTokens: 40,
+		},
+		{
+			// The target would resolve yes whatever the source does
+			name:              "same outcome on both sides",
+			checks:            Checks{},
+			forward:           []*ComparisonResult{implies("target_yes", "target_yes")},
+			wantDisagreements: 2, wantRejected: 2, wantTokens: 10,
+		},
+		{
+			name:              "same outcome on both sides after voting",
+			checks:            Checks{Samples: 3},
+			forward:           []*ComparisonResult{implies("target_no", ""), implies("target_no", "target_no"), implies("", "target_no")},
+			wantDisagreements: 2, wantRejected: 1, wantTokens: 30,
+		},
+		{
+			name:    "some samples fail",
+			checks:  Checks{Samples: 3},
+			forward: []*ComparisonResult{nil, implies("target_yes", ""), implies("target_yes", "")},
+			yes:     "target_yes", yesConf: 2.0 / 3, wantTokens: 20,
+		},
+		{
+			name:              "failed samples count against agreement",
+			checks:            Checks{Samples: 3},
+			forward:           []*ComparisonResult{implies("target_yes", ""), nil, nil},
+			wantDisagreements: 1, wantRejected: 1, wantTokens: 10,
+		},
+		{
+			name:    "all samples fail",
+			checks:  Checks{Samples: 3},
+			forward: []*ComparisonResult{nil, nil, nil},
+			wantErr: errStub.Error(),
+		},
+		{
+			name:    "all reverse samples fail",
+			checks:  Checks{Reverse: true, Samples: 2},
+			forward: repeat(2, implies("target_yes", "")),
+			reverse: []*ComparisonResult{nil, nil},
+			wantErr: "reverse comparison failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubService("stub", map[string][]*ComparisonResult{source: tt.forward, target: tt.reverse})
+			service := NewCheckedService(stub, tt.checks)
+
+			result, err := service.CompareMarkets(fedMarket, fedMarketLower)
+			if tt.wantErr != "" {
+				if !errors.Is(err, errStub) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := outcomeOrEmpty(result.SourceYes); got != tt.yes {
+				t.Errorf("source_yes = %q, want %q", got, tt.yes)
+			}
+			if got := outcomeOrEmpty(result.SourceNo); got != tt.no {
+				t.Errorf("source_no = %q, want %q", got, tt.no)
+			}
+			if math.Abs(result.SourceYesConfidence-tt.yesConf) > 1e-9 || math.Abs(result.SourceNoConfidence-tt.noConf) > 1e-9 {
+				t.Errorf("confidence yes %.3f, no %.3f, want %.3f and %.3f",
+					result.SourceYesConfidence, result.SourceNoConfidence, tt.yesConf, tt.noConf)
+			}
+			if result.Disagreements != tt.wantDisagreements {
+				t.Errorf("disagreements = %d, want %d", result.Disagreements, tt.wantDisagreements)
+			}
+			if rejected := service.Stats().Rejected; rejected != tt.wantRejected {
+				t.Errorf("rejected = %d, want %d", rejected, tt.wantRejected)
+			}
+			if result.TokensUsed != tt.wantTokens {
+				t.Errorf("tokens = %d, want %d", result.TokensUsed, tt.wantTokens)
+			}
+			if result.Reason != "stub" {
+				t.Errorf("reason = %q, want the first answer's", result.Reason)
+			}
+		})
+	}
+}
+
+func TestCheckedServiceSampleTemperatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		compare func(Service) (*ComparisonResult, error)
+		want    []float64
+	}{
+		{
+			// The first answer in each direction is at temperature 0, the rest sampled
+			"CompareMarkets",
+			func(s Service) (*ComparisonResult, error) { return s.CompareMarkets(fedMarket, fedMarketLower) },
+			[]float64{0, 0.7, 0.7, 0, 0.7, 0.7},
+		},
+		{
+			"CompareMarketsAt",
+			func(s Service) (*ComparisonResult, error) { return s.CompareMarketsAt(fedMarket, fedMarketLower, 0.3) },
+			[]float64{0.3, 0.7, 0.7, 0.3, 0.7, 0.7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubService("stub", map[string][]*ComparisonResult{
+				fedMarket.ExternalID:      repeat(3, implies("target_yes", "")),
+				fedMarketLower.ExternalID: repeat(3, implies("", "target_no")),
+			})
+			service := NewCheckedService(stub, Checks{Reverse: true, Samples: 3, Temperature: 0.7})
+			if _, err := tt.compare(service); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(stub.temperatures) != len(tt.want) {
+				t.Fatalf("temperatures %v, want %v", stub.temperatures, tt.want)
+			}
+			for i := range tt.want {
+				if stub.temperatures[i] != tt.want[i] {
+					t.Fatalf("temperatures %v, want %v", stub.temperatures, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckedServiceCompareMarketsAtVotes(t *testing.T) {
+	// The reverse direction never confirms the forward answer
+	stub := newStubService("stub", map[string][]*ComparisonResult{
+		fedMarket.ExternalID:      repeat(3, implies("target_yes", "")),
+		fedMarketLower.ExternalID: repeat(3, implies("", "")),
+	})
+	service := NewCheckedService(stub, Checks{Reverse: true, Samples: 3, Temperature: 0.7})
+
+	result, err := service.CompareMarketsAt(fedMarket, fedMarketLower, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SourceYes != nil || result.SourceNo != nil {
+		t.Errorf("kept %s / %s, want the unconfirmed implication dropped", outcomeOrEmpty(result.SourceYes), outcomeOrEmpty(result.SourceNo))
+	}
+	if result.TokensUsed != 60 {
+		t.Errorf("tokens %d, want 60 across all six answers", result.TokensUsed)
+	}
+	if rejected := service.Stats().Rejected; rejected != 1 {
+		t.Errorf("rejected %d, want 1", rejected)
+	}
+}
@@ -0,0 +1,175 @@
+package slm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// Response formats, chosen with SLM_RESPONSE_FORMAT to match what the backend supports
+const (
+	// FormatJSONSchema constrains output to the comparison schema via the OpenAI
+	// response_format json_schema (OpenAI, ollama, llama.cpp, vLLM)
+	FormatJSONSchema = "json_schema"
+	// FormatJSONObject only guarantees a JSON object (response_format json_object)
+	FormatJSONObject = "json_object"
+	// FormatGBNF sends a llama.cpp grammar with the request
+	FormatGBNF = "gbnf"
+	// FormatNone relies on the prompt alone
+	FormatNone = "none"
+)
+
+// ErrUnparseableOutput is returned when no attempt produced a valid comparison
+var ErrUnparseableOutput = errors.New("SLM output could not be parsed")
+
+// outcomeNone stands for "no necessary outcome", since strict schemas can't mix
+// strings and null in an enum
+const outcomeNone = "none"
+
+// outputFields are the fields the model must produce, in order. In single-turn
+// mode the model reasons in "analysis" before committing to an answer.
+func outputFields(singleTurn bool) []string {
+	fields := []string{"reason", "source_yes", "source_no"}
+	if singleTurn {
+		fields = append([]string{"analysis"}, fields...)
+	}
+	return fields
+}
+
+// comparisonSchema is the JSON schema of the model's answer
+func comparisonSchema(singleTurn bool) *openai.ResponseFormat {
+	outcome := func(description string) *openai.ResponseFormatJSONSchemaProperty {
+		return &openai.ResponseFormatJSONSchemaProperty{
+			Type:        "string",
+			Description: description,
+			Enum:        []interface{}{"target_yes", "target_no", outcomeNone},
+		}
+	}
+	properties := map[string]*openai.ResponseFormatJSONSchemaProperty{
+		"reason":     {Type: "string", Description: "A summary of the logic"},
+		"source_yes": outcome("The necessary outcome of the Target market if the Source market resolves to YES"),
+		"source_no":  outcome("The necessary outcome of the Target market if the Source market resolves to NO"),
+	}
+	if singleTurn {
+		properties["analysis"] = &openai.ResponseFormatJSONSchemaProperty{
+			Type:        "string",
+			Description: "Step-by-step logical analysis, written before answering",
+		}
+	}
+
+	return &openai.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openai.ResponseFormatJSONSchema{
+			Name:   "market_comparison",
+			Strict: true,
+			Schema: &openai.ResponseFormatJSONSchemaProperty{
+				Type:       "object",
+				Properties: properties,
+				Required:   outputFields(singleTurn),
+			},
+		},
+	}
+}
+
+// comparisonGrammar is the GBNF equivalent of comparisonSchema, with the fields in
+// the order they must be generated
+func comparisonGrammar(singleTurn bool) string {
+	var root []string
+	for _, field := range outputFields(singleTurn) {
+		value := "string"
+		if strings.HasPrefix(field, "source_") {
+			value = "outcome"
+		}
+		root = append(root, fmt.Sprintf(`"\"%s\"" ws ":" ws %s`, field, value))
+	}
+	return `root ::= "{" ws ` + strings.Join(root, ` ws "," ws `) + ` ws "}"
+outcome ::= "\"target_yes\"" | "\"target_no\"" | "\"` + outcomeNone + `\"" | "null"
+string ::= "\"" ( [^"\\\x7F\x00-\x1F] | "\\" ( ["\\/bfnrt] | "u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] ) )* "\""
+ws ::= [ \t\n]*
+`
+}
+
+// grammarClient adds a llama.cpp grammar to every chat completion request, which
+// the OpenAI client has no option for
+type grammarClient struct {
+	grammar string
+	client  *http.Client
+}
+
+func (g *grammarClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return g.client.Do(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode chat request: %w", err)
+	}
+	grammar, _ := json.Marshal(g.grammar)
+	payload["grammar"] = grammar
+	if body, err = json.Marshal(payload); err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return g.client.Do(req)
+}
+
+// parseComparison decodes the model's answer. Unlike the schema it accepts null
+// for "no necessary outcome", and any wrapping text when the format isn't enforced.
+func parseComparison(output string) (*ComparisonResult, error) {
+	cleaned := strings.TrimSpace(output)
+	// Sometimes models add markdown blocks ```json ... ``` or thinking before the JSON
+	if idx := strings.Index(cleaned, "{"); idx != -1 {
+		cleaned = cleaned[idx:]
+	}
+	if idx := strings.LastIndex(cleaned, "}"); idx != -1 {
+		cleaned = cleaned[:idx+1]
+	}
+
+	var raw struct {
+		Reason    *string `json:"reason"`
+		SourceYes *string `json:"source_yes"`
+		SourceNo  *string `json:"source_no"`
+	}
+	if err := json.Unmarshal([]byte(cleaned), &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if raw.Reason == nil {
+		return nil, errors.New(`missing "reason"`)
+	}
+
+	result := &ComparisonResult{Reason: *raw.Reason}
+	var err error
+	if result.SourceYes, err = parseOutcome("source_yes", raw.SourceYes); err != nil {
+		return nil, err
+	}
+	if result.SourceNo, err = parseOutcome("source_no", raw.SourceNo); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseOutcome(field string, value *string) (*string, error) {
+	if value == nil || *value == outcomeNone {
+		return nil, nil
+	}
+	switch *value {
+	case "target_yes", "target_no":
+		return value, nil
+	}
+	return nil, fmt.Errorf("invalid %q value %q: expected target_yes, target_no or %s", field, *value, outcomeNone)
+}
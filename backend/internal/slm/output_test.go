@@ -0,0 +1,307 @@
+package slm
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseComparison(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		yes, no  string // "" for no implication
+		wantErr  string
+		wantText string // Expected reason
+	}{
+		{"plain", `{"reason": "r", "source_yes": "target_no", "source_no": "target_yes"}`, "target_no", "target_yes", "", "r"},
+		{"fenced", "```json\n{\"reason\": \"r\", \"source_yes\": \"target_yes\", \"source_no\": \"none\"}\n```", "target_yes", "", "", "r"},
+		{"prefixed and suffixed", "Here is the answer:\n{\"reason\": \"r\", \"source_yes\": \"none\", \"source_no\": \"target_no\"}\nHope that helps.", "", "target_no", "", "r"},
+		{"null means none", `{"reason": "r", "source_yes": null, "source_no": null}`, "", "", "", "r"},
+		{"none means none", `{"reason": "r", "source_yes": "none", "source_no": "none"}`, "", "", "", "r"},
+		{"missing outcomes mean none", `{"reason": "r"}`, "", "", "", "r"},
+		{"extra fields ignored", `{"analysis": "a", "reason": "r", "source_yes": "target_yes", "source_no": "none"}`, "target_yes", "", "", "r"},
+		{"invalid enum", `{"reason": "r", "source_yes": "yes", "source_no": "none"}`, "", "", `invalid "source_yes" value "yes"`, ""},
+		{"enum is case sensitive", `{"reason": "r", "source_yes": "none", "source_no": "TARGET_NO"}`, "", "", `invalid "source_no" value`, ""},
+		{"outcome must be a string", `{"reason": "r", "source_yes": true}`, "", "", "invalid JSON", ""},
+		{"missing reason", `{"source_yes": "target_yes", "source_no": "none"}`, "", "", `missing "reason"`, ""},
+		{"not JSON", "I think the source implies the target.", "", "", "invalid JSON", ""},
+		{"truncated", `{"reason": "r", "source_yes": "target_`, "", "", "invalid JSON", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseComparison(tt.output)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := outcomeOrEmpty(result.SourceYes); got != tt.yes {
+				t.Errorf("source_yes = %q, want %q", got, tt.yes)
+			}
+			if got := outcomeOrEmpty(result.SourceNo); got != tt.no {
+				t.Errorf("source_no = %q, want %q", got, tt.no)
+			}
+			if result.Reason != tt.wantText {
+				t.Errorf("reason = %q, want %q", result.Reason, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestParseOutcome(t *testing.T) {
+	ref := func(s string) *string { return &s }
+	tests := []struct {
+		value   *string
+		want    string
+		wantErr bool
+	}{
+		{nil, "", false},
+		{ref("none"), "", false},
+		{ref("target_yes"), "target_yes", false},
+		{ref("target_no"), "target_no", false},
+		{ref(""), "", true},
+		{ref("null"), "", true},
+		{ref("target_maybe"), "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseOutcome("source_yes", tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOutcome(%s): err = %v, wantErr %v", outcomeName(tt.value), err, tt.wantErr)
+		}
+		if outcomeOrEmpty(got) != tt.want {
+			t.Errorf("parseOutcome(%s) = %q, want %q", outcomeName(tt.value), outcomeOrEmpty(got), tt.want)
+		}
+	}
+}
+
+func TestComparisonGrammar(t *testing.T) {
+	tests := []struct {
+		singleTurn bool
+		fields     []string
+	}{
+		{false, []string{"reason", "source_yes", "source_no"}},
+		{true, []string{"analysis", "reason", "source_yes", "source_no"}},
+	}
+	for _, tt := range tests {
+		grammar := comparisonGrammar(tt.singleTurn)
+		root, _, _ := strings.Cut(grammar, "\n")
+
+		// Fields appear in the order they must be generated
+		last := -1
+		for _, field := range tt.fields {
+			i := strings.Index(root, `"\"`+field+`\""`)
+			if i <= last {
+				t.Errorf("single turn %t: %s missing or out of order in %s", tt.singleTurn, field, root)
+			}
+			last = i
+		}
+		if !tt.singleTurn && strings.Contains(root, "analysis") {
+			t.Errorf("analysis asked for in two-turn mode: %s", root)
+		}
+
+		// Outcomes are limited to the enum, with null accepted
+		if strings.Count(root, "ws outcome") != 2 {
+			t.Errorf("single turn %t: expected both source fields to use the outcome rule: %s", tt.singleTurn, root)
+		}
+		for _, value := range []string{`"\"target_yes\""`, `"\"target_no\""`, `"\"none\""`, `"null"`} {
+			if !strings.Contains(grammar, "outcome ::= ") || !strings.Contains(grammar, value) {
+				t.Errorf("outcome rule lacks %s", value)
+			}
+		}
+	}
+}
+
+func TestComparisonSchema(t *testing.T) {
+	for _, singleTurn := range []bool{false, true} {
+		schema := comparisonSchema(singleTurn).JSONSchema.Schema
+		if strings.Join(schema.Required, ",") != strings.Join(outputFields(singleTurn), ",") {
+			t.Errorf("single turn %t: required %v", singleTurn, schema.Required)
+		}
+		for _, field := range schema.Required {
+			if schema.Properties[field] == nil {
+				t.Errorf("single turn %t: %s required but not defined", singleTurn, field)
+			}
+		}
+		if enum := schema.Properties["source_no"].Enum; len(enum) != 3 || enum[2] != outcomeNone {
+			t.Errorf("source_no enum = %v", enum)
+		}
+	}
+}
+
+func TestGrammarClientDo(t *testing.T) {
+	var received map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 0 {
+			json.Unmarshal(body, &received)
+			if int64(len(body)) != r.ContentLength {
+				t.Errorf("%s: content length %d for a %d byte body", r.URL.Path, r.ContentLength, len(body))
+			}
+		}
+	}))
+	defer srv.Close()
+	client := &grammarClient{grammar: "root ::= \"{}\"", client: srv.Client()}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantGrammar bool
+		wantErr     bool
+	}{
+		{"chat completion", "POST", "/v1/chat/completions", `{"model": "m", "messages": []}`, true, false},
+		{"embeddings", "POST", "/v1/embeddings", `{"model": "m", "input": "x"}`, false, false},
+		{"legacy completion", "POST", "/v1/completions", `{"model": "m", "prompt": "x"}`, false, false},
+		{"no body", "GET", "/v1/models", "", false, false},
+		{"undecodable chat body", "POST", "/v1/chat/completions", `not json`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, srv.URL+tt.path, body)
+			resp, err := client.Do(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			grammar, ok := received["grammar"]
+			if ok != tt.wantGrammar {
+				t.Fatalf("grammar sent = %v, want %v", ok, tt.wantGrammar)
+			}
+			if ok && grammar != client.grammar {
+				t.Errorf("grammar = %v, want %q", grammar, client.grammar)
+			}
+			if tt.body != "" && received["model"] != "m" {
+				t.Errorf("original fields lost: %v", received)
+			}
+		})
+	}
+}
+
+func TestFormatReachesAnswerRequestOnly(t *testing.T) {
+	tests := []struct {
+		format string
+		field  string // Request field carrying the constraint
+	}{
+		{FormatGBNF, "grammar"},
+		{FormatJSONSchema, "response_format"},
+		{FormatJSONObject, "response_format"},
+		{FormatNone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f := newFakeServer(t, func(req chatRequest) string { return answer("target_yes", "none") })
+			service := newTestService(t, f, Config{Format: tt.format})
+			if _, err := service.CompareMarkets(fedMarket, fedMarketLower); err != nil {
+				t.Fatal(err)
+			}
+
+			requests := f.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("%d requests, want 2", len(requests))
+			}
+			for _, field := range []string{"grammar", "response_format"} {
+				_, reasoning := requests[0].Body[field]
+				_, answering := requests[1].Body[field]
+				if reasoning {
+					t.Errorf("reasoning request carries %s", field)
+				}
+				if answering != (field == tt.field) {
+					t.Errorf("answer request carries %s: %v", field, answering)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetries(t *testing.T) {
+	valid := answer("target_yes", "none")
+	tests := []struct {
+		name          string
+		retries       int
+		replies       []string // Answers in order; the reasoning turn is answered separately
+		wantErr       bool
+		wantAnswers   int
+		wantFailures  int64
+		wantUnparsed  int64
+		wantCorrected bool // The model was shown its mistake
+	}{
+		{"valid first time", 2, []string{valid}, false, 1, 0, 0, false},
+		{"fixed on retry", 2, []string{"not json", valid}, false, 2, 1, 0, true},
+		{"fixed on last retry", 2, []string{"{}", `{"reason": "r", "source_yes": "maybe"}`, valid}, false, 3, 2, 0, true},
+		{"retries exhausted", 2, []string{"a", "b", "c", valid}, true, 3, 3, 1, true},
+		{"no retries", 0, []string{"not json", valid}, true, 1, 1, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answers := 0
+			f := newFakeServer(t, func(req chatRequest) string {
+				if len(req.Messages) == 2 {
+					return "Some reasoning."
+				}
+				answers++
+				return tt.replies[answers-1]
+			})
+			service := newTestService(t, f, Config{ParseRetries: tt.retries})
+
+			result, err := service.CompareMarkets(fedMarket, fedMarketLower)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnparseableOutput) {
+					t.Fatalf("err = %v, want ErrUnparseableOutput", err)
+				}
+			} else if err != nil || outcomeOrEmpty(result.SourceYes) != "target_yes" {
+				t.Fatalf("result %+v, err %v", result, err)
+			}
+
+			if answers != tt.wantAnswers {
+				t.Errorf("%d answers asked for, want %d", answers, tt.wantAnswers)
+			}
+			stats := service.Stats()
+			if stats.ParseFailures != tt.wantFailures || stats.Unparseable != tt.wantUnparsed {
+				t.Errorf("parse failures %d, unparseable %d, want %d and %d",
+					stats.ParseFailures, stats.Unparseable, tt.wantFailures, tt.wantUnparsed)
+			}
+
+			requests := f.Requests()
+			last := requests[len(requests)-1].Messages
+			corrected := strings.HasPrefix(last[len(last)-1], "That reply was invalid")
+			if corrected != tt.wantCorrected {
+				t.Errorf("last request corrected the model: %v, want %v", corrected, tt.wantCorrected)
+			}
+			if !tt.wantErr && result.TokensUsed != 10*len(requests) {
+				t.Errorf("tokens = %d, want %d", result.TokensUsed, 10*len(requests))
+			}
+		})
+	}
+}
+
+// outcomeOrEmpty returns an outcome, with "" standing for no implication
+func outcomeOrEmpty(outcome *string) string {
+	if outcome == nil {
+		return ""
+	}
+	return *outcome
+}
@@ -0,0 +1,119 @@
+package slm
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"backend/internal/db"
+)
+
+// Prompt templates live in prompts/<version>/*.tmpl. A version is never edited once
+// results have been recorded with it; changes go in a new version, so evaluation
+// results and stored comparisons can be traced to the exact prompts behind them.
+//
+//go:embed prompts
+var promptFiles embed.FS
+
+// DefaultPromptVersion is used unless SLM_PROMPT_VERSION or SLM_PROMPT_DIR says otherwise
+const DefaultPromptVersion = "v1"
+
+// Templates every prompt version must define
+const (
+	promptReasoningSystem  = "reasoning_system.tmpl"
+	promptReasoningUser    = "reasoning_user.tmpl"
+	promptJSON             = "json.tmpl"
+	promptSingleTurnSystem = "single_turn_system.tmpl"
+	promptSingleTurnUser   = "single_turn_user.tmpl"
+)
+
+// Prompts is one version of the comparison prompt templates
+type Prompts struct {
+	Version   string
+	templates *template.Template
+}
+
+// promptData is what the templates are rendered with. It's also what a trace
+// keeps of the two markets, since their text can change after the call.
+type promptData struct {
+	Source promptMarket `json:"source"`
+	Target promptMarket `json:"target"`
+}
+
+type promptMarket struct {
+	Category    string `json:"category"`
+	Description string `json:"description"`
+}
+
+func newPromptData(source, target db.Market) promptData {
+	return promptData{
+		Source: promptMarket{Category: source.Category, Description: describeMarket(source)},
+		Target: promptMarket{Category: target.Category, Description: describeMarket(target)},
+	}
+}
+
+// LoadPrompts loads a prompt version: from dir when set, for trying out prompts
+// without a rebuild, otherwise the embedded version of that name
+func LoadPrompts(version, dir string) (*Prompts, error) {
+	var files fs.FS
+	if dir != "" {
+		files = os.DirFS(dir)
+		version = filepath.Base(filepath.Clean(dir))
+	} else {
+		if version == "" {
+			version = DefaultPromptVersion
+		}
+		if !slices.Contains(PromptVersions(), version) {
+			return nil, fmt.Errorf("unknown prompt version %q: expected one of %s", version, strings.Join(PromptVersions(), ", "))
+		}
+		sub, err := fs.Sub(promptFiles, "prompts/"+version)
+		if err != nil {
+			return nil, err
+		}
+		files = sub
+	}
+
+	templates, err := template.New(version).Option("missingkey=error").ParseFS(files, "*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt version %q: %w", version, err)
+	}
+	for _, name := range []string{promptReasoningSystem, promptReasoningUser, promptJSON, promptSingleTurnSystem, promptSingleTurnUser} {
+		if templates.Lookup(name) == nil {
+			return nil, fmt.Errorf("prompt version %q has no %s", version, name)
+		}
+	}
+	return &Prompts{Version: version, templates: templates}, nil
+}
+
+// PromptVersions lists the embedded prompt versions
+func PromptVersions() []string {
+	entries, _ := fs.ReadDir(promptFiles, "prompts")
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	return versions
+}
+
+func (p *Prompts) render(name string, source, target db.Market) (string, error) {
+	var b strings.Builder
+	if err := p.templates.ExecuteTemplate(&b, name, newPromptData(source, target)); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s/%s: %w", p.Version, name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// describeMarket gives the market text the prompts compare
+func describeMarket(m db.Market) string {
+	if m.YesSubTitle != "" {
+		return fmt.Sprintf("%s (%s)", m.Title, m.YesSubTitle)
+	}
+	return m.Title
+}
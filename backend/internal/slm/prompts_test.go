@@ -0,0 +1,161 @@
+package slm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writePrompts writes a prompt version to a directory named after it
+func writePrompts(t *testing.T, version string, templates map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), version)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range templates {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// testTemplates marks every prompt with its name so tests can tell which was sent
+func testTemplates() map[string]string {
+	return map[string]string{
+		promptReasoningSystem:  "TEST reasoning system",
+		promptReasoningUser:    "TEST reasoning user: {{.Source.Description}} vs {{.Target.Description}}",
+		promptJSON:             "TEST json",
+		promptSingleTurnSystem: "TEST single turn system",
+		promptSingleTurnUser:   "TEST single turn user: {{.Source.Category}} {{.Source.Description}} vs {{.Target.Description}}",
+	}
+}
+
+func TestLoadPrompts(t *testing.T) {
+	missing := testTemplates()
+	delete(missing, promptJSON)
+	broken := testTemplates()
+	broken[promptJSON] = "{{.Source"
+
+	tests := []struct {
+		name        string
+		version     string
+		dir         string
+		wantVersion string
+		wantErr     string
+	}{
+		{"default version", "", "", DefaultPromptVersion, ""},
+		{"embedded version", "v1", "", "v1", ""},
+		{"unknown version", "v999", "", "", "unknown prompt version"},
+		{"directory named after its version", "v1", writePrompts(t, "v2-draft", testTemplates()), "v2-draft", ""},
+		{"directory missing a template", "", writePrompts(t, "v3", missing), "", "has no json.tmpl"},
+		{"directory with a broken template", "", writePrompts(t, "v4", broken), "", "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompts, err := LoadPrompts(tt.version, tt.dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prompts.Version != tt.wantVersion {
+				t.Errorf("version = %s, want %s", prompts.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestPromptVersions(t *testing.T) {
+	versions := PromptVersions()
+	if !slices.Contains(versions, DefaultPromptVersion) {
+		t.Fatalf("versions %v lack the default %s", versions, DefaultPromptVersion)
+	}
+	// Every embedded version loads
+	for _, v := range versions {
+		if _, err := LoadPrompts(v, ""); err != nil {
+			t.Errorf("version %s: %v", v, err)
+		}
+	}
+}
+
+func TestRenderUnknownField(t *testing.T) {
+	templates := testTemplates()
+	templates[promptJSON] = "{{.Source.Ticker}}"
+	prompts, err := LoadPrompts("", writePrompts(t, "v5", templates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prompts.render(promptJSON, fedMarket, fedMarketLower); err == nil {
+		t.Fatal("expected an error for a field the templates aren't given")
+	}
+}
+
+func TestServiceSendsVersionedPrompts(t *testing.T) {
+	dir := writePrompts(t, "v2-draft", testTemplates())
+
+	tests := []struct {
+		name       string
+		singleTurn bool
+		want       []string // Each request's last message
+	}{
+		{"two turns", false, []string{
+			"TEST reasoning user: Fed funds rate after December? (Above 4.00%) vs Fed funds rate after December? (Above 3.75%)",
+			"TEST json",
+		}},
+		{"single turn", true, []string{
+			"TEST single turn user: Economics Fed funds rate after December? (Above 4.00%) vs Fed funds rate after December? (Above 3.75%)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t, func(req chatRequest) string { return answer("target_yes", "none") })
+			service := newTestService(t, f, Config{PromptDir: dir, SingleTurn: tt.singleTurn})
+
+			result, err := service.CompareMarkets(fedMarket, fedMarketLower)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.PromptVersion != "v2-draft" {
+				t.Errorf("result prompt version = %q, want v2-draft", result.PromptVersion)
+			}
+
+			requests := f.Requests()
+			if len(requests) != len(tt.want) {
+				t.Fatalf("%d requests, want %d", len(requests), len(tt.want))
+			}
+			for i, req := range requests {
+				if !strings.HasPrefix(req.Messages[0], "TEST") {
+					t.Errorf("request %d: system prompt %q is not from the test version", i, req.Messages[0])
+				}
+				if last := req.Messages[len(req.Messages)-1]; last != tt.want[i] {
+					t.Errorf("request %d: last message %q, want %q", i, last, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServiceRecordsEmbeddedVersion(t *testing.T) {
+	f := newFakeServer(t, func(req chatRequest) string { return answer("target_yes", "none") })
+	service := newTestService(t, f, Config{PromptVersion: "v1"})
+
+	result, err := service.CompareMarkets(fedMarket, fedMarketLower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PromptVersion != "v1" {
+		t.Errorf("prompt version = %q, want v1", result.PromptVersion)
+	}
+	system := f.Requests()[0].Messages[0]
+	if !strings.Contains(system, "logical reasoning engine") {
+		t.Errorf("system prompt isn't v1's: %q", system)
+	}
+}
@@ -0,0 +1,136 @@
+package slm
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/db"
+)
+
+// Backend is one OpenAI-compatible server and model comparisons can be sent to:
+// a local ollama or llama.cpp server, or a hosted API with a real key
+type Backend struct {
+	Name        string
+	BaseURL     string
+	Model       string
+	APIKey      string        // Empty for local servers
+	Concurrency int           // Requests in flight at once, 0 for no limit
+	Timeout     time.Duration // Per request, 0 for none
+}
+
+// DefaultEscalateBelow passes on any answer short of unanimous
+const DefaultEscalateBelow = 1.0
+
+// backendsFromEnv reads SLM_BACKENDS, the names of the backends in the order they
+// are tried, cheapest first, e.g. "ollama,openai". Each is configured by
+// SLM_<NAME>_URL, _MODEL, _API_KEY, _CONCURRENCY and _TIMEOUT.
+func backendsFromEnv() []Backend {
+	var backends []Backend
+	for _, name := range strings.Split(os.Getenv("SLM_BACKENDS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "SLM_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		backend := Backend{
+			Name:    name,
+			BaseURL: os.Getenv(prefix + "URL"),
+			Model:   os.Getenv(prefix + "MODEL"),
+			APIKey:  os.Getenv(prefix + "API_KEY"),
+		}
+		if n, err := strconv.Atoi(os.Getenv(prefix + "CONCURRENCY")); err == nil && n > 0 {
+			backend.Concurrency = n
+		}
+		if d, err := time.ParseDuration(os.Getenv(prefix + "TIMEOUT")); err == nil && d > 0 {
+			backend.Timeout = d
+		}
+		if backend.BaseURL == "" || backend.Model == "" {
+			log.Printf("Ignoring SLM backend %s: %sURL and %sMODEL must be set", name, prefix, prefix)
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	return backends
+}
+
+// router sends each comparison to the cheapest backend first and passes it on to
+// the next when that one fails, or its answers disagree or aren't confident enough
+type router struct {
+	tiers         []Service
+	escalateBelow float64
+	escalations   atomic.Int64
+}
+
+// NewRouter routes comparisons across services in order, cheapest first
+func NewRouter(tiers []Service, escalateBelow float64) Service {
+	return &router{tiers: tiers, escalateBelow: escalateBelow}
+}
+
+func (r *router) CompareMarkets(source, target db.Market) (*ComparisonResult, error) {
+	return r.route(source, target, func(s Service) (*ComparisonResult, error) {
+		return s.CompareMarkets(source, target)
+	})
+}
+
+func (r *router) CompareMarketsAt(source, target db.Market, temperature float64) (*ComparisonResult, error) {
+	return r.route(source, target, func(s Service) (*ComparisonResult, error) {
+		return s.CompareMarketsAt(source, target, temperature)
+	})
+}
+
+func (r *router) route(source, target db.Market, compare func(Service) (*ComparisonResult, error)) (*ComparisonResult, error) {
+	tokens := 0
+	var lastErr error
+	for i, tier := range r.tiers {
+		last := i == len(r.tiers)-1
+		result, err := compare(tier)
+		if err != nil {
+			lastErr = err
+			if !last {
+				r.escalations.Add(1)
+				log.Printf("[SLM] Escalating %s vs %s: %v", source.ExternalID, target.ExternalID, err)
+			}
+			continue
+		}
+		tokens += result.TokensUsed
+		if last || r.confident(result) {
+			result.TokensUsed = tokens
+			return result, nil
+		}
+		r.escalations.Add(1)
+		log.Printf("[SLM] Escalating %s vs %s from %s: %d disagreements, confidence yes %.2f, no %.2f",
+			source.ExternalID, target.ExternalID, result.Backend, result.Disagreements, result.SourceYesConfidence, result.SourceNoConfidence)
+	}
+	return nil, fmt.Errorf("every SLM backend failed: %w", lastErr)
+}
+
+// confident reports whether an answer can stand without a stronger model's. Only
+// checked answers carry a confidence; unchecked ones escalate on failure alone.
+func (r *router) confident(result *ComparisonResult) bool {
+	if result.Disagreements > 0 {
+		return false
+	}
+	low := func(implied *string, confidence float64) bool {
+		return implied != nil && confidence > 0 && confidence < r.escalateBelow
+	}
+	return !low(result.SourceYes, result.SourceYesConfidence) && !low(result.SourceNo, result.SourceNoConfidence)
+}
+
+func (r *router) Stats() Stats {
+	stats := Stats{Escalations: r.escalations.Load()}
+	for _, tier := range r.tiers {
+		s := tier.Stats()
+		stats.Comparisons += s.Comparisons
+		stats.ParseFailures += s.ParseFailures
+		stats.Unparseable += s.Unparseable
+		stats.Rejected += s.Rejected
+		stats.Escalations += s.Escalations
+		stats.Backends = append(stats.Backends, s.Backends...)
+	}
+	return stats
+}
@@ -0,0 +1,173 @@
+package slm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// confident is an answer for the router with the given confidence in its yes
+// implication, disagreements and tokens
+func confident(confidence float64, disagreements, tokens int) *ComparisonResult {
+	result := implies("target_yes", "")
+	result.SourceYesConfidence = confidence
+	result.Disagreements = disagreements
+	result.TokensUsed = tokens
+	return result
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name          string
+		escalateBelow float64
+		tiers         [][]*ComparisonResult // Each tier's answer, nil for a failure
+
+		wantErr         bool
+		wantBackend     string
+		wantTokens      int
+		wantEscalations int64
+		wantCalls       []int // Comparisons each tier was asked for
+	}{
+		{
+			name:          "cheapest tier confident",
+			escalateBelow: 1,
+			tiers:         [][]*ComparisonResult{{confident(1, 0, 10)}, {confident(1, 0, 100)}},
+			wantBackend:   "tier0", wantTokens: 10, wantCalls: []int{1, 0},
+		},
+		{
+			name:          "escalates on error",
+			escalateBelow: 1,
+			tiers:         [][]*ComparisonResult{{nil}, {confident(1, 0, 100)}},
+			wantBackend:   "tier1", wantTokens: 100, wantEscalations: 1, wantCalls: []int{1, 1},
+		},
+		{
+			name:          "escalates on disagreement",
+			escalateBelow: 1,
+			tiers:         [][]*ComparisonResult{{confident(1, 1, 10)}, {confident(1, 0, 100)}},
+			wantBackend:   "tier1", wantTokens: 110, wantEscalations: 1, wantCalls: []int{1, 1},
+		},
+		{
+			name:          "escalates below the threshold",
+			escalateBelow: 0.8,
+			tiers:         [][]*ComparisonResult{{confident(0.6, 0, 10)}, {confident(1, 0, 100)}},
+			wantBackend:   "tier1", wantTokens: 110, wantEscalations: 1, wantCalls: []int{1, 1},
+		},
+		{
+			name:          "stays at the threshold",
+			escalateBelow: 0.6,
+			tiers:         [][]*ComparisonResult{{confident(0.6, 0, 10)}, {confident(1, 0, 100)}},
+			wantBackend:   "tier0", wantTokens: 10, wantCalls: []int{1, 0},
+		},
+		{
+			name:          "unchecked answers carry no confidence",
+			escalateBelow: 1,
+			tiers:         [][]*ComparisonResult{{confident(0, 0, 10)}, {confident(1, 0, 100)}},
+			wantBackend:   "tier0", wantTokens: 10, wantCalls: []int{1, 0},
+		},
+		{
+			name:          "no implication needs no confidence",
+			escalateBelow: 1,
+			tiers: [][]*ComparisonResult{
+				{{SourceYesConfidence: 0.5, TokensUsed: 10}},
+				{confident(1, 0, 100)},
+			},
+			wantBackend: "tier0", wantTokens: 10, wantCalls: []int{1, 0},
+		},
+		{
+			name:          "last tier answers however unsure",
+			escalateBelow: 1,
+			tiers:         [][]*ComparisonResult{{confident(0.5, 2, 10)}, {confident(0.5, 2, 100)}},
+			wantBackend:   "tier1", wantTokens: 110, wantEscalations: 1, wantCalls: []int{1, 1},
+		},
+		{
+			name:          "tokens add up across tiers",
+			escalateBelow: 1,
+			tiers: [][]*ComparisonResult{
+				{confident(0.5, 0, 10)},
+				{nil},
+				{confident(1, 1, 100)},
+				{confident(1, 0, 1000)},
+			},
+			wantBackend: "tier3", wantTokens: 1110, wantEscalations: 3, wantCalls: []int{1, 1, 1, 1},
+		},
+		{
+			name:          "every tier fails",
+			escalateBelow: 1,
+			tiers:         [][]*ComparisonResult{{nil}, {nil}},
+			wantErr:       true, wantEscalations: 1, wantCalls: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tiers []Service
+			var stubs []*stubService
+			for i, answers := range tt.tiers {
+				stub := newStubService(fmt.Sprintf("tier%d", i), map[string][]*ComparisonResult{fedMarket.ExternalID: answers})
+				stubs = append(stubs, stub)
+				tiers = append(tiers, stub)
+			}
+			router := NewRouter(tiers, tt.escalateBelow)
+
+			result, err := router.CompareMarkets(fedMarket, fedMarketLower)
+			if tt.wantErr {
+				if !errors.Is(err, errStub) {
+					t.Fatalf("err = %v, want the last tier's error", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Backend != tt.wantBackend {
+					t.Errorf("answered by %s, want %s", result.Backend, tt.wantBackend)
+				}
+				if result.TokensUsed != tt.wantTokens {
+					t.Errorf("tokens = %d, want %d", result.TokensUsed, tt.wantTokens)
+				}
+			}
+
+			stats := router.Stats()
+			if stats.Escalations != tt.wantEscalations {
+				t.Errorf("escalations = %d, want %d", stats.Escalations, tt.wantEscalations)
+			}
+			for i, stub := range stubs {
+				if calls := stub.calls[fedMarket.ExternalID]; calls != tt.wantCalls[i] {
+					t.Errorf("tier %d asked %d times, want %d", i, calls, tt.wantCalls[i])
+				}
+			}
+			if len(stats.Backends) != len(stubs) {
+				t.Errorf("stats for %d backends, want %d", len(stats.Backends), len(stubs))
+			}
+		})
+	}
+}
+
+func TestRouterPassesTemperature(t *testing.T) {
+	tier0 := newStubService("tier0", map[string][]*ComparisonResult{fedMarket.ExternalID: {nil}})
+	tier1 := newStubService("tier1", map[string][]*ComparisonResult{fedMarket.ExternalID: {confident(1, 0, 10)}})
+	router := NewRouter([]Service{tier0, tier1}, 1)
+
+	if _, err := router.CompareMarketsAt(fedMarket, fedMarketLower, 0.7); err != nil {
+		t.Fatal(err)
+	}
+	if len(tier0.temperatures) != 1 || tier0.temperatures[0] != 0.7 || len(tier1.temperatures) != 1 || tier1.temperatures[0] != 0.7 {
+		t.Errorf("temperatures %v and %v, want 0.7 at each tier", tier0.temperatures, tier1.temperatures)
+	}
+}
+
+func TestRouterStats(t *testing.T) {
+	tier0 := newStubService("tier0", map[string][]*ComparisonResult{fedMarket.ExternalID: {confident(1, 1, 10)}})
+	tier1 := newStubService("tier1", map[string][]*ComparisonResult{fedMarket.ExternalID: {confident(1, 0, 10)}})
+	router := NewRouter([]Service{tier0, tier1}, 1)
+	if _, err := router.CompareMarkets(fedMarket, fedMarketLower); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := router.Stats()
+	if stats.Comparisons != 2 || stats.Escalations != 1 {
+		t.Errorf("comparisons %d, escalations %d, want 2 and 1", stats.Comparisons, stats.Escalations)
+	}
+	if len(stats.Backends) != 2 || stats.Backends[0].Name != "tier0" || stats.Backends[1].Name != "tier1" {
+		t.Errorf("backends %+v, want tier0 then tier1", stats.Backends)
+	}
+}
@@ -2,12 +2,14 @@ package slm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
-	"strings"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"backend/internal/db"
 
@@ -18,6 +20,11 @@ import (
 // Service defines the interface for the SLM service
 type Service interface {
 	CompareMarkets(source, target db.Market) (*ComparisonResult, error)
+	// CompareMarketsAt samples a comparison at a temperature above zero, so several
+	// answers can be voted on
+	CompareMarketsAt(source, target db.Market, temperature float64) (*ComparisonResult, error)
+	// Stats counts the comparisons made since the service started
+	Stats() Stats
 }
 
 // ComparisonResult represents the JSON output from the SLM
@@ -29,197 +36,420 @@ type ComparisonResult struct {
 	Reason           string  `json:"reason"`
 	SourceYes        *string `json:"source_yes"` // "target_yes", "target_no", or null
 	SourceNo         *string `json:"source_no"`  // "target_yes", "target_no", or null
+	// SourceYesConfidence and SourceNoConfidence are the share of answers, across
+	// samples and both directions, that agreed on each implication
+	SourceYesConfidence float64 `json:"source_yes_confidence,omitempty"`
+	SourceNoConfidence  float64 `json:"source_no_confidence,omitempty"`
+	// Disagreements counts implications some answer gave that the checks didn't keep
+	Disagreements int `json:"-"`
+	// PromptVersion is the version of the prompts the SLM answered
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// Backend and Model are where the answer came from
+	Backend string `json:"backend,omitempty"`
+	Model   string `json:"model,omitempty"`
+	// Engine is what produced the result: the SLM, or the rules that resolve mechanical pairs
+	Engine string `json:"engine,omitempty"`
+	// TokensUsed is what the comparison cost across all turns, when the server reports it
+	TokensUsed int `json:"-"`
+}
+
+// Stats counts comparisons since the service started
+type Stats struct {
+	Comparisons   int64 // Answers asked of the model, counting every sample and direction
+	ParseFailures int64 // Answers that couldn't be parsed, including ones retried successfully
+	Unparseable   int64 // Comparisons that failed because no answer could be parsed
+	Rejected      int64 // Implications a single answer gave that failed the consistency checks
+	Escalations   int64 // Comparisons passed on to a stronger backend
+	Backends      []BackendStats
+}
+
+// BackendStats is the usage of one backend since the service started
+type BackendStats struct {
+	Name     string
+	Model    string
+	Requests int64 // Model requests, each turn and retry counting once
+	Failures int64 // Requests that errored or timed out
+	Tokens   int64
+	Latency  time.Duration // Total time spent in requests
 }
 
 type slmService struct {
-	llm llms.Model
+	backend    Backend
+	reasoner   llms.Model // Free-form turns
+	answerer   llms.Model // Turns that must produce the JSON answer, constrained by format
+	prompts    *Prompts
+	format     string
+	singleTurn bool
+	retries    int
+	logLevel   string
+	traces     *TraceStore
+
+	comparisons   atomic.Int64
+	parseFailures atomic.Int64
+	unparseable   atomic.Int64
+
+	slots    chan struct{} // Limits requests in flight; nil for no limit
+	requests atomic.Int64
+	failures atomic.Int64
+	tokens   atomic.Int64
+	latency  atomic.Int64 // Nanoseconds
 }
 
-// NewService initializes a new SLM service using the OpenAI adapter
-// This is compatible with local runners like llama.cpp server
-func NewService(modelName string) (Service, error) {
-	// 1. Determine Base URL
-	baseURL := os.Getenv("SLM_URL")
-	if baseURL == "" {
+// Config selects the models, prompts and output handling of an SLM service
+type Config struct {
+	BaseURL       string // OpenAI-compatible API, e.g. http://localhost:8088/v1
+	Model         string
+	Format        string // How the answer is constrained, e.g. FormatJSONSchema
+	SingleTurn    bool   // Reason and answer in one request
+	ParseRetries  int    // Extra attempts when an answer doesn't parse
+	PromptVersion string // Embedded prompt version, e.g. v1
+	PromptDir     string // Directory of prompt templates, overriding PromptVersion
+	Checks        Checks // Agreement required before an implication is trusted
+
+	APIKey  string        // Empty for local servers
+	Timeout time.Duration // Per model request, 0 for none
+	// Backends are tried in order, cheapest first, overriding the single backend
+	// above; an answer confident below EscalateBelow goes on to the next one
+	Backends      []Backend
+	EscalateBelow float64
+
+	LogLevel string      // LogOff, LogSummary or LogFull
+	Traces   *TraceStore // Where every call is recorded; nil for nowhere
+}
+
+// ConfigFromEnv reads the SLM_* environment variables
+func ConfigFromEnv(modelName string) Config {
+	cfg := Config{
+		BaseURL:       os.Getenv("SLM_URL"),
+		Model:         modelName,
+		APIKey:        os.Getenv("SLM_API_KEY"),
+		EscalateBelow: DefaultEscalateBelow,
+		LogLevel:      os.Getenv("SLM_LOG"),
+		Format:        os.Getenv("SLM_RESPONSE_FORMAT"),
+		SingleTurn:    os.Getenv("SLM_SINGLE_TURN") == "true",
+		ParseRetries:  2,
+		PromptVersion: os.Getenv("SLM_PROMPT_VERSION"),
+		PromptDir:     os.Getenv("SLM_PROMPT_DIR"),
+		Checks: Checks{
+			Reverse:       os.Getenv("SLM_CHECK_REVERSE") != "false",
+			Samples:       DefaultSamples,
+			Temperature:   DefaultTemperature,
+			MinConfidence: DefaultMinConfidence,
+		},
+	}
+	if cfg.BaseURL == "" {
 		// Default to local dev docker-compose setup
-		baseURL = "http://localhost:8088/v1"
+		cfg.BaseURL = "http://localhost:8088/v1"
 	}
-
-	log.Printf("Initializing SLM service with model: %s at %s", modelName, baseURL)
-
-	// 2. Validate URL
-	if _, err := url.Parse(baseURL); err != nil {
-		return nil, fmt.Errorf("invalid SLM_URL: %w", err)
+	if n, err := strconv.Atoi(os.Getenv("SLM_PARSE_RETRIES")); err == nil && n >= 0 {
+		cfg.ParseRetries = n
 	}
-
-	// 3. Initialize OpenAI Client (points to local llama.cpp)
-	// We need a dummy token because the client requires it, even if the server doesn't.
-	llm, err := openai.New(
-		openai.WithModel(modelName),
-		openai.WithBaseURL(baseURL),
-		openai.WithToken("dummy-token"),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create openai/slm client: %w", err)
+	if n, err := strconv.Atoi(os.Getenv("SLM_SAMPLES")); err == nil && n > 0 {
+		cfg.Checks.Samples = n
 	}
-
-	return &slmService{llm: llm}, nil
+	if t, err := strconv.ParseFloat(os.Getenv("SLM_SAMPLE_TEMPERATURE"), 64); err == nil && t >= 0 {
+		cfg.Checks.Temperature = t
+	}
+	if c, err := strconv.ParseFloat(os.Getenv("SLM_MIN_CONFIDENCE"), 64); err == nil && c > 0 && c <= 1 {
+		cfg.Checks.MinConfidence = c
+	}
+	if d, err := time.ParseDuration(os.Getenv("SLM_TIMEOUT")); err == nil && d >= 0 {
+		cfg.Timeout = d
+	}
+	if c, err := strconv.ParseFloat(os.Getenv("SLM_ESCALATE_BELOW"), 64); err == nil && c >= 0 && c <= 1 {
+		cfg.EscalateBelow = c
+	}
+	cfg.Backends = backendsFromEnv()
+	return cfg
 }
 
-func (s *slmService) CompareMarkets(source, target db.Market) (*ComparisonResult, error) {
-	reasoningSystemPrompt, reasoningUserPrompt := s.buildReasoningPrompts(source, target)
-
-	ctx := context.Background()
-	log.Printf("[SLM] Reasoning System Prompt:\n%s\n", reasoningSystemPrompt)
-	log.Printf("[SLM] Reasoning User Prompt:\n%s\n", reasoningUserPrompt)
+// NewService initializes a new SLM service using the OpenAI adapter, configured
+// from the environment. This is compatible with local runners like llama.cpp server
+func NewService(modelName string) (Service, error) {
+	return NewServiceWithConfig(ConfigFromEnv(modelName))
+}
 
-	// Turn 1: Reasoning
-	messages := []llms.MessageContent{
-		llms.TextParts(llms.ChatMessageTypeSystem, reasoningSystemPrompt),
-		llms.TextParts(llms.ChatMessageTypeHuman, reasoningUserPrompt),
+// NewServiceWithConfig initializes an SLM service from an explicit configuration
+func NewServiceWithConfig(cfg Config) (Service, error) {
+	if cfg.Format == "" {
+		cfg.Format = FormatJSONSchema
 	}
-
-	reasoningResp, err := s.llm.GenerateContent(ctx, messages, llms.WithTemperature(0.0))
-	if err != nil {
-		return nil, fmt.Errorf("SLM reasoning generation failed: %w", err)
+	switch cfg.LogLevel {
+	case "":
+		cfg.LogLevel = LogSummary
+	case LogOff, LogSummary, LogFull:
+	default:
+		return nil, fmt.Errorf("invalid SLM_LOG %q: expected %s, %s or %s", cfg.LogLevel, LogOff, LogSummary, LogFull)
 	}
-	reasoningCompletion := reasoningResp.Choices[0].Content
-	log.Printf("[SLM] Reasoning Output:\n%s\n", reasoningCompletion)
-
-	// Turn 2: JSON Extraction
-	messages = append(messages, llms.TextParts(llms.ChatMessageTypeAI, reasoningCompletion))
-	jsonUserPrompt := s.buildJSONPrompt()
-	messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, jsonUserPrompt))
 
-	jsonResp, err := s.llm.GenerateContent(ctx, messages, llms.WithTemperature(0.0))
+	// 1. Load the prompts
+	prompts, err := LoadPrompts(cfg.PromptVersion, cfg.PromptDir)
 	if err != nil {
-		return nil, fmt.Errorf("SLM JSON generation failed: %w", err)
+		return nil, err
 	}
-	jsonCompletion := jsonResp.Choices[0].Content
-	log.Printf("[SLM] JSON Output:\n%s\n", jsonCompletion)
 
-	// Clean up response to ensure valid JSON
-	// Sometimes models add markdown blocks ```json ... ```
-	cleaned := strings.TrimSpace(jsonCompletion)
-	if idx := strings.Index(cleaned, "{"); idx != -1 {
-		cleaned = cleaned[idx:]
+	// 2. Build a service per backend, each behind the consistency checks
+	backends := cfg.Backends
+	if len(backends) == 0 {
+		backends = []Backend{{Name: "default", BaseURL: cfg.BaseURL, Model: cfg.Model, APIKey: cfg.APIKey, Timeout: cfg.Timeout}}
 	}
-	if idx := strings.LastIndex(cleaned, "}"); idx != -1 {
-		cleaned = cleaned[:idx+1]
+	services := make([]Service, len(backends))
+	for i, backend := range backends {
+		log.Printf("Initializing SLM backend %s with model: %s at %s (prompts %s, format %s, single turn %t, reverse check %t, %d samples)",
+			backend.Name, backend.Model, backend.BaseURL, prompts.Version, cfg.Format, cfg.SingleTurn, cfg.Checks.Reverse, max(cfg.Checks.Samples, 1))
+		service, err := newBackendService(backend, prompts, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("SLM backend %s: %w", backend.Name, err)
+		}
+		services[i] = service
+		if cfg.Checks.enabled() {
+			services[i] = NewCheckedService(service, cfg.Checks)
+		}
 	}
 
-	var result ComparisonResult
-	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse SLM JSON output: %w. Output was: %s", err, jsonCompletion)
+	// 3. Route between them, cheapest first
+	if len(services) == 1 {
+		return services[0], nil
 	}
+	return NewRouter(services, cfg.EscalateBelow), nil
+}
 
-	// Ensure IDs are set correctly (in case model hallucinated them)
-	result.MarketID = target.ExternalID
-	result.EventID = target.EventTicker
-	result.ComparedMarketID = source.ExternalID
-	result.ComparedEventID = source.EventTicker
+// newBackendService builds the service that talks to one backend
+func newBackendService(backend Backend, prompts *Prompts, cfg Config) (*slmService, error) {
+	// 1. Validate URL
+	if _, err := url.Parse(backend.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid SLM_URL: %w", err)
+	}
 
-	// Validate the result format (SourceYes/SourceNo fields)
-	if err := validateResult(&result); err != nil {
-		// Log but continue (validateResult now only nullifies invalid fields and shouldn't return error in practice for this usecase, but we keep the error return in signature just in case)
-		// Actually, per instructions: "effectively always return nil error".
-		// But if we did return an error, we should log it.
-		// However, validateResult below is being modified to not return error for values.
-		// Let's just log if err happens (it won't).
-		log.Printf("SLM output validation warning: %v. Output was: %s", err, jsonCompletion)
+	// 2. Initialize OpenAI Clients (points to local llama.cpp unless configured otherwise)
+	// Local servers need a dummy token because the client requires it, even if the server doesn't.
+	token := backend.APIKey
+	if token == "" {
+		token = "dummy-token"
+	}
+	newClient := func(opts ...openai.Option) (*openai.LLM, error) {
+		return openai.New(append([]openai.Option{
+			openai.WithModel(backend.Model),
+			openai.WithBaseURL(backend.BaseURL),
+			openai.WithToken(token),
+		}, opts...)...)
 	}
 
-	return &result, nil
-}
+	// The response format is set per client, so reasoning turns get a client of their own
+	var answerOpts []openai.Option
+	switch cfg.Format {
+	case FormatJSONSchema:
+		answerOpts = append(answerOpts, openai.WithResponseFormat(comparisonSchema(cfg.SingleTurn)))
+	case FormatJSONObject:
+		answerOpts = append(answerOpts, openai.WithResponseFormat(openai.ResponseFormatJSON))
+	case FormatGBNF:
+		answerOpts = append(answerOpts, openai.WithHTTPClient(&grammarClient{
+			grammar: comparisonGrammar(cfg.SingleTurn),
+			client:  http.DefaultClient,
+		}))
+	case FormatNone:
+	default:
+		return nil, fmt.Errorf("invalid SLM_RESPONSE_FORMAT %q: expected %s, %s, %s or %s",
+			cfg.Format, FormatJSONSchema, FormatJSONObject, FormatGBNF, FormatNone)
+	}
 
-func validateResult(r *ComparisonResult) error {
-	validValues := map[string]bool{
-		"target_yes": true,
-		"target_no":  true,
+	reasoner, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai/slm client: %w", err)
+	}
+	answerer, err := newClient(answerOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai/slm client: %w", err)
 	}
 
-	if r.SourceYes != nil {
-		if !validValues[*r.SourceYes] {
-			// Instead of erroring, set to nil
-			r.SourceYes = nil
-		}
+	service := &slmService{
+		backend:    backend,
+		reasoner:   reasoner,
+		answerer:   answerer,
+		prompts:    prompts,
+		format:     cfg.Format,
+		singleTurn: cfg.SingleTurn,
+		retries:    cfg.ParseRetries,
+		logLevel:   cfg.LogLevel,
+		traces:     cfg.Traces,
 	}
-	if r.SourceNo != nil {
-		if !validValues[*r.SourceNo] {
-			// Instead of erroring, set to nil
-			r.SourceNo = nil
-		}
+	if backend.Concurrency > 0 {
+		service.slots = make(chan struct{}, backend.Concurrency)
 	}
-	return nil
+	return service, nil
 }
 
-func (s *slmService) buildReasoningPrompts(source, target db.Market) (string, string) {
-	sourceDesc := source.Description
-	if source.YesSubTitle != "" {
-		sourceDesc = fmt.Sprintf("%s (%s)", source.Title, source.YesSubTitle)
-	} else {
-		sourceDesc = source.Title
+func (s *slmService) Stats() Stats {
+	return Stats{
+		Comparisons:   s.comparisons.Load(),
+		ParseFailures: s.parseFailures.Load(),
+		Unparseable:   s.unparseable.Load(),
+		Backends: []BackendStats{{
+			Name:     s.backend.Name,
+			Model:    s.backend.Model,
+			Requests: s.requests.Load(),
+			Failures: s.failures.Load(),
+			Tokens:   s.tokens.Load(),
+			Latency:  time.Duration(s.latency.Load()),
+		}},
 	}
+}
 
-	targetDesc := target.Description
-	if target.YesSubTitle != "" {
-		targetDesc = fmt.Sprintf("%s (%s)", target.Title, target.YesSubTitle)
-	} else {
-		targetDesc = target.Title
+// generate sends one request to the backend, within its concurrency limit and
+// timeout, and accounts for it
+func (s *slmService) generate(client llms.Model, messages []llms.MessageContent, temperature float64) (*llms.ContentResponse, error) {
+	if s.slots != nil {
+		s.slots <- struct{}{}
+		defer func() { <-s.slots }()
+	}
+	ctx := context.Background()
+	if s.backend.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.backend.Timeout)
+		defer cancel()
 	}
 
-	systemPrompt := `You are a logical reasoning engine specialized in prediction market implications.
-Task: Determine if the outcome of a "Source" market logically necessitates a specific outcome in a "Target" market.
+	start := time.Now()
+	resp, err := client.GenerateContent(ctx, messages, llms.WithTemperature(temperature))
+	s.requests.Add(1)
+	s.latency.Add(int64(time.Since(start)))
+	if err == nil && len(resp.Choices) == 0 {
+		err = fmt.Errorf("empty response")
+	}
+	if err != nil {
+		s.failures.Add(1)
+		return nil, err
+	}
+	s.tokens.Add(int64(totalTokens(resp)))
+	return resp, nil
+}
 
-CRITICAL DISTINCTION:
-- You must distinguish between CORRELATION (likely to happen) and LOGICAL NECESSITY (must happen).
-- Only conclude a definite outcome if the outcome is a LOGICAL NECESSITY based on the definitions of the events.
-- If the relationship is merely correlational (e.g. "Stock A going up usually means Stock B goes up"), you MUST conclude no necessity.
+func (s *slmService) CompareMarkets(source, target db.Market) (*ComparisonResult, error) {
+	return s.CompareMarketsAt(source, target, 0)
+}
 
-Rules:
-1. Analyze if Source=YES implies Target=YES (NECESSITY).
-2. Analyze if Source=YES implies Target=NO (NECESSITY).
-3. Analyze if Source=NO implies Target=YES (NECESSITY).
-4. Analyze if Source=NO implies Target=NO (NECESSITY).
-5. If the outcome is uncertain, not guaranteed, or merely correlated, state that there is no logical necessity.
-6. Check for MUTUAL EXCLUSIVITY: If Source and Target describe different outcomes for the same unique position (e.g. Winner, Top Rank, Next CEO), then Source=YES implies Target=NO.
+func (s *slmService) CompareMarketsAt(source, target db.Market, temperature float64) (*ComparisonResult, error) {
+	s.comparisons.Add(1)
+	tr := s.startTrace(source, target, temperature)
+	result, err := s.compare(source, target, temperature, tr)
+	s.finishTrace(tr, result, err)
+	return result, err
+}
 
-Constraint Examples:
-- "Total > 10" implies "Total > 5" (NECESSITY).
-- "A wins" implies "B loses" (if mutually exclusive) (NECESSITY).
-- "Inflation goes up" implies "Rates go up" (CORRELATION - No necessity).
-- 'Song A is #1' implies 'Song B is NOT #1' (NECESSITY - Mutually Exclusive).
-- Specific dates/values must be strictly compared.
+// compare asks the backend for one comparison, collecting its replies in tr
+func (s *slmService) compare(source, target db.Market, temperature float64, tr *trace) (*ComparisonResult, error) {
 
-DO NOT output JSON. Provide a step-by-step logical analysis.`
+	var messages []llms.MessageContent
+	if s.singleTurn {
+		// Single turn: the model reasons inside the JSON answer
+		systemPrompt, userPrompt, err := s.buildPrompts(promptSingleTurnSystem, promptSingleTurnUser, source, target)
+		if err != nil {
+			return nil, err
+		}
+		s.tracePrompts(tr, systemPrompt, userPrompt)
+		s.logFull("[SLM] System Prompt:\n%s\n", systemPrompt)
+		s.logFull("[SLM] User Prompt:\n%s\n", userPrompt)
+		messages = []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, systemPrompt),
+			llms.TextParts(llms.ChatMessageTypeHuman, userPrompt),
+		}
+	} else {
+		reasoningSystemPrompt, reasoningUserPrompt, err := s.buildPrompts(promptReasoningSystem, promptReasoningUser, source, target)
+		if err != nil {
+			return nil, err
+		}
+		jsonPrompt, err := s.prompts.render(promptJSON, source, target)
+		if err != nil {
+			return nil, err
+		}
+		s.tracePrompts(tr, reasoningSystemPrompt, reasoningUserPrompt, jsonPrompt)
+		s.logFull("[SLM] Reasoning System Prompt:\n%s\n", reasoningSystemPrompt)
+		s.logFull("[SLM] Reasoning User Prompt:\n%s\n", reasoningUserPrompt)
+
+		// Turn 1: Reasoning
+		messages = []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, reasoningSystemPrompt),
+			llms.TextParts(llms.ChatMessageTypeHuman, reasoningUserPrompt),
+		}
 
-	userPrompt := fmt.Sprintf(`Source Bet:
-Category: %s
-Market: %s
+		reasoningResp, err := s.generate(s.reasoner, messages, temperature)
+		if err != nil {
+			return nil, fmt.Errorf("SLM reasoning generation failed: %w", err)
+		}
+		tr.call.Tokens += totalTokens(reasoningResp)
+		reasoningCompletion := reasoningResp.Choices[0].Content
+		tr.outputs = append(tr.outputs, reasoningCompletion)
+		s.logFull("[SLM] Reasoning Output:\n%s\n", reasoningCompletion)
+
+		// Turn 2: JSON Extraction
+		messages = append(messages,
+			llms.TextParts(llms.ChatMessageTypeAI, reasoningCompletion),
+			llms.TextParts(llms.ChatMessageTypeHuman, jsonPrompt),
+		)
+	}
 
-Target Bet:
-Category: %s
-Market: %s
+	// Answer, showing the model its mistake and retrying when the output can't be parsed
+	var result *ComparisonResult
+	for attempt := 1; ; attempt++ {
+		tr.call.Attempts = attempt
+		jsonResp, err := s.generate(s.answerer, messages, temperature)
+		if err != nil {
+			return nil, fmt.Errorf("SLM JSON generation failed: %w", err)
+		}
+		tr.call.Tokens += totalTokens(jsonResp)
+		jsonCompletion := jsonResp.Choices[0].Content
+		tr.outputs = append(tr.outputs, jsonCompletion)
+		s.logFull("[SLM] JSON Output:\n%s\n", jsonCompletion)
+
+		result, err = parseComparison(jsonCompletion)
+		if err == nil {
+			break
+		}
+		s.parseFailures.Add(1)
+		if attempt > s.retries {
+			s.unparseable.Add(1)
+			return nil, fmt.Errorf("%w after %d attempts: %v. Output was: %s", ErrUnparseableOutput, attempt, err, redact(jsonCompletion))
+		}
+		if s.logLevel != LogOff {
+			log.Printf("[SLM] Unparseable output from %s (attempt %d of %d): %v", s.backend.Name, attempt, s.retries+1, err)
+		}
+		messages = append(messages,
+			llms.TextParts(llms.ChatMessageTypeAI, jsonCompletion),
+			llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(
+				"That reply was invalid: %v. Reply with only the JSON object described above.", err)),
+		)
+	}
 
-Please provide a step-by-step logical analysis of whether the Source outcome necessitates the Target outcome.`,
-		source.Category, sourceDesc,
-		target.Category, targetDesc)
+	// Ensure IDs are set correctly (in case model hallucinated them)
+	result.MarketID = target.ExternalID
+	result.EventID = target.EventTicker
+	result.ComparedMarketID = source.ExternalID
+	result.ComparedEventID = source.EventTicker
+	result.TokensUsed = tr.call.Tokens
+	result.PromptVersion = s.prompts.Version
+	result.Backend = s.backend.Name
+	result.Model = s.backend.Model
 
-	return systemPrompt, userPrompt
+	return result, nil
 }
 
-func (s *slmService) buildJSONPrompt() string {
-	return `Based on the above analysis, map the logical implications to strict JSON.
-
-JSON Schema:
-{
-  "reason": "A summary of the logic",
-  "source_yes": "target_yes" | "target_no" | null,
-  "source_no": "target_yes" | "target_no" | null
+// totalTokens reads the token usage an OpenAI-compatible server reported for a response
+func totalTokens(resp *llms.ContentResponse) int {
+	if len(resp.Choices) == 0 {
+		return 0
+	}
+	tokens, _ := resp.Choices[0].GenerationInfo["TotalTokens"].(int)
+	return tokens
 }
 
-Constraints:
-- "source_yes": The necessary outcome of the Target market if the Source market resolves to YES. Null if no necessity.
-- "source_no": The necessary outcome of the Target market if the Source market resolves to NO. Null if no necessity.
-- "reason": A brief summary string explaining the logic.`
+func (s *slmService) buildPrompts(systemName, userName string, source, target db.Market) (string, string, error) {
+	systemPrompt, err := s.prompts.render(systemName, source, target)
+	if err != nil {
+		return "", "", err
+	}
+	userPrompt, err := s.prompts.render(userName, source, target)
+	if err != nil {
+		return "", "", err
+	}
+	return systemPrompt, userPrompt, nil
 }
@@ -0,0 +1,113 @@
+package slm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/internal/db"
+)
+
+// chatRequest is a chat completion request as the fake server saw it
+type chatRequest struct {
+	Path     string
+	Messages []string // Text of each message, in order
+	Body     map[string]any
+}
+
+// fakeServer is an OpenAI-compatible server that answers each chat completion
+// with whatever reply returns for it
+type fakeServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []chatRequest
+}
+
+func newFakeServer(t *testing.T, reply func(req chatRequest) string) *fakeServer {
+	t.Helper()
+	f := &fakeServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := chatRequest{Path: r.URL.Path, Body: body}
+		messages, _ := body["messages"].([]any)
+		for _, m := range messages {
+			req.Messages = append(req.Messages, messageText(m))
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":     "fake",
+			"object": "chat.completion",
+			"model":  body["model"],
+			"choices": []any{map[string]any{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": reply(req)},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]int{"prompt_tokens": 7, "completion_tokens": 3, "total_tokens": 10},
+		})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// messageText reads a message's content, sent either as a string or as parts
+func messageText(message any) string {
+	content := message.(map[string]any)["content"]
+	if s, ok := content.(string); ok {
+		return s
+	}
+	var text strings.Builder
+	parts, _ := content.([]any)
+	for _, p := range parts {
+		if s, ok := p.(map[string]any)["text"].(string); ok {
+			text.WriteString(s)
+		}
+	}
+	return text.String()
+}
+
+func (f *fakeServer) Requests() []chatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]chatRequest(nil), f.requests...)
+}
+
+// newTestService builds a service on the fake server, without consistency checks
+// unless cfg asks for them
+func newTestService(t *testing.T, f *fakeServer, cfg Config) Service {
+	t.Helper()
+	cfg.BaseURL = f.URL + "/v1"
+	cfg.Model = "test"
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = LogOff
+	}
+	service, err := NewServiceWithConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+// answer is a reply in the comparison JSON format
+func answer(sourceYes, sourceNo string) string {
+	b, _ := json.Marshal(map[string]string{"reason": "test", "source_yes": sourceYes, "source_no": sourceNo})
+	return string(b)
+}
+
+var (
+	fedMarket = db.Market{ExternalID: "KXFED-26DEC-T4.00", EventTicker: "KXFED-26DEC", Category: "Economics",
+		Title: "Fed funds rate after December?", YesSubTitle: "Above 4.00%"}
+	fedMarketLower = db.Market{ExternalID: "KXFED-26DEC-T3.75", EventTicker: "KXFED-26DEC", Category: "Economics",
+		Title: "Fed funds rate after December?", YesSubTitle: "Above 3.75%"}
+)
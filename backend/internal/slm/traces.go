@@ -0,0 +1,181 @@
+package slm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// Log verbosity, chosen with SLM_LOG. Every call is recorded in the trace store
+// whatever the verbosity, so the log only needs enough to follow along.
+const (
+	LogOff     = "off"     // Nothing per call; failures still reach the caller
+	LogSummary = "summary" // One line per call
+	LogFull    = "full"    // Prompts and replies too, for working on prompts
+)
+
+// DefaultTraceRetention is how long recorded calls are kept
+const DefaultTraceRetention = 30 * 24 * time.Hour
+
+// TraceStore records every SLM call in the slm_calls table
+type TraceStore struct {
+	DB *gorm.DB
+}
+
+// NewTraceStore creates a trace store on the given database
+func NewTraceStore(database *gorm.DB) *TraceStore {
+	return &TraceStore{DB: database}
+}
+
+// Record stores a call. Failing to store it is logged rather than returned, so
+// tracing never fails a comparison.
+func (t *TraceStore) Record(call *db.SLMCall) {
+	if err := t.DB.Create(call).Error; err != nil {
+		log.Printf("Failed to record SLM call %s vs %s: %v", call.SourceTicker, call.TargetTicker, err)
+	}
+}
+
+// TraceFilter selects recorded calls
+type TraceFilter struct {
+	Ticker  string // Either market of the pair
+	Backend string
+	Failed  bool // Only calls that ended in an error
+	Before  uint // Only calls older than this ID, for paging
+	Limit   int
+}
+
+// List returns the calls matching filter, newest first
+func (t *TraceStore) List(filter TraceFilter) ([]db.SLMCall, error) {
+	query := t.DB.Model(&db.SLMCall{})
+	if filter.Ticker != "" {
+		query = query.Where("source_ticker = ? OR target_ticker = ?", filter.Ticker, filter.Ticker)
+	}
+	if filter.Backend != "" {
+		query = query.Where("backend = ?", filter.Backend)
+	}
+	if filter.Failed {
+		query = query.Where("error <> ''")
+	}
+	if filter.Before > 0 {
+		query = query.Where("id < ?", filter.Before)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var calls []db.SLMCall
+	err := query.Order("id DESC").Limit(limit).Find(&calls).Error
+	return calls, err
+}
+
+// Get returns one call, or gorm.ErrRecordNotFound
+func (t *TraceStore) Get(id uint) (*db.SLMCall, error) {
+	var call db.SLMCall
+	if err := t.DB.Where("id = ?", id).Limit(1).Find(&call).Error; err != nil {
+		return nil, err
+	}
+	if call.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &call, nil
+}
+
+// Prune deletes calls older than retention and returns how many
+func (t *TraceStore) Prune(retention time.Duration) (int, error) {
+	res := t.DB.Where("created_at < ?", time.Now().Add(-retention)).Delete(&db.SLMCall{})
+	return int(res.RowsAffected), res.Error
+}
+
+// trace collects what one call to a backend did
+type trace struct {
+	call    db.SLMCall
+	outputs []string
+	started time.Time
+}
+
+func (s *slmService) startTrace(source, target db.Market, temperature float64) *trace {
+	markets, _ := json.Marshal(newPromptData(source, target))
+	return &trace{
+		call: db.SLMCall{
+			SourceTicker:  source.ExternalID,
+			TargetTicker:  target.ExternalID,
+			PromptVersion: s.prompts.Version,
+			Backend:       s.backend.Name,
+			Model:         s.backend.Model,
+			Temperature:   temperature,
+			Markets:       string(markets),
+		},
+		outputs: []string{},
+		started: time.Now(),
+	}
+}
+
+// tracePrompts notes the prompts a call was sent, in order. Their hash is always
+// kept, which with the market snapshot is enough to tell whether re-rendering
+// reproduces them; the text itself only at full verbosity, where it's also logged.
+func (s *slmService) tracePrompts(tr *trace, prompts ...string) {
+	h := sha256.New()
+	for _, p := range prompts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	tr.call.PromptHash = hex.EncodeToString(h.Sum(nil))
+	if s.logLevel == LogFull {
+		text, _ := json.Marshal(prompts)
+		tr.call.Prompt = string(text)
+	}
+}
+
+// finishTrace records a call and logs it at the configured verbosity
+func (s *slmService) finishTrace(tr *trace, result *ComparisonResult, err error) {
+	call := &tr.call
+	call.LatencyMs = time.Since(tr.started).Milliseconds()
+	call.CreatedAt = time.Now()
+	output, _ := json.Marshal(tr.outputs)
+	call.Output = string(output)
+	if err != nil {
+		call.Error = err.Error()
+	} else {
+		parsed, _ := json.Marshal(result)
+		call.Result = string(parsed)
+	}
+	if s.traces != nil {
+		s.traces.Record(call)
+	}
+
+	if s.logLevel == LogOff {
+		return
+	}
+	if err != nil {
+		log.Printf("[SLM] %s/%s %s vs %s failed after %dms and %d attempts: %v",
+			call.Backend, call.Model, call.SourceTicker, call.TargetTicker, call.LatencyMs, call.Attempts, err)
+		return
+	}
+	log.Printf("[SLM] %s/%s %s vs %s: SourceYes->%s, SourceNo->%s in %dms, %d tokens, %d attempts",
+		call.Backend, call.Model, call.SourceTicker, call.TargetTicker,
+		outcomeName(result.SourceYes), outcomeName(result.SourceNo), call.LatencyMs, call.Tokens, call.Attempts)
+}
+
+// logFull logs prompts and replies when the verbosity asks for them
+func (s *slmService) logFull(format string, args ...any) {
+	if s.logLevel == LogFull {
+		log.Printf(format, args...)
+	}
+}
+
+// redact shortens model output quoted in errors, which end up in logs and job
+// records; the full output is in the trace
+func redact(output string) string {
+	const max = 200
+	if len(output) <= max {
+		return output
+	}
+	return output[:max] + "…"
+}
@@ -0,0 +1,193 @@
+package slm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	sqlite_vec.Auto()
+	m.Run()
+}
+
+func newTestTraceStore(t *testing.T) *TraceStore {
+	t.Helper()
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "slm.db"))
+	database, err := db.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.MigrateUp(database); err != nil {
+		t.Fatal(err)
+	}
+	return NewTraceStore(database)
+}
+
+func TestTraceStore(t *testing.T) {
+	store := newTestTraceStore(t)
+	now := time.Now()
+	calls := []db.SLMCall{
+		{SourceTicker: "KXA", TargetTicker: "KXB", Backend: "local", CreatedAt: now.Add(-40 * 24 * time.Hour)},
+		{SourceTicker: "KXA", TargetTicker: "KXC", Backend: "local", Error: "timeout", CreatedAt: now.Add(-time.Hour)},
+		{SourceTicker: "KXC", TargetTicker: "KXA", Backend: "hosted", CreatedAt: now.Add(-time.Minute)},
+		{SourceTicker: "KXB", TargetTicker: "KXC", Backend: "hosted", Error: "unparseable", CreatedAt: now},
+	}
+	for i := range calls {
+		store.Record(&calls[i])
+	}
+	id := func(i int) uint { return calls[i].ID }
+
+	tests := []struct {
+		name   string
+		filter TraceFilter
+		want   []uint
+	}{
+		{"newest first", TraceFilter{}, []uint{id(3), id(2), id(1), id(0)}},
+		{"either side of the pair", TraceFilter{Ticker: "KXA"}, []uint{id(2), id(1), id(0)}},
+		{"backend", TraceFilter{Backend: "hosted"}, []uint{id(3), id(2)}},
+		{"failed", TraceFilter{Failed: true}, []uint{id(3), id(1)}},
+		{"before", TraceFilter{Before: id(2)}, []uint{id(1), id(0)}},
+		{"limit", TraceFilter{Limit: 2}, []uint{id(3), id(2)}},
+		{"combined", TraceFilter{Ticker: "KXC", Failed: true, Limit: 1}, []uint{id(3)}},
+		{"nothing matches", TraceFilter{Backend: "other"}, []uint{}},
+	}
+	for _, tt := range tests {
+		got, err := store.List(tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		ids := make([]uint, len(got))
+		for i, call := range got {
+			ids[i] = call.ID
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+
+	call, err := store.Get(id(1))
+	if err != nil || call.SourceTicker != "KXA" || call.TargetTicker != "KXC" || call.Error != "timeout" {
+		t.Errorf("Get(%d) = %+v, %v", id(1), call, err)
+	}
+	if _, err := store.Get(id(3) + 1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Get of a missing call: %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	// Only the call past retention is pruned
+	pruned, err := store.Prune(DefaultTraceRetention)
+	if err != nil || pruned != 1 {
+		t.Errorf("Prune() = %d, %v, want 1", pruned, err)
+	}
+	if _, err := store.Get(id(0)); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("call past retention still stored: %v", err)
+	}
+	if remaining, _ := store.List(TraceFilter{}); len(remaining) != 3 {
+		t.Errorf("%d calls left after pruning, want 3", len(remaining))
+	}
+}
+
+func TestTraceVerbosity(t *testing.T) {
+	tests := []struct {
+		level       string
+		wantLines   int  // Log lines per call
+		wantPrompts bool // Prompt text logged and stored
+	}{
+		{LogOff, 0, false},
+		{LogSummary, 1, false},
+		// Two prompts, reasoning and answer for the two turns, then the summary
+		{LogFull, 5, true},
+	}
+
+	hashes := map[string]string{}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			store := newTestTraceStore(t)
+			f := newFakeServer(t, func(req chatRequest) string {
+				if len(req.Messages) == 2 {
+					return "Some reasoning."
+				}
+				return answer("target_yes", "none")
+			})
+			service := newTestService(t, f, Config{LogLevel: tt.level, Traces: store})
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			log.SetFlags(0)
+			t.Cleanup(func() {
+				log.SetOutput(os.Stderr)
+				log.SetFlags(log.LstdFlags)
+			})
+
+			if _, err := service.CompareMarkets(fedMarket, fedMarketLower); err != nil {
+				t.Fatal(err)
+			}
+
+			// 1. The call is recorded whatever the verbosity
+			calls, _ := store.List(TraceFilter{})
+			if len(calls) != 1 {
+				t.Fatalf("%d calls recorded, want 1", len(calls))
+			}
+			call := calls[0]
+			if call.SourceTicker != fedMarket.ExternalID || call.Tokens != 20 || call.Result == "" {
+				t.Errorf("recorded %+v", call)
+			}
+
+			// 2. The market text is kept as it was when asked, and the hash identifies the prompts
+			var markets promptData
+			if err := json.Unmarshal([]byte(call.Markets), &markets); err != nil {
+				t.Fatalf("market snapshot %q: %v", call.Markets, err)
+			}
+			if markets != newPromptData(fedMarket, fedMarketLower) {
+				t.Errorf("market snapshot %+v", markets)
+			}
+			if len(call.PromptHash) != 64 {
+				t.Errorf("prompt hash %q", call.PromptHash)
+			}
+			hashes[tt.level] = call.PromptHash
+
+			// 3. Prompt text only at full verbosity, where it's exactly what was sent
+			var prompts []string
+			json.Unmarshal([]byte(call.Prompt), &prompts)
+			if tt.wantPrompts {
+				requests := f.Requests()
+				answerMessages := requests[len(requests)-1].Messages
+				sent := []string{requests[0].Messages[0], requests[0].Messages[1], answerMessages[len(answerMessages)-1]}
+				if fmt.Sprint(prompts) != fmt.Sprint(sent) {
+					t.Errorf("stored prompts %q, want %q", prompts, sent)
+				}
+			} else if call.Prompt != "" {
+				t.Errorf("stored prompts at %s verbosity: %s", tt.level, call.Prompt)
+			}
+
+			output := strings.TrimSpace(logs.String())
+			lines := 0
+			if output != "" {
+				lines = len(strings.Split(output, "\n[SLM]")) // Prompts span several lines
+			}
+			if lines != tt.wantLines {
+				t.Errorf("%d log entries, want %d:\n%s", lines, tt.wantLines, output)
+			}
+			if logged := strings.Contains(output, "Prompt:"); logged != tt.wantPrompts {
+				t.Errorf("prompts logged: %v, want %v", logged, tt.wantPrompts)
+			}
+		})
+	}
+
+	if hashes[LogSummary] != hashes[LogFull] || hashes[LogOff] != hashes[LogFull] {
+		t.Errorf("the same prompts hashed differently by verbosity: %v", hashes)
+	}
+}
@@ -1,150 +1,154 @@
 package sync
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"time"
 
 	"backend/internal/db"
+	"backend/internal/slm"
+	"backend/internal/vectorstore"
 )
 
-// AnalyzeRelatedMarkets finds related markets for upcoming events
-func (s *Syncer) AnalyzeRelatedMarkets() {
-	log.Println("Starting related markets analysis...")
+// AnalysisStats counts the work done by a related markets analysis
+type AnalysisStats struct {
+	Cycle       uint // Comparison cycle worked on
+	Resumed     bool // Whether the cycle was planned by an earlier, interrupted run
+	Events      int  // Upcoming events analyzed
+	Markets     int  // Live markets searched for related ones
+	Pairs       int  // Market pairs queued for comparison
+	Comparisons int  // Market pairs compared
+	ByRules     int  // Comparisons resolved by rules rather than the SLM
+	Failed      int  // Comparisons that failed
+	Skipped     int  // Pairs dropped because a market closed or the budget ran out
+	Tokens      int  // SLM tokens spent
+	// ParseFailures counts SLM answers that didn't parse, including retried ones
+	ParseFailures int
+	// Rejected counts SLM implications dropped for failing the consistency checks
+	Rejected int
+	// Escalations counts comparisons passed on to a stronger SLM backend
+	Escalations int
+	Status      string
+}
 
-	// 1. Fetch upcoming events (closing within 14 days)
-	now := time.Now()
-	nextTwoWeeks := now.AddDate(0, 0, 14)
+// AnalyzeRelatedMarkets finds related markets for upcoming events and works out how
+// each pair is logically related, by rules where that is mechanical and otherwise
+// by asking the SLM; without one only the rules run and the other pairs are
+// skipped. The pairs are planned into a comparison cycle first and then
+// compared in priority order, several at a time, until the cycle's budget runs out.
+// Canceling ctx stops it after the comparisons in flight; the next run resumes the
+// cycle where it left off.
+func (s *Syncer) AnalyzeRelatedMarkets(ctx context.Context, progress Progress) (AnalysisStats, error) {
+	progress = orNoProgress(progress)
+	var stats AnalysisStats
+	log.Println("Starting related markets analysis...")
 
-	var upcomingEvents []db.Event
-	err := s.DB.Where("closest_market_close_time BETWEEN ? AND ?", now, nextTwoWeeks).
-		Find(&upcomingEvents).Error
+	// 1. Resume an interrupted cycle, or plan a new one
+	cycle, err := s.resumableComparisonCycle()
 	if err != nil {
-		log.Printf("Failed to fetch upcoming events for analysis: %v", err)
-		return
+		return stats, err
 	}
-
-	log.Printf("Found %d upcoming events to analyze.", len(upcomingEvents))
-
-	for _, event := range upcomingEvents {
-		// 2. Fetch live markets for this event
-		// Using the new method we added to the client
-		liveMarkets, err := s.KClient.GetMarketsForEventNextMonth(event.ExternalID)
-		if err != nil {
-			log.Printf("Failed to fetch live markets for event %s: %v", event.ExternalID, err)
-			continue
+	if cycle != nil {
+		stats.Resumed = true
+		log.Printf("Resuming comparison cycle %d started at %s.", cycle.ID, cycle.StartedAt.Format(time.RFC3339))
+	} else {
+		if cycle, err = s.planComparisonCycle(ctx, progress, &stats); err != nil {
+			return stats, err
 		}
-
-		// 3. Loop through markets and find related ones
-		for _, m := range liveMarkets {
-			// Construct query from title and subtitle
-			queryText := fmt.Sprintf("%s %s", m.Title, m.Subtitle)
-
-			// Find top 10 related markets
-			related, err := s.findRelatedMarkets(queryText, 10)
-			if err != nil {
-				log.Printf("Failed to find related markets for %s: %v", m.Ticker, err)
-				continue
-			}
-
-			// Process related markets with SLM
-			if len(related) > 0 {
-				// log.Printf("Analyzing %d related markets for %s...", len(related), m.Ticker)
-
-				// Convert simplified market to db.Market style for SLM helper
-				sourceMarket := db.Market{
-					ExternalID:  m.Ticker,
-					EventTicker: m.EventTicker,
-					Title:       m.Title,
-					Description: m.Subtitle,
-					YesSubTitle: m.YesSubTitle,
-					NoSubTitle:  m.NoSubTitle,
-				}
-
-				for _, r := range related {
-					if r.Market.ExternalID == m.Ticker {
-						continue
-					}
-					var targetEvent db.Event
-					// Try to fetch event to get close time, fallback to current market update time if fails
-					targetCloseTime := r.Market.LastDataUpdate
-					if err := s.DB.Where("external_id = ?", r.EventTicker).First(&targetEvent).Error; err == nil {
-						targetCloseTime = targetEvent.ClosestMarketCloseTime
-					}
-
-					s.processComparison(sourceMarket, r.Market, m.CloseTime, targetCloseTime)
-				}
-			}
+	}
+	stats.Cycle = cycle.ID
+
+	// 2. Work through the queue
+	var slmBefore slm.Stats
+	if s.SLMService != nil {
+		slmBefore = s.SLMService.Stats()
+	}
+	err = s.runComparisonCycle(ctx, cycle, progress, &stats)
+	if s.SLMService != nil {
+		slmAfter := s.SLMService.Stats()
+		stats.ParseFailures = int(slmAfter.ParseFailures - slmBefore.ParseFailures)
+		progress.Add("slm_parse_failures", stats.ParseFailures)
+		stats.Rejected = int(slmAfter.Rejected - slmBefore.Rejected)
+		progress.Add("slm_rejected_implications", stats.Rejected)
+		log.Printf("SLM output: %d unparseable answers this cycle; %d of %d comparisons since startup failed to parse.",
+			stats.ParseFailures, slmAfter.Unparseable, slmAfter.Comparisons)
+		log.Printf("SLM consistency checks: %d implications rejected this cycle, %d since startup.", stats.Rejected, slmAfter.Rejected)
+		stats.Escalations = int(slmAfter.Escalations - slmBefore.Escalations)
+		progress.Add("slm_escalations", stats.Escalations)
+		for _, b := range slmAfter.Backends {
+			log.Printf("SLM backend %s (%s) since startup: %d requests, %d failed, %d tokens, %v in requests.",
+				b.Name, b.Model, b.Requests, b.Failures, b.Tokens, b.Latency.Round(time.Second))
 		}
-
 	}
+	log.Printf("Related markets analysis %s. Cycle %d: %d events, %d markets, %d pairs queued, %d compared (%d by rules), %d failed, %d skipped, %d tokens.",
+		stats.Status, cycle.ID, stats.Events, stats.Markets, stats.Pairs, stats.Comparisons, stats.ByRules, stats.Failed, stats.Skipped, stats.Tokens)
+	return stats, err
 }
 
-func (s *Syncer) processComparison(source, target db.Market, sourceTime, targetTime time.Time) {
-	// 1. Date Check (within 1 month)
-	diff := sourceTime.Sub(targetTime)
-	daysDiff := math.Abs(diff.Hours() / 24.0)
+// processComparison works out how target relates to source and records any
+// implications found. Mechanical pairs are resolved by rules; the rest go to the
+// SLM unless a recent answer is cached. Returns the engine that answered and the
+// SLM tokens spent.
+func (s *Syncer) processComparison(source, target db.Market) (string, int, error) {
+	fmtMarket := func(m db.Market) string {
+		if m.YesSubTitle != "" || m.NoSubTitle != "" {
+			return fmt.Sprintf("%s [Yes: %s | No: %s]", m.Title, m.YesSubTitle, m.NoSubTitle)
+		}
+		return fmt.Sprintf("%s [%s]", m.Title, m.Description)
+	}
 
-	if daysDiff > 30 {
-		// log.Printf("Skipping comparison %s vs %s: Date diff %.1f days > 30", source.ExternalID, target.ExternalID, daysDiff)
-		return
+	// 1. Rules
+	if result := s.ruleImplication(source, target); result != nil {
+		if result.SourceYes != nil || result.SourceNo != nil {
+			s.saveImplications(source, target, result)
+			s.detectImplicationOpportunities(source, target, result)
+			log.Printf("Rules Analysis [%s vs %s]: SourceYes->%s, SourceNo->%s | %s",
+				fmtMarket(source), fmtMarket(target), outcomeString(result.SourceYes), outcomeString(result.SourceNo), result.Reason)
+		}
+		return EngineRules, 0, nil
 	}
 
 	// 2. Redis Check
 	cacheKey := fmt.Sprintf("rel:%s:%s", source.ExternalID, target.ExternalID)
 
 	if s.Redis != nil {
-		_, err := s.Redis.Get(cacheKey)
+		val, err := s.Redis.Get(cacheKey)
 		if err == nil {
-			// Found in Redis. Extend TTL to 3 hours and skip SLM.
-			// Re-save (or just set expire) - Set is easiest with existing helper if we had the value.
-			// Since we just want to extend, Expire is better but our wrapper might not expose it.
-			// Let's just skip for now as requested "if its in redis and not expired... it won't do the comparison".
-			// The user said "lets set the expiration to 3 hours".
-			// We need a way to extend. If wrapper doesn't have it, we assume we skip.
-			// User: "If its in redis and not expired lets set the expiration to 3 hours"
-			// I'll add Expire to Redis wrapper if needed or just re-set if I had the value.
-			// Wrapper only has Get/Add. I will use Get, then AddWithTTL.
-			// Wait, I need the value to re-set it. Get returns string.
-			val, _ := s.Redis.Get(cacheKey)
+			// Found in Redis: extend the TTL to 3 hours and skip the SLM
 			s.Redis.AddWithTTL(cacheKey, val, 3*time.Hour)
-			return
+
+			// Prices move even when the logic doesn't, so re-price cached implications
+			var cached slm.ComparisonResult
+			if err := json.Unmarshal([]byte(val), &cached); err == nil {
+				s.detectImplicationOpportunities(source, target, &cached)
+			}
+			return engineCache, 0, nil
 		}
 	}
 
 	// 3. SLM Call
 	if s.SLMService == nil {
-		return
+		return EngineSLM, 0, ErrNoSLMService
 	}
 
 	result, err := s.SLMService.CompareMarkets(source, target)
 	if err != nil {
-		log.Printf("SLM Comparison failed for %s vs %s: %v", source.ExternalID, target.ExternalID, err)
-		return
+		return EngineSLM, 0, fmt.Errorf("SLM comparison failed: %w", err)
 	}
+	result.Engine = EngineSLM
 
 	if result.SourceYes == nil && result.SourceNo == nil {
 		log.Printf("SLM Analysis [%s vs %s]: No logical necessity found (both null), skipping cache", source.ExternalID, target.ExternalID)
-		return
+		return EngineSLM, result.TokensUsed, nil
 	}
 
-	yesStr := "null"
-	if result.SourceYes != nil {
-		yesStr = *result.SourceYes
-	}
-	noStr := "null"
-	if result.SourceNo != nil {
-		noStr = *result.SourceNo
-	}
+	s.saveImplications(source, target, result)
+	s.detectImplicationOpportunities(source, target, result)
 
-	fmtMarket := func(m db.Market) string {
-		if m.YesSubTitle != "" || m.NoSubTitle != "" {
-			return fmt.Sprintf("%s [Yes: %s | No: %s]", m.Title, m.YesSubTitle, m.NoSubTitle)
-		}
-		return fmt.Sprintf("%s [%s]", m.Title, m.Description)
-	}
+	yesStr := outcomeString(result.SourceYes)
+	noStr := outcomeString(result.SourceNo)
 
 	// 4. Save to Redis
 	if s.Redis != nil {
@@ -158,40 +162,44 @@ func (s *Syncer) processComparison(source, target db.Market, sourceTime, targetT
 	} else {
 		log.Printf("SLM Analysis [%s vs %s]: SourceYes->%s, SourceNo->%s | Redis not available", fmtMarket(source), fmtMarket(target), yesStr, noStr)
 	}
+	return EngineSLM, result.TokensUsed, nil
+}
+
+// outcomeString prints an implied outcome, or null when there is none
+func outcomeString(outcome *string) string {
+	if outcome == nil {
+		return "null"
+	}
+	return *outcome
 }
 
 type MarketWithScore struct {
 	db.Market
-	Score float32
+	Score      float32
+	Similarity float64 // Score rescaled to 0 for the worst result and 1 for the best
 }
 
-func (s *Syncer) findRelatedMarkets(query string, limit int) ([]MarketWithScore, error) {
-	// 1. Generate embedding
-	vec, err := s.EmbeddingService.Generate(query)
-	if err != nil {
-		return nil, fmt.Errorf("embedding generation failed: %w", err)
-	}
-
-	vecBytes, _ := json.Marshal(vec)
-	vecString := string(vecBytes)
-
-	// 2. Perform vector search
-	type SearchResult struct {
-		ID       uint
-		Distance float64
-	}
-	var results []SearchResult
-
-	err = s.DB.Raw(`
-		SELECT rowid as id, distance 
-		FROM vec_markets 
-		WHERE embedding MATCH ? 
-		AND k = ?
-		ORDER BY distance
-	`, vecString, limit).Scan(&results).Error
+func (s *Syncer) findRelatedMarkets(query string, limit int, filters vectorstore.Filters) ([]MarketWithScore, error) {
+	var results []vectorstore.Result
+	if vectorstore.Compatible(s.Vectors, s.EmbeddingService.Model(), s.EmbeddingService.Dimension()) {
+		// 1. Generate embedding
+		vec, err := s.EmbeddingService.Generate(query)
+		if err != nil {
+			return nil, fmt.Errorf("embedding generation failed: %w", err)
+		}
 
-	if err != nil {
-		return nil, err
+		// 2. Perform hybrid search so shared tickers, names and numbers count as well as meaning
+		results, err = vectorstore.Hybrid(s.Vectors, vec, query, limit, filters)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// The active index was built with another model and is being replaced
+		var err error
+		results, err = s.Vectors.SearchText(query, limit, filters)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if len(results) == 0 {
@@ -200,10 +208,8 @@ func (s *Syncer) findRelatedMarkets(query string, limit int) ([]MarketWithScore,
 
 	// 3. Fetch full market details
 	var marketIDs []uint
-	scoreMap := make(map[uint]float64)
 	for _, r := range results {
 		marketIDs = append(marketIDs, r.ID)
-		scoreMap[r.ID] = r.Distance
 	}
 
 	var dbMarkets []db.Market
@@ -218,11 +224,13 @@ func (s *Syncer) findRelatedMarkets(query string, limit int) ([]MarketWithScore,
 		marketMap[m.ID] = m
 	}
 
-	for _, r := range results {
+	similarities := vectorstore.Similarities(results)
+	for i, r := range results {
 		if m, exists := marketMap[r.ID]; exists {
 			response = append(response, MarketWithScore{
-				Market: m,
-				Score:  float32(r.Distance),
+				Market:     m,
+				Score:      float32(r.Score),
+				Similarity: similarities[i],
 			})
 		}
 	}
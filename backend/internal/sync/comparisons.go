@@ -0,0 +1,468 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/vectorstore"
+
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+// Comparison cycle statuses
+const (
+	cycleRunning   = "running"
+	cycleCompleted = "completed"
+	cycleExhausted = "exhausted" // Stopped when its budget ran out
+	cycleExpired   = "expired"   // Abandoned before finishing and too old to resume
+)
+
+// Comparison pair statuses
+const (
+	pairPending = "pending"
+	pairDone    = "done"
+	pairFailed  = "failed"
+	pairSkipped = "skipped"
+)
+
+const (
+	// DefaultComparisonWorkers matches the OLLAMA_NUM_PARALLEL of the compose setup
+	DefaultComparisonWorkers = 2
+	// DefaultComparisonTimeBudget leaves headroom before the next analysis run
+	DefaultComparisonTimeBudget = 2 * time.Hour
+	// relatedMarketsPerMarket is how many related markets are queued per market
+	relatedMarketsPerMarket = 10
+	// maxCycleAge is how long an interrupted cycle stays worth resuming; after that
+	// its plan is stale and a fresh one is made
+	maxCycleAge = 12 * time.Hour
+)
+
+// ComparisonBudget caps what one comparison cycle may spend. Zero means no limit.
+// Comparisons already in flight when the budget runs out still finish, so a cycle
+// can overshoot by up to ComparisonWorkers comparisons.
+type ComparisonBudget struct {
+	Tokens int           // SLM tokens
+	Time   time.Duration // Time spent comparing, summed over resumes
+}
+
+// exceeded describes why the budget is spent, or returns "" while it isn't
+func (b ComparisonBudget) exceeded(tokens int, elapsed time.Duration) string {
+	switch {
+	case b.Tokens > 0 && tokens >= b.Tokens:
+		return fmt.Sprintf("token budget of %d spent", b.Tokens)
+	case b.Time > 0 && elapsed >= b.Time:
+		return fmt.Sprintf("time budget of %v spent", b.Time)
+	}
+	return ""
+}
+
+// comparisonPriority ranks a pair for the queue from 0 to 1. Pairs that are close
+// search matches, liquid enough to trade and about to close are compared first.
+// Closeness is the target's search score normalised across the source's results,
+// so a clear best match stands apart from a list of near ties; pairs that tie on
+// priority are taken in search rank order.
+func comparisonPriority(similarity float64, source, target db.Market, sourceClose, targetClose, now time.Time) float64 {
+	// A pair is only as tradeable as its thinner market; $1M of liquidity scores 1
+	dollars := float64(min(source.Liquidity, target.Liquidity)) / 100
+	liquidity := math.Min(math.Max(math.Log10(dollars+1)/6, 0), 1)
+
+	earliest := sourceClose
+	if targetClose.Before(earliest) {
+		earliest = targetClose
+	}
+	days := math.Max(earliest.Sub(now).Hours()/24, 0)
+	urgency := 1 / (1 + days)
+
+	return 0.5*similarity + 0.3*liquidity + 0.2*urgency
+}
+
+// resumableComparisonCycle returns the cycle an earlier run left unfinished, if it
+// is recent enough to resume. Older unfinished cycles are expired.
+func (s *Syncer) resumableComparisonCycle() (*db.ComparisonCycle, error) {
+	var cycles []db.ComparisonCycle
+	if err := s.DB.Where("status = ?", cycleRunning).Order("started_at DESC").Find(&cycles).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch unfinished comparison cycles: %w", err)
+	}
+
+	var resumable *db.ComparisonCycle
+	for i := range cycles {
+		cycle := &cycles[i]
+		if resumable == nil && time.Since(cycle.StartedAt) < maxCycleAge {
+			resumable = cycle
+			continue
+		}
+		if _, err := s.finishComparisonCycle(cycle, cycleExpired, "cycle expired"); err != nil {
+			return nil, err
+		}
+		log.Printf("Expired comparison cycle %d started at %s.", cycle.ID, cycle.StartedAt.Format(time.RFC3339))
+	}
+	return resumable, nil
+}
+
+// planComparisonCycle searches related markets for every live market of the
+// upcoming events and queues the pairs found as a new cycle
+func (s *Syncer) planComparisonCycle(ctx context.Context, progress Progress, stats *AnalysisStats) (*db.ComparisonCycle, error) {
+	if s.KClient == nil {
+		return nil, ErrNoKalshiClient
+	}
+
+	// 1. Fetch upcoming events (closing within 14 days)
+	now := time.Now()
+	nextTwoWeeks := now.AddDate(0, 0, 14)
+
+	var upcomingEvents []db.Event
+	err := s.DB.Where("closest_market_close_time BETWEEN ? AND ?", now, nextTwoWeeks).
+		Find(&upcomingEvents).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upcoming events for analysis: %w", err)
+	}
+
+	log.Printf("Found %d upcoming events to analyze.", len(upcomingEvents))
+
+	var pairs []db.ComparisonPair
+	queued := make(map[[2]string]bool)
+	for _, event := range upcomingEvents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// 2. Fetch live markets for this event
+		liveMarkets, err := s.KClient.GetMarketsForEventNextMonth(event.ExternalID)
+		if err != nil {
+			log.Printf("Failed to fetch live markets for event %s: %v", event.ExternalID, err)
+			continue
+		}
+		stats.Events++
+		progress.Add("events", 1)
+
+		// Liquidity and descriptions come from the stored markets
+		tickers := make([]string, len(liveMarkets))
+		for i, m := range liveMarkets {
+			tickers[i] = m.Ticker
+		}
+		var stored []db.Market
+		if err := s.DB.Where("external_id IN ?", tickers).Find(&stored).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch markets of event %s: %w", event.ExternalID, err)
+		}
+		storedByTicker := make(map[string]db.Market, len(stored))
+		for _, m := range stored {
+			storedByTicker[m.ExternalID] = m
+		}
+
+		// 3. Queue each market's related markets
+		for _, m := range liveMarkets {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			source, ok := storedByTicker[m.Ticker]
+			if !ok {
+				// Not synced yet; the next cycle will pick it up
+				continue
+			}
+
+			// Construct query from title and subtitle
+			queryText := fmt.Sprintf("%s %s", m.Title, m.Subtitle)
+
+			// Find related markets still trading and closing within a month of this one
+			filters := vectorstore.Filters{Status: "active"}
+			if !m.CloseTime.IsZero() {
+				filters.CloseAfter = m.CloseTime.AddDate(0, 0, -30)
+				filters.CloseBefore = m.CloseTime.AddDate(0, 0, 30)
+			}
+			related, err := s.findRelatedMarkets(queryText, relatedMarketsPerMarket, filters)
+			if err != nil {
+				log.Printf("Failed to find related markets for %s: %v", m.Ticker, err)
+				continue
+			}
+			stats.Markets++
+			progress.Add("markets", 1)
+
+			for rank, r := range related {
+				key := [2]string{m.Ticker, r.Market.ExternalID}
+				if r.Market.ExternalID == m.Ticker || queued[key] {
+					continue
+				}
+
+				targetCloseTime := r.Market.CloseTime
+				if targetCloseTime.IsZero() {
+					// Fall back to the event's close time, then to the last update
+					targetCloseTime = r.Market.LastDataUpdate
+					var targetEvent db.Event
+					if err := s.DB.Where("external_id = ?", r.EventTicker).First(&targetEvent).Error; err == nil {
+						targetCloseTime = targetEvent.ClosestMarketCloseTime
+					}
+				}
+				// Markets closing more than a month apart are not compared
+				if math.Abs(m.CloseTime.Sub(targetCloseTime).Hours()/24) > 30 {
+					continue
+				}
+
+				queued[key] = true
+				pairs = append(pairs, db.ComparisonPair{
+					SourceTicker:    m.Ticker,
+					TargetTicker:    r.Market.ExternalID,
+					SourceCloseTime: m.CloseTime,
+					TargetCloseTime: targetCloseTime,
+					SearchRank:      rank,
+					Priority:        comparisonPriority(r.Similarity, source, r.Market, m.CloseTime, targetCloseTime, now),
+					Status:          pairPending,
+				})
+			}
+		}
+	}
+
+	// 4. Store the cycle with its queue
+	cycle := &db.ComparisonCycle{Status: cycleRunning, Pairs: len(pairs), StartedAt: now}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(cycle).Error; err != nil {
+			return err
+		}
+		for i := range pairs {
+			pairs[i].CycleID = cycle.ID
+		}
+		if len(pairs) > 0 {
+			return tx.CreateInBatches(pairs, 100).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store comparison cycle: %w", err)
+	}
+
+	stats.Pairs = len(pairs)
+	progress.Add("pairs_queued", len(pairs))
+	log.Printf("Planned comparison cycle %d with %d pairs.", cycle.ID, len(pairs))
+	return cycle, nil
+}
+
+// pairResult is the outcome of one queued comparison
+type pairResult struct {
+	pair   db.ComparisonPair
+	status string
+	engine string
+	tokens int
+	err    error
+}
+
+// runComparisonCycle compares a cycle's pending pairs, highest priority first, on
+// ComparisonWorkers workers. Results are recorded as they arrive so an interrupted
+// cycle resumes without repeating work.
+func (s *Syncer) runComparisonCycle(ctx context.Context, cycle *db.ComparisonCycle, progress Progress, stats *AnalysisStats) error {
+	// 1. Load the pairs still to compare
+	var pending []db.ComparisonPair
+	if err := s.DB.Where("cycle_id = ? AND status = ?", cycle.ID, pairPending).
+		Order("priority DESC, search_rank, id").Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to fetch comparison queue: %w", err)
+	}
+
+	started := time.Now()
+	spent := time.Duration(cycle.ElapsedMs) * time.Millisecond
+	elapsed := func() time.Duration {
+		return spent + time.Since(started)
+	}
+	var tokensUsed atomic.Int64
+	tokensUsed.Store(int64(cycle.TokensUsed))
+	workers := max(s.ComparisonWorkers, 1)
+
+	// 2. Dispatch pairs until the queue is empty, ctx is canceled or the budget is
+	// spent. A pair is only dispatched once a worker's previous result is recorded,
+	// so the budget check sees every comparison but those in flight.
+	queue := make(chan db.ComparisonPair, workers)
+	slots := make(chan struct{}, workers)
+	var exhausted string
+	var dispatch errgroup.Group
+	dispatch.Go(func() error {
+		defer close(queue)
+		for _, pair := range pending {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil
+			}
+			if exhausted = s.ComparisonBudget.exceeded(int(tokensUsed.Load()), elapsed()); exhausted != "" {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			queue <- pair
+		}
+		return nil
+	})
+
+	// 3. Compare on the workers
+	results := make(chan pairResult, workers)
+	var compare errgroup.Group
+	for range workers {
+		compare.Go(func() error {
+			for pair := range queue {
+				results <- s.comparePair(pair)
+			}
+			return nil
+		})
+	}
+	go func() {
+		compare.Wait()
+		close(results)
+	}()
+
+	// 4. Record results one at a time
+	for res := range results {
+		tokensUsed.Add(int64(res.tokens))
+		if err := s.recordPairResult(cycle, res, elapsed()); err != nil {
+			log.Printf("Failed to record comparison %s vs %s: %v", res.pair.SourceTicker, res.pair.TargetTicker, err)
+		}
+
+		switch res.status {
+		case pairDone:
+			stats.Comparisons++
+			progress.Add("comparisons", 1)
+			if res.engine == EngineRules {
+				stats.ByRules++
+				progress.Add("resolved_by_rules", 1)
+			}
+		case pairFailed:
+			stats.Comparisons++
+			stats.Failed++
+			progress.Add("comparisons", 1)
+			progress.Add("comparisons_failed", 1)
+		case pairSkipped:
+			stats.Skipped++
+			progress.Add("pairs_skipped", 1)
+		}
+		stats.Tokens += res.tokens
+		progress.Add("tokens", res.tokens)
+		<-slots
+	}
+	dispatch.Wait()
+
+	// 5. Close the cycle unless it was interrupted
+	if err := ctx.Err(); err != nil {
+		stats.Status = "interrupted"
+		return err
+	}
+	status, reason := cycleCompleted, ""
+	if exhausted != "" {
+		status, reason = cycleExhausted, exhausted
+		log.Printf("Comparison cycle %d stopped: %s.", cycle.ID, exhausted)
+	}
+	skipped, err := s.finishComparisonCycle(cycle, status, reason)
+	if err != nil {
+		return err
+	}
+	stats.Status = status
+	stats.Skipped += skipped
+	progress.Add("pairs_skipped", skipped)
+	return nil
+}
+
+// comparePair compares a queued pair using the markets as currently stored, so
+// pairs whose markets stopped trading since the cycle was planned are skipped
+func (s *Syncer) comparePair(pair db.ComparisonPair) pairResult {
+	res := pairResult{pair: pair, status: pairSkipped}
+
+	var markets []db.Market
+	if err := s.DB.Where("external_id IN ?", []string{pair.SourceTicker, pair.TargetTicker}).
+		Find(&markets).Error; err != nil {
+		res.status, res.err = pairFailed, err
+		return res
+	}
+	var source, target *db.Market
+	for i := range markets {
+		switch markets[i].ExternalID {
+		case pair.SourceTicker:
+			source = &markets[i]
+		case pair.TargetTicker:
+			target = &markets[i]
+		}
+	}
+	now := time.Now()
+	for _, m := range []*db.Market{source, target} {
+		if m == nil || m.Status != "active" || (!m.CloseTime.IsZero() && m.CloseTime.Before(now)) {
+			res.err = errors.New("market no longer trading")
+			return res
+		}
+	}
+
+	res.engine, res.tokens, res.err = s.processComparison(*source, *target)
+	res.status = pairDone
+	if errors.Is(res.err, ErrNoSLMService) {
+		// Rules still run without an SLM; the pairs they can't settle wait for one
+		res.status = pairSkipped
+		return res
+	}
+	if res.err != nil {
+		log.Printf("Comparison %s vs %s failed: %v", pair.SourceTicker, pair.TargetTicker, res.err)
+		res.status = pairFailed
+	}
+	return res
+}
+
+// recordPairResult stores a comparison's outcome and adds it to its cycle's totals
+func (s *Syncer) recordPairResult(cycle *db.ComparisonCycle, res pairResult, elapsed time.Duration) error {
+	now := time.Now()
+	errMsg := ""
+	if res.err != nil {
+		errMsg = res.err.Error()
+	}
+
+	compared, failed := 0, 0
+	switch res.status {
+	case pairDone:
+		compared = 1
+	case pairFailed:
+		compared, failed = 1, 1
+	}
+
+	cycle.Compared += compared
+	cycle.Failed += failed
+	cycle.TokensUsed += res.tokens
+	cycle.ElapsedMs = elapsed.Milliseconds()
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&db.ComparisonPair{}).Where("id = ?", res.pair.ID).Updates(map[string]interface{}{
+			"status":       res.status,
+			"engine":       res.engine,
+			"error":        errMsg,
+			"tokens":       res.tokens,
+			"processed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&db.ComparisonCycle{}).Where("id = ?", cycle.ID).Updates(map[string]interface{}{
+			"compared":    cycle.Compared,
+			"failed":      cycle.Failed,
+			"tokens_used": cycle.TokensUsed,
+			"elapsed_ms":  cycle.ElapsedMs,
+		}).Error
+	})
+}
+
+// finishComparisonCycle closes a cycle, skipping the pairs it never got to with
+// reason. Returns how many pairs were skipped.
+func (s *Syncer) finishComparisonCycle(cycle *db.ComparisonCycle, status, reason string) (int, error) {
+	now := time.Now()
+	skipped := 0
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&db.ComparisonPair{}).Where("cycle_id = ? AND status = ?", cycle.ID, pairPending).
+			Updates(map[string]interface{}{"status": pairSkipped, "error": reason})
+		if result.Error != nil {
+			return result.Error
+		}
+		skipped = int(result.RowsAffected)
+		return tx.Model(&db.ComparisonCycle{}).Where("id = ?", cycle.ID).
+			Updates(map[string]interface{}{"status": status, "finished_at": now}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to finish comparison cycle %d: %w", cycle.ID, err)
+	}
+	cycle.Status = status
+	cycle.FinishedAt = &now
+	return skipped, nil
+}
@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+)
+
+func TestMain(m *testing.M) {
+	sqlite_vec.Auto()
+	os.Exit(m.Run())
+}
+
+func TestComparisonBudgetExceeded(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  ComparisonBudget
+		tokens  int
+		elapsed time.Duration
+		want    string // "" while the budget isn't spent
+	}{
+		{"no limits", ComparisonBudget{}, 1_000_000, 100 * time.Hour, ""},
+		{"under the token budget", ComparisonBudget{Tokens: 1000}, 999, 0, ""},
+		{"at the token budget", ComparisonBudget{Tokens: 1000}, 1000, 0, "token budget of 1000 spent"},
+		{"over the token budget", ComparisonBudget{Tokens: 1000}, 1500, 0, "token budget of 1000 spent"},
+		{"under the time budget", ComparisonBudget{Time: time.Hour}, 0, 59 * time.Minute, ""},
+		{"at the time budget", ComparisonBudget{Time: time.Hour}, 0, time.Hour, "time budget of 1h0m0s spent"},
+		{"time unlimited with tokens spent", ComparisonBudget{Tokens: 10}, 10, 100 * time.Hour, "token budget of 10 spent"},
+		{"tokens unlimited with time spent", ComparisonBudget{Time: time.Minute}, 1_000_000, time.Minute, "time budget of 1m0s spent"},
+		{"both spent reports tokens", ComparisonBudget{Tokens: 10, Time: time.Minute}, 10, time.Minute, "token budget of 10 spent"},
+		{"both set, neither spent", ComparisonBudget{Tokens: 10, Time: time.Minute}, 9, time.Second, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.budget.exceeded(tt.tokens, tt.elapsed); got != tt.want {
+			t.Errorf("%s: exceeded(%d, %v) = %q, want %q", tt.name, tt.tokens, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestComparisonPriority(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	liquid := func(cents int) db.Market { return db.Market{Liquidity: cents} }
+
+	tests := []struct {
+		name                     string
+		similarity               float64
+		source, target           db.Market
+		sourceClose, targetClose time.Time
+		want                     float64
+	}{
+		{"best match, $1M liquid, closing now", 1, liquid(100_000_000), liquid(100_000_000), now, now, 1},
+		{"scored halfway between the best and worst", 0.5, liquid(0), liquid(0), now, now, 0.5*0.5 + 0.2},
+		{"worst match, illiquid, closing in 3 days", 0, liquid(0), liquid(0), now.Add(3 * day), now.Add(3 * day), 0.2 * 0.25},
+		{"thinner market sets liquidity", 1, liquid(100_000_000), liquid(0), now.Add(day), now.Add(day), 0.5 + 0.2*0.5},
+		{"$999 is halfway to $1M on a log scale", 1, liquid(99_900), liquid(1_000_000), now.Add(3 * day), now.Add(3 * day), 0.5 + 0.3*0.5 + 0.2*0.25},
+		{"liquidity caps at $1M", 0, liquid(10_000_000_000), liquid(10_000_000_000), now.Add(3 * day), now.Add(3 * day), 0.3 + 0.2*0.25},
+		{"earliest close counts, source first", 0, liquid(0), liquid(0), now.Add(day), now.Add(3 * day), 0.2 * 0.5},
+		{"earliest close counts, target first", 0, liquid(0), liquid(0), now.Add(3 * day), now.Add(day), 0.2 * 0.5},
+		{"already closed counts as closing now", 0, liquid(0), liquid(0), now.Add(-3 * day), now.Add(day), 0.2},
+	}
+	for _, tt := range tests {
+		got := comparisonPriority(tt.similarity, tt.source, tt.target, tt.sourceClose, tt.targetClose, now)
+		if math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: priority = %.6f, want %.6f", tt.name, got, tt.want)
+		}
+	}
+}
+
+// stubSLM answers every comparison with no implication for a fixed number of
+// tokens. The first hold comparisons wait until all of them are in flight.
+type stubSLM struct {
+	tokens int
+	hold   int64
+	held   chan struct{}
+	calls  atomic.Int64
+}
+
+func (s *stubSLM) CompareMarkets(source, target db.Market) (*slm.ComparisonResult, error) {
+	if n := s.calls.Add(1); n <= s.hold {
+		if n == s.hold {
+			close(s.held)
+		}
+		<-s.held
+	}
+	return &slm.ComparisonResult{Reason: "unrelated", TokensUsed: s.tokens}, nil
+}
+
+func (s *stubSLM) CompareMarketsAt(source, target db.Market, temperature float64) (*slm.ComparisonResult, error) {
+	return s.CompareMarkets(source, target)
+}
+
+func (s *stubSLM) Stats() slm.Stats {
+	return slm.Stats{Comparisons: s.calls.Load()}
+}
+
+func TestRunComparisonCycleBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		budget       ComparisonBudget
+		workers      int
+		spent        time.Duration // Time spent before the cycle was resumed
+		wantCompared int
+		wantStatus   string
+		wantReason   string
+	}{
+		{"no budget", ComparisonBudget{}, 1, 0, 6, cycleCompleted, ""},
+		{"budget not reached", ComparisonBudget{Tokens: 1000}, 1, 0, 6, cycleCompleted, ""},
+		{"token budget", ComparisonBudget{Tokens: 25}, 1, 0, 3, cycleExhausted, "token budget of 25 spent"},
+		{"token budget on a pair boundary", ComparisonBudget{Tokens: 20}, 1, 0, 2, cycleExhausted, "token budget of 20 spent"},
+		{"time spent before resuming", ComparisonBudget{Time: time.Hour}, 1, time.Hour, 0, cycleExhausted, "time budget of 1h0m0s spent"},
+		// Workers each take a pair before any result is in
+		{"in-flight comparisons overshoot", ComparisonBudget{Tokens: 5}, 3, 0, 3, cycleExhausted, "token budget of 5 spent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newTestDB(t)
+
+			// Six pairs of markets from different events, so no rule resolves them
+			closes := time.Now().Add(7 * 24 * time.Hour)
+			cycle := &db.ComparisonCycle{Status: cycleRunning, Pairs: 6, StartedAt: time.Now(), ElapsedMs: tt.spent.Milliseconds()}
+			database.Create(cycle)
+			for i := range 6 {
+				source, target := fmt.Sprintf("KXA-%d", i), fmt.Sprintf("KXB-%d", i)
+				for _, ticker := range []string{source, target} {
+					database.Create(&db.Market{ExternalID: ticker, Ticker: ticker, EventTicker: ticker, Status: "active", CloseTime: closes})
+				}
+				database.Create(&db.ComparisonPair{CycleID: cycle.ID, SourceTicker: source, TargetTicker: target,
+					Priority: float64(i), Status: pairPending})
+			}
+
+			service := &stubSLM{tokens: 10, hold: int64(min(tt.workers, tt.wantCompared)), held: make(chan struct{})}
+			syncer := &Syncer{DB: database, SLMService: service, ComparisonWorkers: tt.workers, ComparisonBudget: tt.budget}
+			stats := &AnalysisStats{}
+			if err := syncer.runComparisonCycle(context.Background(), cycle, orNoProgress(nil), stats); err != nil {
+				t.Fatal(err)
+			}
+
+			if calls := int(service.calls.Load()); calls != tt.wantCompared {
+				t.Errorf("%d comparisons, want %d", calls, tt.wantCompared)
+			}
+			var stored db.ComparisonCycle
+			database.First(&stored, cycle.ID)
+			if stored.Status != tt.wantStatus || stored.Compared != tt.wantCompared || stored.TokensUsed != 10*tt.wantCompared {
+				t.Errorf("cycle %s with %d compared and %d tokens, want %s with %d and %d",
+					stored.Status, stored.Compared, stored.TokensUsed, tt.wantStatus, tt.wantCompared, 10*tt.wantCompared)
+			}
+			if stats.Skipped != 6-tt.wantCompared {
+				t.Errorf("%d pairs skipped, want %d", stats.Skipped, 6-tt.wantCompared)
+			}
+
+			// The highest priority pairs are compared first, the rest skipped with the reason
+			var pairs []db.ComparisonPair
+			database.Where("cycle_id = ?", cycle.ID).Order("priority DESC").Find(&pairs)
+			for i, pair := range pairs {
+				if i < tt.wantCompared {
+					if pair.Status != pairDone {
+						t.Errorf("pair %s is %s, want done", pair.SourceTicker, pair.Status)
+					}
+					continue
+				}
+				if pair.Status != pairSkipped || !strings.Contains(pair.Error, tt.wantReason) {
+					t.Errorf("pair %s is %s (%q), want skipped for %q", pair.SourceTicker, pair.Status, pair.Error, tt.wantReason)
+				}
+			}
+		})
+	}
+}
+
+func TestRunComparisonCycleWithoutSLM(t *testing.T) {
+	database := newTestDB(t)
+	closes := time.Now().Add(7 * 24 * time.Hour)
+
+	// Two markets of a mutually exclusive event, which rules resolve, and two
+	// unrelated markets only the SLM could compare
+	database.Create(&db.Event{ExternalID: "KXFED", MutuallyExclusive: true, Status: "active"})
+	for _, m := range []db.Market{
+		{ExternalID: "KXFED-A", EventTicker: "KXFED"},
+		{ExternalID: "KXFED-B", EventTicker: "KXFED"},
+		{ExternalID: "KXA-1", EventTicker: "KXA"},
+		{ExternalID: "KXB-1", EventTicker: "KXB"},
+	} {
+		m.Ticker, m.Status, m.CloseTime = m.ExternalID, "active", closes
+		database.Create(&m)
+	}
+	cycle := &db.ComparisonCycle{Status: cycleRunning, Pairs: 2, StartedAt: time.Now()}
+	database.Create(cycle)
+	database.Create(&db.ComparisonPair{CycleID: cycle.ID, SourceTicker: "KXA-1", TargetTicker: "KXB-1", Priority: 1, Status: pairPending})
+	database.Create(&db.ComparisonPair{CycleID: cycle.ID, SourceTicker: "KXFED-A", TargetTicker: "KXFED-B", Status: pairPending})
+
+	syncer := &Syncer{DB: database}
+	stats := &AnalysisStats{}
+	if err := syncer.runComparisonCycle(context.Background(), cycle, orNoProgress(nil), stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Comparisons != 1 || stats.ByRules != 1 || stats.Failed != 0 || stats.Skipped != 1 || stats.Status != cycleCompleted {
+		t.Errorf("stats %+v, want one pair resolved by rules and one skipped", stats)
+	}
+
+	pairs := map[string]db.ComparisonPair{}
+	var rows []db.ComparisonPair
+	database.Where("cycle_id = ?", cycle.ID).Find(&rows)
+	for _, p := range rows {
+		pairs[p.SourceTicker] = p
+	}
+	if p := pairs["KXFED-A"]; p.Status != pairDone || p.Engine != EngineRules {
+		t.Errorf("rules pair %s by %q, want done by rules", p.Status, p.Engine)
+	}
+	if p := pairs["KXA-1"]; p.Status != pairSkipped || p.Error != ErrNoSLMService.Error() {
+		t.Errorf("SLM pair %s (%q), want skipped for the missing SLM", p.Status, p.Error)
+	}
+
+	var implications int64
+	database.Model(&db.MarketImplication{}).Where("source_ticker = ? AND target_ticker = ?", "KXFED-A", "KXFED-B").Count(&implications)
+	if implications != 1 {
+		t.Errorf("%d implications stored for the rules pair, want 1", implications)
+	}
+}
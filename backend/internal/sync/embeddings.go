@@ -0,0 +1,205 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/vectorstore"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// EmbeddingTemplateVersion identifies marketEmbeddingText. Bump it whenever the
+	// template changes so the index is rebuilt with the new text.
+	EmbeddingTemplateVersion = 1
+
+	// embedBatchSize is how many markets are embedded and stored per GenerateBatch call
+	embedBatchSize = 64
+	// hashLookupBatchSize bounds the number of IDs bound into a single hash lookup
+	hashLookupBatchSize = 500
+	// maxBuildPasses bounds the catch-up passes over markets that changed during a build
+	maxBuildPasses = 3
+)
+
+// EmbedStats summarises an embedding pass
+type EmbedStats struct {
+	Embedded int
+	Skipped  int // Text unchanged since the last embedding
+	Failed   int
+	Duration time.Duration
+}
+
+// Rate returns markets embedded per second
+func (st EmbedStats) Rate() float64 {
+	if st.Duration <= 0 {
+		return 0
+	}
+	return float64(st.Embedded) / st.Duration.Seconds()
+}
+
+// marketEmbeddingText is the text a market's embedding is generated from
+func marketEmbeddingText(m db.Market) string {
+	return fmt.Sprintf("%s %s %s", m.Title, m.Description, m.Category)
+}
+
+func embeddingHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// embeddingIndexes returns the live indexes built with this syncer's embedding model
+// and template, which are the ones its vectors can be written to
+func (s *Syncer) embeddingIndexes() ([]db.EmbeddingIndex, error) {
+	indexes, err := vectorstore.LiveIndexes(s.DB)
+	if err != nil {
+		return nil, err
+	}
+	matching := indexes[:0]
+	for _, index := range indexes {
+		if vectorstore.SameModel(index, s.EmbeddingService.Model(), s.EmbeddingService.Dimension()) &&
+			index.TemplateVersion == EmbeddingTemplateVersion {
+			matching = append(matching, index)
+		}
+	}
+	return matching, nil
+}
+
+// EmbedMarkets embeds the given markets in batches and stores the vectors in the index.
+// Markets whose embedding text is unchanged since they were last embedded into the
+// index are skipped unless force is set. Markets must have their IDs populated.
+func (s *Syncer) EmbedMarkets(index db.EmbeddingIndex, markets []db.Market, force bool) EmbedStats {
+	start := time.Now()
+	var stats EmbedStats
+	store := vectorstore.ForIndex(s.DB, index)
+
+	// 1. Work out which markets actually need a new vector
+	hashes := make(map[uint]string, len(markets))
+	if !force {
+		ids := make([]uint, len(markets))
+		for i, m := range markets {
+			ids[i] = m.ID
+		}
+		for from := 0; from < len(ids); from += hashLookupBatchSize {
+			var existing []db.MarketEmbedding
+			if err := s.DB.Where("index_id = ? AND market_id IN ?", index.ID, ids[from:min(from+hashLookupBatchSize, len(ids))]).
+				Find(&existing).Error; err != nil {
+				log.Printf("Failed to load embedding hashes, re-embedding all: %v", err)
+				break
+			}
+			for _, e := range existing {
+				hashes[e.MarketID] = e.TextHash
+			}
+		}
+	}
+
+	var pending []db.Market
+	var texts []string
+	for _, m := range markets {
+		text := marketEmbeddingText(m)
+		if hash, ok := hashes[m.ID]; ok && hash == embeddingHash(text) {
+			stats.Skipped++
+			continue
+		}
+		pending = append(pending, m)
+		texts = append(texts, text)
+	}
+
+	// 2. Embed and store batch by batch so progress survives a failure part way through
+	for from := 0; from < len(pending); from += embedBatchSize {
+		to := min(from+embedBatchSize, len(pending))
+
+		vectors, err := s.EmbeddingService.GenerateBatch(texts[from:to])
+		if err != nil {
+			log.Printf("Failed to generate embeddings for batch of %d markets: %v", to-from, err)
+			stats.Failed += to - from
+			continue
+		}
+
+		for i, vec := range vectors {
+			m := pending[from+i]
+			if err := store.Upsert(m, vec); err != nil {
+				log.Printf("Failed to save embedding for market %d: %v", m.ID, err)
+				stats.Failed++
+				continue
+			}
+			if err := s.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "index_id"}, {Name: "market_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"text_hash", "updated_at"}),
+			}).Create(&db.MarketEmbedding{
+				IndexID:  index.ID,
+				MarketID: m.ID,
+				TextHash: embeddingHash(texts[from+i]),
+			}).Error; err != nil {
+				log.Printf("Failed to record embedding hash for market %d: %v", m.ID, err)
+			}
+			stats.Embedded++
+		}
+	}
+
+	stats.Duration = time.Since(start)
+	return stats
+}
+
+// BuildEmbeddingIndex rebuilds the vector index for the current embedding model and
+// template into a shadow table while the active index keeps serving searches, then
+// swaps it in. It does nothing if the active index already matches, unless force is
+// set. An interrupted build resumes from the markets it had already embedded.
+func (s *Syncer) BuildEmbeddingIndex(force bool) error {
+	if s.EmbeddingService == nil {
+		return fmt.Errorf("embedding service not available")
+	}
+	if !s.buildingIndex.CompareAndSwap(false, true) {
+		return fmt.Errorf("an embedding index build is already running")
+	}
+	defer s.buildingIndex.Store(false)
+
+	model, dimension := s.EmbeddingService.Model(), s.EmbeddingService.Dimension()
+
+	// 1. Check whether the active index is already current
+	active, err := vectorstore.ActiveIndex(s.DB)
+	if err != nil {
+		return err
+	}
+	if !force && vectorstore.SameModel(active, model, dimension) && active.TemplateVersion == EmbeddingTemplateVersion {
+		return nil
+	}
+
+	// 2. Start or resume the shadow index
+	index, err := vectorstore.BeginIndex(s.DB, model, dimension, EmbeddingTemplateVersion)
+	if err != nil {
+		return err
+	}
+	log.Printf("Building embedding index %d (%s, %d dims, template v%d) to replace index %d (%s, %d dims, template v%d)",
+		index.ID, model, dimension, EmbeddingTemplateVersion, active.ID, active.Model, active.Dimension, active.TemplateVersion)
+
+	// 3. Embed every active market, then catch up with markets that changed meanwhile
+	var stats EmbedStats
+	for pass := 1; pass <= maxBuildPasses; pass++ {
+		var markets []db.Market
+		if err := s.DB.Where("status = ?", "active").Find(&markets).Error; err != nil {
+			return fmt.Errorf("failed to load markets: %w", err)
+		}
+
+		stats = s.EmbedMarkets(index, markets, false)
+		log.Printf("Index %d pass %d: %d embedded, %d already current, %d failed (%.1f markets/s)",
+			index.ID, pass, stats.Embedded, stats.Skipped, stats.Failed, stats.Rate())
+		if stats.Embedded == 0 && stats.Failed == 0 {
+			break
+		}
+	}
+	if stats.Failed > 0 {
+		return fmt.Errorf("%d markets failed to embed; index %d left building and will resume on the next run", stats.Failed, index.ID)
+	}
+
+	// 4. Swap it in
+	if err := vectorstore.ActivateIndex(s.DB, index); err != nil {
+		return err
+	}
+	log.Printf("Embedding index %d is now active", index.ID)
+	return nil
+}
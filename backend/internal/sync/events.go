@@ -1,117 +1,246 @@
 package sync
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"backend/internal/db"
 	"backend/internal/kalshi"
 	kalshiTypes "backend/internal/kalshi/types"
+	"backend/internal/vectorstore"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-func (s *Syncer) SyncEvents() {
+const (
+	// FullSyncInterval is how often the whole event list is crawled; in between,
+	// only markets changed since the watermark are pulled
+	FullSyncInterval = 24 * time.Hour
+	// watermarkOverlap re-reads a little before the watermark to cover clock skew
+	watermarkOverlap = time.Minute
+	// updatedMarketsPageSize is the page size when listing changed markets
+	updatedMarketsPageSize = 1000
+)
+
+// EventSyncStats summarizes an event sync
+type EventSyncStats struct {
+	Full   bool // A full crawl ran or resumed, rather than an incremental sync
+	Events int  // Events fetched and stored
+	Closed int  // Markets found closed or settled
+	Pruned int  // Embeddings dropped for markets no longer active
+	// Stages measures each stage of a full crawl's pipeline
+	Stages []StageStats
+}
+
+// SyncEvents brings events and markets up to date. It resumes an interrupted full
+// crawl, runs a full crawl when one is due or forced, and otherwise only pulls
+// events whose markets changed since the last sync. Canceling ctx stops it after
+// the current batch; an interrupted full crawl resumes on the next call.
+func (s *Syncer) SyncEvents(ctx context.Context, forceFull bool, progress Progress) (EventSyncStats, error) {
+	progress = orNoProgress(progress)
 	if s.KClient == nil {
-		log.Println("Skipping event sync: Kalshi client not configured")
-		return
+		return EventSyncStats{}, ErrNoKalshiClient
 	}
-
-	log.Println("Starting daily event sync...")
+	if !s.syncingEvents.CompareAndSwap(false, true) {
+		return EventSyncStats{}, fmt.Errorf("event sync is already running")
+	}
+	defer s.syncingEvents.Store(false)
 
 	// 1. Get/Create Kalshi Provider
 	var provider db.Provider
 	if err := s.DB.Where("name = ?", "kalshi").FirstOrCreate(&provider, db.Provider{Name: "kalshi"}).Error; err != nil {
-		log.Printf("Failed to get/create provider: %v", err)
-		return
+		return EventSyncStats{}, fmt.Errorf("failed to get/create provider: %w", err)
 	}
 
-	// Check if we synced recently (within 24 hours)
-	if time.Since(provider.LastEventSync) < 24*time.Hour {
-		log.Printf("Skipping sync: Last sync was %v ago", time.Since(provider.LastEventSync))
-		// For development/debugging, you might want to comment this out to force sync
-		// return
-		// Keeping return for production behavior, but ensure logic allows forcing if needed.
-		// Given the user wants to test the "next part", we might want to bypass this check if we haven't synced *this run*.
-		// However, s.LastEventSync on the struct is ephemeral. provider.LastEventSync is persistent.
-		// If the user wants to run the analysis, we should let it proceed even if sync is skipped.
-	} else {
-		// Only run the heavy sync if needed
-		s.performEventSync(provider)
+	// 2. Pick full or incremental
+	var stats EventSyncStats
+	var err error
+	switch {
+	case forceFull:
+		log.Println("Starting forced full event sync...")
+		provider.EventSyncCursor = "" // Start over rather than resume
+		stats, err = s.performEventSync(ctx, provider, progress)
+	case provider.EventSyncCursor != "":
+		log.Println("Resuming interrupted full event sync...")
+		stats, err = s.performEventSync(ctx, provider, progress)
+	case time.Since(provider.LastFullEventSync) >= FullSyncInterval || provider.MarketWatermark.IsZero():
+		log.Println("Starting full event sync...")
+		stats, err = s.performEventSync(ctx, provider, progress)
+	default:
+		stats, err = s.performIncrementalSync(ctx, provider, progress)
+	}
+	if err != nil {
+		return stats, err
 	}
 
 	// Update in-memory state
 	s.LastEventSync = time.Now()
+	return stats, nil
 }
 
-func (s *Syncer) performEventSync(provider db.Provider) {
-	totalFetched := 0
-	eventCursor := ""
-	const batchSize = 100
-	const rateLimitDelay = 100 * time.Millisecond
+// performEventSync crawls every open event. The cursor is checkpointed on the
+// provider after each stored batch so an interrupted crawl resumes from there.
+func (s *Syncer) performEventSync(ctx context.Context, provider db.Provider, progress Progress) (EventSyncStats, error) {
+	stats := EventSyncStats{Full: true}
+	progress.Add("full", 1)
+	resuming := provider.EventSyncCursor != ""
+
+	// A resumed crawl keeps its original start, which marks the markets it has seen
+	start := provider.EventSyncStartedAt
+	if !resuming || start.IsZero() {
+		start = time.Now()
+		if err := s.DB.Model(&provider).Update("event_sync_started_at", start).Error; err != nil {
+			log.Printf("Failed to record event sync start: %v", err)
+		}
+	}
 
-	for {
-		time.Sleep(rateLimitDelay)
+	// 2-5. Fetch, normalize, store and embed every page
+	crawlStart := time.Now()
+	totalFetched, stages, err := s.crawlEvents(ctx, provider, progress)
+	stats.Events = totalFetched
+	stats.Stages = stages
+	for _, st := range stages {
+		log.Printf("Event sync %s", st)
+	}
+	if err != nil {
+		return stats, fmt.Errorf("event sync interrupted after %d events, it will resume from the checkpoint: %w", totalFetched, err)
+	}
+	log.Printf("Crawled %d events in %v", totalFetched, time.Since(crawlStart).Round(time.Millisecond))
 
-		// 2. Fetch from API
-		resp, err := s.fetchEventsWithRetry(batchSize, eventCursor)
-		if err != nil {
-			log.Printf("Failed to fetch events: %v", err)
-			break
-		}
-		eventCursor = resp.Cursor
-		if resp == nil || len(resp.Events) == 0 {
-			break
-		}
+	// 6. Close out markets the crawl no longer returned, then drop their embeddings
+	stats.Closed = s.reconcileMarkets(provider.ID, start)
+	progress.Add("markets_closed", stats.Closed)
+	pruned, err := s.PruneStaleEmbeddings()
+	if err != nil {
+		log.Printf("Failed to prune stale embeddings: %v", err)
+	}
+	stats.Pruned = pruned
+	progress.Add("embeddings_pruned", pruned)
+
+	// Update provider sync times. Changes made during the crawl may have been missed,
+	// so incremental syncs continue from when it started.
+	if err := s.DB.Model(&provider).Updates(map[string]interface{}{
+		"event_sync_cursor":    "",
+		"last_event_sync":      time.Now(),
+		"last_full_event_sync": start,
+		"market_watermark":     start,
+	}).Error; err != nil {
+		log.Printf("Failed to update provider last sync time: %v", err)
+	}
+
+	log.Printf("Event sync complete. Total processed: %d", totalFetched)
+	return stats, nil
+}
 
-		// 3. Process Data into Structs
-		eventsToUpsert, marketsToUpsert := s.processEventBatch(resp.Events, provider.ID)
+// performIncrementalSync pulls the events of markets created or changed since the watermark
+func (s *Syncer) performIncrementalSync(ctx context.Context, provider db.Provider, progress Progress) (EventSyncStats, error) {
+	var stats EventSyncStats
+	start := time.Now()
+	since := provider.MarketWatermark.Add(-watermarkOverlap)
 
-		// 4. DB Operations (Upsert Events & Markets)
-		// We do this in a transaction to ensure consistency
-		if len(eventsToUpsert) > 0 {
-			err := s.upsertEventsData(eventsToUpsert)
-			if err != nil {
-				log.Printf("Failed to upsert events batch: %v", err)
+	// 1. Find the events whose markets changed
+	var eventTickers []string
+	seen := make(map[string]bool)
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		resp, err := s.KClient.ListMarkets(kalshi.MarketQuery{
+			Limit:        updatedMarketsPageSize,
+			Cursor:       cursor,
+			MinUpdatedTs: since.Unix(),
+		})
+		if err != nil {
+			return stats, fmt.Errorf("failed to fetch changed markets: %w", err)
+		}
+		for _, m := range resp.Markets {
+			// min_updated_ts can't be combined with mve_filter, so drop combos here
+			if m.MveCollectionTicker != "" || seen[m.EventTicker] {
 				continue
 			}
+			seen[m.EventTicker] = true
+			eventTickers = append(eventTickers, m.EventTicker)
+		}
+		if resp.Cursor == "" || len(resp.Markets) == 0 {
+			break
 		}
+		cursor = resp.Cursor
+	}
+	log.Printf("Incremental sync: %d events changed since %s", len(eventTickers), since.Format(time.RFC3339))
 
-		if len(marketsToUpsert) > 0 {
-			err := s.upsertMarketData(marketsToUpsert)
+	// 2. Re-fetch and store those events in batches
+	const batchSize = 100
+	for from := 0; from < len(eventTickers); from += batchSize {
+		var events []kalshiTypes.SimplifiedEvent
+		for _, ticker := range eventTickers[from:min(from+batchSize, len(eventTickers))] {
+			if err := ctx.Err(); err != nil {
+				return stats, err // Keep the watermark so the next run picks these up
+			}
+			time.Sleep(50 * time.Millisecond) // Rate limit protection
+			event, err := s.KClient.GetEvent(ticker)
 			if err != nil {
-				log.Printf("Failed to upsert markets batch: %v", err)
-				continue
+				// Keep the watermark so the next run retries
+				return stats, fmt.Errorf("failed to fetch event %s: %w", ticker, err)
 			}
+			events = append(events, *event)
 		}
-
-		// 5. Update Embeddings (Outside Transaction)
-		if s.EmbeddingService != nil {
-			if len(marketsToUpsert) > 0 {
-				s.updateMarketEmbeddings(marketsToUpsert)
-			}
+		if err := s.storeEventBatch(events, provider.ID); err != nil {
+			return stats, fmt.Errorf("failed to store events batch: %w", err)
 		}
+		stats.Events += len(events)
+		progress.Add("events", len(events))
+	}
 
-		totalFetched += len(resp.Events)
-		if resp.Cursor == "" {
-			log.Println("Reached end of events list.")
-			break
+	// 3. Close out markets past their close time
+	stats.Closed = s.reconcileMarkets(provider.ID, time.Time{})
+	progress.Add("markets_closed", stats.Closed)
+	if stats.Closed > 0 {
+		pruned, err := s.PruneStaleEmbeddings()
+		if err != nil {
+			log.Printf("Failed to prune stale embeddings: %v", err)
 		}
+		stats.Pruned = pruned
+		progress.Add("embeddings_pruned", pruned)
 	}
 
-	// 6. Cleanup
-	s.pruneStaleEmbeddings()
+	// 4. Advance the watermark
+	if err := s.DB.Model(&provider).Updates(map[string]interface{}{
+		"last_event_sync":  time.Now(),
+		"market_watermark": start,
+	}).Error; err != nil {
+		log.Printf("Failed to advance market watermark: %v", err)
+	}
+	log.Printf("Incremental sync complete. %d events updated.", len(eventTickers))
+	return stats, nil
+}
 
-	// Update provider last sync time
-	now := time.Now()
-	if err := s.DB.Model(&provider).Update("last_event_sync", now).Error; err != nil {
-		log.Printf("Failed to update provider last sync time: %v", err)
+// storeEventBatch upserts a batch of events with their markets and embeds the markets
+func (s *Syncer) storeEventBatch(apiEvents []kalshiTypes.SimplifiedEvent, providerID uint) error {
+	// 3. Process Data into Structs
+	page := &eventPage{raw: apiEvents}
+	page.events, page.markets = s.processEventBatch(apiEvents, providerID)
+
+	// 4. DB Operations (Upsert Events & Markets)
+	if err := s.upsertEventPage(page); err != nil {
+		return err
 	}
 
-	log.Printf("Event sync complete. Total processed: %d", totalFetched)
+	// 5. Update Embeddings (Outside Transaction)
+	if s.EmbeddingService != nil && len(page.markets) > 0 {
+		s.updateMarketEmbeddings(page.markets)
+	}
+	return nil
+}
+
+func (s *Syncer) checkpointEventSync(provider db.Provider, cursor string) {
+	if err := s.DB.Model(&provider).Update("event_sync_cursor", cursor).Error; err != nil {
+		log.Printf("Failed to checkpoint event sync: %v", err)
+	}
 }
 
 // --- Helper Functions ---
@@ -149,7 +278,6 @@ func (s *Syncer) processEventBatch(apiEvents []kalshiTypes.SimplifiedEvent, prov
 			eventMarkets = e.Markets
 		} else {
 			// Fallback: Fetch markets individually if not nested
-			time.Sleep(50 * time.Millisecond) // Rate limit protection
 			fullEvent, err := s.KClient.GetEvent(e.EventTicker)
 			if err != nil {
 				log.Printf("Failed to fetch fallback markets for event %s: %v", e.EventTicker, err)
@@ -160,8 +288,10 @@ func (s *Syncer) processEventBatch(apiEvents []kalshiTypes.SimplifiedEvent, prov
 			}
 		}
 
-		// Calculate closest time
-		for _, m := range eventMarkets {
+		// Calculate closest time and the event's status
+		statuses := make([]string, len(eventMarkets))
+		for i, m := range eventMarkets {
+			statuses[i] = marketStatus(m.Status)
 			if m.CloseTime.After(now) {
 				if closestCloseTime.IsZero() || m.CloseTime.Before(closestCloseTime) {
 					closestCloseTime = m.CloseTime
@@ -183,6 +313,7 @@ func (s *Syncer) processEventBatch(apiEvents []kalshiTypes.SimplifiedEvent, prov
 			StrikePeriod:           e.StrikePeriod,
 			ExpirationTime:         expTime,
 			ClosestMarketCloseTime: closestCloseTime,
+			Status:                 eventStatus(statuses),
 		})
 
 		for _, m := range eventMarkets {
@@ -199,17 +330,24 @@ func (s *Syncer) processEventBatch(apiEvents []kalshiTypes.SimplifiedEvent, prov
 			}
 
 			dbMarkets = append(dbMarkets, db.Market{
-				ProviderID:     providerID,
-				ExternalID:     m.Ticker,
-				Ticker:         m.Ticker,
-				EventTicker:    e.EventTicker,
-				Title:          fullTitle,
-				Description:    m.Subtitle,
-				YesSubTitle:    m.YesSubTitle,
-				NoSubTitle:     m.NoSubTitle,
-				Status:         m.Status,
-				Category:       cat,
-				LastDataUpdate: time.Now(),
+				ProviderID:         providerID,
+				ExternalID:         m.Ticker,
+				Ticker:             m.Ticker,
+				EventTicker:        e.EventTicker,
+				Title:              fullTitle,
+				Description:        m.Subtitle,
+				YesSubTitle:        m.YesSubTitle,
+				NoSubTitle:         m.NoSubTitle,
+				Rules:              strings.TrimSpace(m.RulesPrimary + "\n" + m.RulesSecondary),
+				Status:             marketStatus(m.Status),
+				Category:           cat,
+				SeriesTicker:       e.SeriesTicker,
+				StrikeType:         m.StrikeType,
+				FloorStrike:        m.FloorStrike,
+				CapStrike:          m.CapStrike,
+				FeeWaiverExpiresAt: m.FeeWaiverExpirationTime,
+				CloseTime:          m.CloseTime,
+				LastDataUpdate:     time.Now(),
 			})
 		}
 	}
@@ -224,6 +362,7 @@ func (s *Syncer) upsertMarketData(markets []db.Market) error {
 				Columns: []clause.Column{{Name: "provider_id"}, {Name: "external_id"}},
 				DoUpdates: clause.AssignmentColumns([]string{
 					"title", "description", "yes_sub_title", "no_sub_title", "status", "category", "last_data_update", "updated_at", "event_ticker",
+					"series_ticker", "fee_waiver_expires_at", "close_time", "rules", "strike_type", "floor_strike", "cap_strike",
 				}),
 			}).Create(&markets).Error; err != nil {
 				return err
@@ -239,7 +378,7 @@ func (s *Syncer) upsertEventsData(events []db.Event) error {
 		if err := tx.Clauses(clause.OnConflict{
 			Columns: []clause.Column{{Name: "provider_id"}, {Name: "external_id"}},
 			DoUpdates: clause.AssignmentColumns([]string{
-				"title", "subtitle", "category", "expiration_time", "closest_market_close_time", "updated_at",
+				"title", "subtitle", "category", "mutually_exclusive", "expiration_time", "closest_market_close_time", "status", "updated_at",
 			}),
 		}).Create(&events).Error; err != nil {
 			return err
@@ -250,68 +389,74 @@ func (s *Syncer) upsertEventsData(events []db.Event) error {
 }
 
 func (s *Syncer) updateMarketEmbeddings(markets []db.Market) {
-	// Re-fetch the markets to ensure we have the IDs populated from the upsert
+	indexes, err := s.embeddingIndexes()
+	if err != nil {
+		log.Printf("Failed to load embedding indexes: %v", err)
+		return
+	}
+	if len(indexes) == 0 {
+		log.Println("Skipping embeddings: no index matches the embedding model (a rebuild is pending)")
+		return
+	}
+
+	// Re-fetch the active markets to ensure we have the IDs populated from the upsert
 	var freshMarkets []db.Market
 	tickers := make([]string, len(markets))
 	for i, m := range markets {
 		tickers[i] = m.Ticker
 	}
 
-	if err := s.DB.Where("ticker IN ?", tickers).Find(&freshMarkets).Error; err != nil {
+	if err := s.DB.Where("ticker IN ? AND status = ?", tickers, "active").Find(&freshMarkets).Error; err != nil {
 		log.Printf("Failed to fetch fresh markets for embeddings: %v", err)
 		return
 	}
 
-	log.Printf("Updating embeddings for %d markets...", len(freshMarkets))
-
-	for _, m := range freshMarkets {
-		if m.Status != "active" {
-			continue
-		}
-
-		embeddingText := fmt.Sprintf("%s %s %s", m.Title, m.Description, m.Category)
-		vec, err := s.EmbeddingService.Generate(embeddingText)
-		if err != nil {
-			log.Printf("Gen failed: %v", err)
-			continue
-		}
-
-		vecBytes, err := json.Marshal(vec)
-		if err != nil {
-			log.Printf("Marshal failed: %v", err)
-			continue
-		}
-		vecString := string(vecBytes)
-
-		// Delete existing
-		_ = s.DB.Exec("DELETE FROM vec_markets WHERE rowid = ?", m.ID)
-
-		query := `
-			INSERT INTO vec_markets(rowid, embedding) 
-			VALUES (?, ?)
-		`
-		if err := s.DB.Exec(query, m.ID, vecString).Error; err != nil {
-			log.Printf("Failed to save embedding for market %d: %v", m.ID, err)
-		}
+	// Keep an index being built current too, so it needs no catch-up when it is swapped in
+	for _, index := range indexes {
+		stats := s.EmbedMarkets(index, freshMarkets, false)
+		log.Printf("Embeddings (index %d): %d updated, %d unchanged, %d failed (%.1f markets/s)",
+			index.ID, stats.Embedded, stats.Skipped, stats.Failed, stats.Rate())
 	}
 }
 
-func (s *Syncer) pruneStaleEmbeddings() {
+// PruneStaleEmbeddings drops markets that are no longer active from every live
+// embedding index. Returns how many embeddings were removed.
+func (s *Syncer) PruneStaleEmbeddings() (int, error) {
 	if s.EmbeddingService == nil {
-		return
+		return 0, nil
 	}
 	log.Println("Pruning stale embeddings...")
 
-	err := s.DB.Exec(`
-		DELETE FROM vec_markets 
-		WHERE rowid IN (
-			SELECT id FROM markets WHERE status != 'active'
-		)
-	`).Error
+	var staleIDs []uint
+	if err := s.DB.Model(&db.Market{}).Where("status != ?", "active").Pluck("id", &staleIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find stale markets: %w", err)
+	}
 
+	indexes, err := vectorstore.LiveIndexes(s.DB)
 	if err != nil {
-		log.Printf("Failed to prune stale embeddings: %v", err)
-	} else {
-		log.Println("Pruning complete.")
+		return 0, fmt.Errorf("failed to load embedding indexes: %w", err)
+	}
+	pruned := 0
+	for _, index := range indexes {
+		if err := vectorstore.ForIndex(s.DB, index).Delete(staleIDs...); err != nil {
+			log.Printf("Failed to prune stale embeddings from index %d: %v", index.ID, err)
+			continue
+		}
+		// Forget the hashes so the markets are re-embedded if they ever reopen
+		result := s.DB.Where("index_id = ? AND market_id IN (?)", index.ID,
+			s.DB.Model(&db.Market{}).Select("id").Where("status != ?", "active")).
+			Delete(&db.MarketEmbedding{})
+		if result.Error != nil {
+			log.Printf("Failed to prune stale embeddings from index %d: %v", index.ID, result.Error)
+			continue
+		}
+		pruned += int(result.RowsAffected)
+	}
+
+	remaining, err := s.Vectors.Count()
+	if err != nil {
+		return pruned, fmt.Errorf("failed to count embeddings: %w", err)
 	}
+	log.Printf("Pruning complete. %d removed, %d embeddings indexed.", pruned, remaining)
+	return pruned, nil
 }
@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/kalshi"
+	kalshiTypes "backend/internal/kalshi/types"
+
+	"gorm.io/gorm"
+)
+
+// marketRefreshPageSize is the largest page Kalshi returns when listing markets
+const marketRefreshPageSize = 1000
+
+// RefreshMarkets updates the status, quotes and activity of every open market we
+// already track. It is much cheaper than an event sync and runs far more often;
+// new markets and text changes are left to SyncEvents. Returns how many markets
+// were updated.
+func (s *Syncer) RefreshMarkets(ctx context.Context, progress Progress) (int, error) {
+	progress = orNoProgress(progress)
+	if s.KClient == nil {
+		return 0, ErrNoKalshiClient
+	}
+	if !s.refreshingMarkets.CompareAndSwap(false, true) {
+		return 0, fmt.Errorf("market refresh is already running")
+	}
+	defer s.refreshingMarkets.Store(false)
+
+	var provider db.Provider
+	if err := s.DB.Where("name = ?", "kalshi").Limit(1).Find(&provider).Error; err != nil {
+		return 0, fmt.Errorf("failed to get provider: %w", err)
+	}
+	if provider.ID == 0 {
+		return 0, nil // Nothing synced yet
+	}
+
+	start := time.Now()
+	updated, pages := 0, 0
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return updated, err
+		}
+		resp, err := s.KClient.ListMarkets(kalshi.MarketQuery{
+			Limit:     marketRefreshPageSize,
+			Cursor:    cursor,
+			Status:    "open",
+			MveFilter: "exclude",
+		})
+		if err != nil {
+			return updated, fmt.Errorf("market refresh failed after %d pages: %w", pages, err)
+		}
+		pages++
+
+		n, err := s.applyMarketQuotes(provider.ID, resp.Markets)
+		if err != nil {
+			log.Printf("Failed to store market quotes: %v", err)
+		}
+		updated += n
+		progress.Add("pages", 1)
+		progress.Add("markets", n)
+
+		if resp.Cursor == "" || len(resp.Markets) == 0 {
+			break
+		}
+		cursor = resp.Cursor
+	}
+
+	log.Printf("Market refresh: %d markets updated from %d pages in %v", updated, pages, time.Since(start).Round(time.Millisecond))
+	return updated, nil
+}
+
+// applyMarketQuotes writes the latest status and quotes onto the markets we know of.
+// Returns how many were updated.
+func (s *Syncer) applyMarketQuotes(providerID uint, markets []kalshiTypes.MarketData) (int, error) {
+	updated := 0
+	now := time.Now()
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, m := range markets {
+			result := tx.Model(&db.Market{}).
+				Where("provider_id = ? AND external_id = ?", providerID, m.Ticker).
+				Updates(map[string]interface{}{
+					"status":           marketStatus(m.Status),
+					"close_time":       m.CloseTime,
+					"yes_bid":          m.YesBid,
+					"yes_ask":          m.YesAsk,
+					"no_bid":           m.NoBid,
+					"no_ask":           m.NoAsk,
+					"last_price":       m.LastPrice,
+					"volume24h":        m.Volume24h,
+					"open_interest":    m.OpenInterest,
+					"liquidity":        m.Liquidity,
+					"last_data_update": now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			updated += int(result.RowsAffected)
+		}
+		return nil
+	})
+	return updated, err
+}
@@ -0,0 +1,159 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/fees"
+	"backend/internal/slm"
+)
+
+const (
+	// opportunityContracts is the number of contracts sized per detected opportunity
+	opportunityContracts = 10
+	// opportunityTTL bounds how long a detection stays tradeable on the quotes it was priced from
+	opportunityTTL = 5 * time.Minute
+)
+
+// detectImplicationOpportunities prices the hedges implied by an SLM comparison.
+// If Source=X necessarily implies Target=Y, then holding Source(not X) and Target(Y)
+// pays out at least $1 in every outcome, so buying both for under $1 locks in a profit.
+func (s *Syncer) detectImplicationOpportunities(source, target db.Market, result *slm.ComparisonResult) {
+	if s.KClient == nil || result == nil {
+		return
+	}
+
+	implications := []struct {
+		sourceOutcome string
+		targetOutcome *string
+	}{
+		{"yes", result.SourceYes},
+		{"no", result.SourceNo},
+	}
+
+	for _, imp := range implications {
+		if imp.targetOutcome == nil {
+			continue
+		}
+		sourceSide := oppositeSide(imp.sourceOutcome)
+		targetSide := outcomeSide(*imp.targetOutcome)
+
+		if err := s.recordHedgeOpportunity(source.ExternalID, sourceSide, target.ExternalID, targetSide); err != nil {
+			log.Printf("Failed to price hedge %s %s / %s %s: %v", sourceSide, source.ExternalID, targetSide, target.ExternalID, err)
+		}
+	}
+}
+
+// recordHedgeOpportunity stores an opportunity to buy both legs if their combined cost, fees included, is under $1
+func (s *Syncer) recordHedgeOpportunity(sourceTicker, sourceSide, targetTicker, targetSide string) error {
+	// 1. Skip if we already have a live detection for this hedge
+	var existing int64
+	err := s.DB.Model(&db.ArbitrageOpportunity{}).
+		Where("status IN ?", []string{"detected", "pending"}).
+		Where("id IN (?)", s.DB.Model(&db.OpportunityLeg{}).Select("opportunity_id").Where("ticker = ? AND side = ?", sourceTicker, sourceSide)).
+		Where("id IN (?)", s.DB.Model(&db.OpportunityLeg{}).Select("opportunity_id").Where("ticker = ? AND side = ?", targetTicker, targetSide)).
+		Count(&existing).Error
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	// 2. Fetch live quotes for both legs
+	sourceQuote, err := s.fetchQuote(sourceTicker, sourceSide)
+	if err != nil {
+		return err
+	}
+	targetQuote, err := s.fetchQuote(targetTicker, targetSide)
+	if err != nil {
+		return err
+	}
+	if sourceQuote.Ask <= 0 || targetQuote.Ask <= 0 {
+		return nil // No liquidity on one side
+	}
+
+	// 3. Price the hedge. Payout is guaranteed to be at least 100¢ per pair,
+	// and both legs cross the spread so they pay taker fees.
+	price := sourceQuote.Ask + targetQuote.Ask
+	sourceFee := s.Fees.Fee(sourceQuote.FeeMarket, opportunityContracts, sourceQuote.Ask, fees.Taker)
+	targetFee := s.Fees.Fee(targetQuote.FeeMarket, opportunityContracts, targetQuote.Ask, fees.Taker)
+
+	capital := price*opportunityContracts + sourceFee + targetFee
+	profit := 100*opportunityContracts - capital
+	if profit <= 0 {
+		return nil
+	}
+
+	var sourceMarket, targetMarket db.Market
+	s.DB.Where("external_id = ?", sourceTicker).First(&sourceMarket)
+	s.DB.Where("external_id = ?", targetTicker).First(&targetMarket)
+
+	opp := db.ArbitrageOpportunity{
+		MarketID:        sourceMarket.ID,
+		StrategyType:    "implication",
+		BuyPrice:        float64(price) / 100,
+		SellPrice:       1.0,
+		ExpectedYield:   float64(profit) / float64(capital),
+		PotentialProfit: float64(profit) / 100,
+		RequiredCapital: float64(capital) / 100,
+		ExpiresAt:       time.Now().Add(opportunityTTL),
+		Legs: []db.OpportunityLeg{
+			{MarketID: sourceMarket.ID, Ticker: sourceTicker, Side: sourceSide, Action: "buy", Price: sourceQuote.Ask, Count: opportunityContracts},
+			{MarketID: targetMarket.ID, Ticker: targetTicker, Side: targetSide, Action: "buy", Price: targetQuote.Ask, Count: opportunityContracts},
+		},
+	}
+	if err := s.DB.Create(&opp).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Detected implication opportunity %d: buy %s %s @ %d¢ + %s %s @ %d¢, fees %d¢ (yield %.2f%%)",
+		opp.ID, sourceSide, sourceTicker, sourceQuote.Ask, targetSide, targetTicker, targetQuote.Ask, sourceFee+targetFee, opp.ExpectedYield*100)
+	return nil
+}
+
+// quote is the current ask for one side of a market along with its fee attributes
+type quote struct {
+	Ask       int // Cents, 0 if no liquidity
+	FeeMarket fees.Market
+}
+
+// fetchQuote returns the current ask for one side of a market
+func (s *Syncer) fetchQuote(ticker, side string) (*quote, error) {
+	m, err := s.KClient.GetMarket(ticker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quote for %s: %w", ticker, err)
+	}
+
+	q := &quote{
+		FeeMarket: fees.Market{
+			Ticker:             m.Ticker,
+			SeriesTicker:       fees.SeriesFromTicker(m.EventTicker),
+			FeeWaiverExpiresAt: m.FeeWaiverExpirationTime,
+		},
+	}
+	if m.Status != "active" {
+		return q, nil
+	}
+
+	q.Ask = m.NoAsk
+	if side == "yes" {
+		q.Ask = m.YesAsk
+	}
+	return q, nil
+}
+
+func oppositeSide(side string) string {
+	if side == "yes" {
+		return "no"
+	}
+	return "yes"
+}
+
+// outcomeSide converts an SLM outcome such as "target_yes" to a contract side
+func outcomeSide(outcome string) string {
+	return strings.TrimPrefix(outcome, "target_")
+}
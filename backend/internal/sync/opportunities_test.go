@@ -0,0 +1,141 @@
+package sync
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/fees"
+	"backend/internal/kalshi"
+	"backend/internal/kalshi/types"
+	"backend/internal/slm"
+
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "sync.db"))
+	database, err := db.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.MigrateUp(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// newKalshiStub returns a client whose requests are answered by handler
+func newKalshiStub(t *testing.T, handler http.HandlerFunc) *kalshi.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kalshi.Client{
+		BaseURL:     srv.URL,
+		HTTPClient:  srv.Client(),
+		Credentials: kalshi.AuthCredentials{AccessKey: "test", PrivateKey: key},
+	}
+}
+
+// serveMarkets answers GET /markets/{ticker} from a fixed set of quotes
+func serveMarkets(markets map[string]types.MarketData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticker := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		m, ok := markets[ticker]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"market": m})
+	}
+}
+
+func TestDetectImplicationOpportunitiesNetOfFees(t *testing.T) {
+	waived := time.Now().Add(time.Hour)
+	tests := []struct {
+		name        string
+		sourceNoAsk int
+		targetAsk   int
+		waiver      time.Time
+		wantCapital float64 // Dollars, 0 if no opportunity should be stored
+		wantProfit  float64
+	}{
+		// 10 × (45 + 50) = 950¢, plus ceil(0.07 × 10 × .45 × .55) = 18¢ and ceil(0.07 × 10 × .5 × .5) = 18¢
+		{"profitable after fees", 45, 50, time.Time{}, 9.86, 0.14},
+		// 980¢ leaves 20¢ before fees but 36¢ of fees turns it into a loss
+		{"fees eat the spread", 48, 50, time.Time{}, 0, 0},
+		{"fees waived", 48, 50, waived, 9.80, 0.20},
+		{"costs a dollar before fees", 50, 50, time.Time{}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newTestDB(t)
+			database.Create(&db.Market{ExternalID: "KXA-1", Ticker: "KXA-1", Status: "active"})
+			database.Create(&db.Market{ExternalID: "KXB-1", Ticker: "KXB-1", Status: "active"})
+
+			kClient := newKalshiStub(t, serveMarkets(map[string]types.MarketData{
+				"KXA-1": {Ticker: "KXA-1", EventTicker: "KXA", Status: "active", NoAsk: tt.sourceNoAsk, FeeWaiverExpirationTime: tt.waiver},
+				"KXB-1": {Ticker: "KXB-1", EventTicker: "KXB", Status: "active", YesAsk: tt.targetAsk, FeeWaiverExpirationTime: tt.waiver},
+			}))
+			syncer := &Syncer{
+				DB:      database,
+				KClient: kClient,
+				Fees:    &fees.Model{Default: fees.DefaultSchedule, Now: time.Now},
+			}
+
+			// Source=YES implies Target=YES, so NO on the source and YES on the target always pays $1
+			targetYes := "target_yes"
+			source, target := db.Market{ExternalID: "KXA-1"}, db.Market{ExternalID: "KXB-1"}
+			syncer.detectImplicationOpportunities(source, target, &slm.ComparisonResult{SourceYes: &targetYes})
+
+			var opps []db.ArbitrageOpportunity
+			database.Preload("Legs").Find(&opps)
+			if tt.wantCapital == 0 {
+				if len(opps) != 0 {
+					t.Fatalf("stored %d opportunities, want none", len(opps))
+				}
+				return
+			}
+			if len(opps) != 1 {
+				t.Fatalf("stored %d opportunities, want 1", len(opps))
+			}
+
+			opp := opps[0]
+			if !near(opp.RequiredCapital, tt.wantCapital) || !near(opp.PotentialProfit, tt.wantProfit) {
+				t.Errorf("capital %.2f, profit %.2f, want %.2f, %.2f", opp.RequiredCapital, opp.PotentialProfit, tt.wantCapital, tt.wantProfit)
+			}
+			if want := tt.wantProfit / tt.wantCapital; !near(opp.ExpectedYield, want) {
+				t.Errorf("yield %.4f, want %.4f", opp.ExpectedYield, want)
+			}
+			if len(opp.Legs) != 2 || opp.Legs[0].Side != "no" || opp.Legs[1].Side != "yes" {
+				t.Errorf("legs %+v, want NO on the source and YES on the target", opp.Legs)
+			}
+
+			// A second pass on the same quotes doesn't stack another detection
+			syncer.detectImplicationOpportunities(source, target, &slm.ComparisonResult{SourceYes: &targetYes})
+			var count int64
+			database.Model(&db.ArbitrageOpportunity{}).Count(&count)
+			if count != 1 {
+				t.Errorf("%d opportunities after re-pricing, want 1", count)
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -0,0 +1,228 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/db"
+	kalshiTypes "backend/internal/kalshi/types"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// eventPageSize is how many events the crawl requests per page
+	eventPageSize = 100
+	// normalizeWorkers is how many pages are normalized at once; pages whose events
+	// lack nested markets need a request per event, so this stage is the slowest
+	normalizeWorkers = 4
+	// maxPagesInFlight bounds how far fetching may run ahead of the checkpoint
+	maxPagesInFlight = 8
+)
+
+// StageStats measures one stage of the event sync pipeline
+type StageStats struct {
+	Stage   string
+	Batches int
+	Items   int
+	Busy    time.Duration // Time spent working, summed over the stage's workers
+}
+
+// Rate is the stage's throughput in items per busy second
+func (st StageStats) Rate() float64 {
+	if st.Busy <= 0 {
+		return 0
+	}
+	return float64(st.Items) / st.Busy.Seconds()
+}
+
+func (st StageStats) String() string {
+	return fmt.Sprintf("%s: %d batches, %d items, %v busy (%.1f/s)",
+		st.Stage, st.Batches, st.Items, st.Busy.Round(time.Millisecond), st.Rate())
+}
+
+// stageMeter accumulates a stage's StageStats; safe for concurrent workers
+type stageMeter struct {
+	stage   string
+	batches atomic.Int64
+	items   atomic.Int64
+	busy    atomic.Int64 // Nanoseconds
+}
+
+func (m *stageMeter) record(items int, started time.Time) {
+	m.batches.Add(1)
+	m.items.Add(int64(items))
+	m.busy.Add(int64(time.Since(started)))
+}
+
+func (m *stageMeter) stats() StageStats {
+	return StageStats{
+		Stage:   m.stage,
+		Batches: int(m.batches.Load()),
+		Items:   int(m.items.Load()),
+		Busy:    time.Duration(m.busy.Load()),
+	}
+}
+
+// eventPage is one page of the crawl as it moves through the pipeline
+type eventPage struct {
+	seq     int
+	cursor  string // Cursor of the page after this one
+	raw     []kalshiTypes.SimplifiedEvent
+	events  []db.Event
+	markets []db.Market
+}
+
+// crawlEvents runs the full crawl as a pipeline: pages are fetched in order,
+// normalized concurrently, then upserted, embedded and checkpointed in order, so
+// the checkpoint never skips a page that wasn't stored. Bounded channels and a cap
+// on pages in flight give backpressure; request pacing is left to the Kalshi
+// client's rate limiter, which all stages share. Returns how many events were
+// stored and each stage's throughput.
+func (s *Syncer) crawlEvents(ctx context.Context, provider db.Provider, progress Progress) (int, []StageStats, error) {
+	fetch := &stageMeter{stage: "fetch"}
+	normalize := &stageMeter{stage: "normalize"}
+	upsert := &stageMeter{stage: "upsert"}
+	embed := &stageMeter{stage: "embed"}
+	stages := func() []StageStats {
+		return []StageStats{fetch.stats(), normalize.stats(), upsert.stats(), embed.stats()}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	inFlight := make(chan struct{}, maxPagesInFlight)
+	fetched := make(chan *eventPage, 2)
+	normalized := make(chan *eventPage, 2)
+	upserted := make(chan *eventPage, 2)
+
+	// 1. Fetch pages in cursor order
+	resuming := provider.EventSyncCursor != ""
+	g.Go(func() error {
+		defer close(fetched)
+		cursor := provider.EventSyncCursor
+		for seq := 0; ; seq++ {
+			select {
+			case inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			started := time.Now()
+			resp, err := s.fetchEventsWithRetry(eventPageSize, cursor)
+			if err != nil {
+				if resuming && seq == 0 {
+					// The checkpointed cursor may have expired; start over next time
+					s.checkpointEventSync(provider, "")
+				}
+				return fmt.Errorf("failed to fetch events: %w", err)
+			}
+			fetch.record(len(resp.Events), started)
+			if len(resp.Events) == 0 {
+				return nil
+			}
+
+			select {
+			case fetched <- &eventPage{seq: seq, cursor: resp.Cursor, raw: resp.Events}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if resp.Cursor == "" {
+				log.Println("Reached end of events list.")
+				return nil
+			}
+			cursor = resp.Cursor
+		}
+	})
+
+	// 2. Normalize pages into events and markets, several at a time
+	g.Go(func() error {
+		defer close(normalized)
+		var workers errgroup.Group
+		for range normalizeWorkers {
+			workers.Go(func() error {
+				for page := range fetched {
+					started := time.Now()
+					page.events, page.markets = s.processEventBatch(page.raw, provider.ID)
+					normalize.record(len(page.raw), started)
+
+					select {
+					case normalized <- page:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				return nil
+			})
+		}
+		return workers.Wait()
+	})
+
+	// 3. Upsert pages, restoring their fetch order
+	g.Go(func() error {
+		defer close(upserted)
+		pending := make(map[int]*eventPage)
+		next := 0
+		for page := range normalized {
+			pending[page.seq] = page
+			for {
+				page, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				started := time.Now()
+				if err := s.upsertEventPage(page); err != nil {
+					return err
+				}
+				upsert.record(len(page.raw), started)
+
+				select {
+				case upserted <- page:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		return nil
+	})
+
+	// 4. Embed, then checkpoint past the page
+	stored := 0
+	g.Go(func() error {
+		for page := range upserted {
+			if s.EmbeddingService != nil && len(page.markets) > 0 {
+				started := time.Now()
+				s.updateMarketEmbeddings(page.markets)
+				embed.record(len(page.markets), started)
+			}
+
+			s.checkpointEventSync(provider, page.cursor)
+			stored += len(page.raw)
+			progress.Add("events", len(page.raw))
+			<-inFlight
+		}
+		return nil
+	})
+
+	err := g.Wait()
+	return stored, stages(), err
+}
+
+// upsertEventPage stores a normalized page's events and markets
+func (s *Syncer) upsertEventPage(page *eventPage) error {
+	if len(page.events) > 0 {
+		if err := s.upsertEventsData(page.events); err != nil {
+			return fmt.Errorf("failed to upsert events: %w", err)
+		}
+	}
+	if len(page.markets) > 0 {
+		if err := s.upsertMarketData(page.markets); err != nil {
+			return fmt.Errorf("failed to upsert markets: %w", err)
+		}
+	}
+	return nil
+}
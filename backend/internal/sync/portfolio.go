@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/trader"
+)
+
+// SnapshotBalances records the current equity of the live and paper accounts
+func (s *Syncer) SnapshotBalances() {
+	now := time.Now()
+
+	// 1. Live account as reported by Kalshi
+	if s.KClient != nil {
+		bal, err := s.KClient.GetPortfolioBalance()
+		if err != nil {
+			log.Printf("Failed to fetch balance for snapshot: %v", err)
+		} else {
+			s.saveBalanceSnapshot(db.BalanceSnapshot{
+				Provider:       "kalshi",
+				Ledger:         "live",
+				Cash:           bal.Balance,
+				PositionsValue: bal.PortfolioValue,
+				Equity:         bal.Balance + bal.PortfolioValue,
+				CapturedAt:     now,
+			})
+		}
+	}
+
+	// 2. Paper account rebuilt from the simulated ledger. Cash is the starting balance
+	// plus everything realized, less what is still tied up in open positions.
+	positions, err := trader.PaperLedger.MarkPositions(s.DB)
+	if err != nil {
+		log.Printf("Failed to mark paper positions for snapshot: %v", err)
+		return
+	}
+
+	var realized float64
+	if err := s.DB.Table(trader.PaperLedger.Positions).Select("COALESCE(SUM(realized_pnl), 0)").Scan(&realized).Error; err != nil {
+		log.Printf("Failed to sum paper realized P&L for snapshot: %v", err)
+		return
+	}
+
+	var costBasis, value float64
+	for _, p := range positions {
+		costBasis += p.CostBasis()
+		value += p.Value()
+	}
+
+	cash := int64(math.Round(float64(s.PaperStartingBalance) + realized - costBasis))
+	positionsValue := int64(math.Round(value))
+	s.saveBalanceSnapshot(db.BalanceSnapshot{
+		Provider:       "kalshi",
+		Ledger:         "paper",
+		Cash:           cash,
+		PositionsValue: positionsValue,
+		Equity:         cash + positionsValue,
+		CapturedAt:     now,
+	})
+}
+
+func (s *Syncer) saveBalanceSnapshot(snapshot db.BalanceSnapshot) {
+	if err := s.DB.Create(&snapshot).Error; err != nil {
+		log.Printf("Failed to save %s balance snapshot: %v", snapshot.Ledger, err)
+	}
+}
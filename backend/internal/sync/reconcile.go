@@ -0,0 +1,156 @@
+package sync
+
+import (
+	"log"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/kalshi"
+	kalshiTypes "backend/internal/kalshi/types"
+)
+
+// reconcileBatchSize is how many tickers are looked up per market listing request
+const reconcileBatchSize = 100
+
+// marketStatus folds Kalshi's market lifecycle into active, closed and settled
+func marketStatus(kalshiStatus string) string {
+	switch kalshiStatus {
+	case "active", "open":
+		return "active"
+	case "finalized", "settled":
+		return "settled"
+	default:
+		// initialized, inactive, closed, determined, disputed, amended
+		return "closed"
+	}
+}
+
+// eventStatus derives an event's status from its markets' statuses
+func eventStatus(marketStatuses []string) string {
+	if len(marketStatuses) == 0 {
+		return "active" // Markets not fetched yet
+	}
+	allSettled := true
+	for _, status := range marketStatuses {
+		if status == "active" {
+			return "active"
+		}
+		if status != "settled" {
+			allSettled = false
+		}
+	}
+	if allSettled {
+		return "settled"
+	}
+	return "closed"
+}
+
+// reconcileMarkets finds markets we still think are active but that have closed or
+// dropped out of the feed, asks Kalshi for their final state and updates them and
+// their events. Markets past their close time are always checked; with a non-zero
+// seenSince, so are markets the crawl that started then didn't return.
+// Returns how many markets changed status.
+func (s *Syncer) reconcileMarkets(providerID uint, seenSince time.Time) int {
+	// 1. Find candidates
+	q := s.DB.Model(&db.Market{}).Where("provider_id = ? AND status = ?", providerID, "active")
+	if seenSince.IsZero() {
+		q = q.Where("close_time <= ?", time.Now())
+	} else {
+		q = q.Where("close_time <= ? OR last_data_update < ?", time.Now(), seenSince)
+	}
+	var tickers []string
+	if err := q.Pluck("ticker", &tickers).Error; err != nil {
+		log.Printf("Failed to find markets to reconcile: %v", err)
+		return 0
+	}
+	if len(tickers) == 0 {
+		return 0
+	}
+	log.Printf("Reconciling %d markets missing from the feed or past close...", len(tickers))
+
+	// 2. Look up their current state in batches
+	changed := 0
+	events := make(map[string]bool)
+	for from := 0; from < len(tickers); from += reconcileBatchSize {
+		batch := tickers[from:min(from+reconcileBatchSize, len(tickers))]
+
+		time.Sleep(50 * time.Millisecond) // Rate limit protection
+		resp, err := s.KClient.ListMarkets(kalshi.MarketQuery{Limit: len(batch), Tickers: batch})
+		if err != nil {
+			log.Printf("Failed to fetch markets to reconcile: %v", err)
+			continue
+		}
+
+		found := make(map[string]kalshiTypes.MarketData, len(resp.Markets))
+		for _, m := range resp.Markets {
+			found[m.Ticker] = m
+		}
+
+		for _, ticker := range batch {
+			updates := map[string]interface{}{"last_data_update": time.Now()}
+			data, ok := found[ticker]
+			if !ok {
+				// Delisted markets can't trade any more; settlement is picked up separately
+				updates["status"] = "closed"
+			} else {
+				updates["status"] = marketStatus(data.Status)
+				updates["close_time"] = data.CloseTime
+				if data.Result == "yes" || data.Result == "no" {
+					updates["result"] = data.Result
+				}
+			}
+			if updates["status"] == "active" {
+				// Still trading, e.g. its close time was extended
+				s.DB.Model(&db.Market{}).Where("provider_id = ? AND ticker = ?", providerID, ticker).Updates(updates)
+				continue
+			}
+
+			var market db.Market
+			s.DB.Where("provider_id = ? AND ticker = ?", providerID, ticker).Limit(1).Find(&market)
+			if err := s.DB.Model(&market).Updates(updates).Error; err != nil {
+				log.Printf("Failed to reconcile market %s: %v", ticker, err)
+				continue
+			}
+			events[market.EventTicker] = true
+			changed++
+		}
+	}
+
+	// 3. Bring their events in line
+	s.updateEventStatuses(providerID, events)
+
+	log.Printf("Reconciliation complete: %d markets closed or settled", changed)
+	return changed
+}
+
+// updateEventStatuses recomputes the status and nearest close time of the given events
+func (s *Syncer) updateEventStatuses(providerID uint, eventTickers map[string]bool) {
+	now := time.Now()
+	for ticker := range eventTickers {
+		var markets []db.Market
+		if err := s.DB.Select("status", "close_time").
+			Where("provider_id = ? AND event_ticker = ?", providerID, ticker).
+			Find(&markets).Error; err != nil {
+			log.Printf("Failed to load markets for event %s: %v", ticker, err)
+			continue
+		}
+
+		statuses := make([]string, len(markets))
+		closest := time.Time{}
+		for i, m := range markets {
+			statuses[i] = m.Status
+			if m.Status == "active" && m.CloseTime.After(now) && (closest.IsZero() || m.CloseTime.Before(closest)) {
+				closest = m.CloseTime
+			}
+		}
+
+		updates := map[string]interface{}{"status": eventStatus(statuses)}
+		if !closest.IsZero() {
+			updates["closest_market_close_time"] = closest
+		}
+		if err := s.DB.Model(&db.Event{}).Where("provider_id = ? AND external_id = ?", providerID, ticker).
+			Updates(updates).Error; err != nil {
+			log.Printf("Failed to update event %s: %v", ticker, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/kalshi/types"
+)
+
+func TestEventStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{nil, "active"},
+		{[]string{"active", "closed", "settled"}, "active"},
+		{[]string{"closed", "settled"}, "closed"},
+		{[]string{"settled", "settled"}, "settled"},
+		{[]string{"closed"}, "closed"},
+	}
+	for _, tt := range tests {
+		if got := eventStatus(tt.statuses); got != tt.want {
+			t.Errorf("eventStatus(%v) = %s, want %s", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestReconcileMarkets(t *testing.T) {
+	now := time.Now()
+	past, soon, later := now.Add(-time.Hour), now.Add(24*time.Hour), now.Add(48*time.Hour)
+	crawlStart := now.Add(-10 * time.Minute)
+	seen, missed := now, now.Add(-24*time.Hour) // last_data_update for markets the crawl did and didn't return
+
+	markets := []db.Market{
+		// Still listed and trading, and the rest of its event has ended
+		{Ticker: "EV1-A", EventTicker: "EV1", CloseTime: soon, LastDataUpdate: seen},
+		{Ticker: "EV1-B", EventTicker: "EV1", CloseTime: past, LastDataUpdate: seen},
+		{Ticker: "EV1-C", EventTicker: "EV1", CloseTime: later, LastDataUpdate: missed},
+		// Every market closed: one past close, one dropped from the feed and delisted
+		{Ticker: "EV2-A", EventTicker: "EV2", CloseTime: past, LastDataUpdate: seen},
+		{Ticker: "EV2-B", EventTicker: "EV2", CloseTime: later, LastDataUpdate: missed},
+		// Every market settled
+		{Ticker: "EV3-A", EventTicker: "EV3", CloseTime: past, LastDataUpdate: seen},
+		// Past its original close, but Kalshi extended it
+		{Ticker: "EV4-A", EventTicker: "EV4", CloseTime: past, LastDataUpdate: seen},
+	}
+	kalshiMarkets := map[string]types.MarketData{
+		"EV1-A": {Ticker: "EV1-A", Status: "active", CloseTime: soon},
+		"EV1-B": {Ticker: "EV1-B", Status: "finalized", Result: "yes", CloseTime: past},
+		"EV1-C": {Ticker: "EV1-C", Status: "closed", CloseTime: past},
+		"EV2-A": {Ticker: "EV2-A", Status: "determined", Result: "no", CloseTime: past},
+		"EV3-A": {Ticker: "EV3-A", Status: "settled", Result: "no", CloseTime: past},
+		"EV4-A": {Ticker: "EV4-A", Status: "active", CloseTime: later},
+	}
+
+	tests := []struct {
+		name          string
+		seenSince     time.Time
+		wantRequested []string
+		wantChanged   int
+		wantMarkets   map[string]string // Ticker to status
+		wantEvents    map[string]string
+	}{
+		{
+			name:          "markets past close and missing from the crawl",
+			seenSince:     crawlStart,
+			wantRequested: []string{"EV1-B", "EV1-C", "EV2-A", "EV2-B", "EV3-A", "EV4-A"},
+			wantChanged:   5,
+			wantMarkets: map[string]string{"EV1-A": "active", "EV1-B": "settled", "EV1-C": "closed",
+				"EV2-A": "closed", "EV2-B": "closed", "EV3-A": "settled", "EV4-A": "active"},
+			wantEvents: map[string]string{"EV1": "active", "EV2": "closed", "EV3": "settled", "EV4": "active"},
+		},
+		{
+			// A refresh that isn't a full crawl can't tell which markets went missing
+			name:          "only markets past close without a crawl",
+			wantRequested: []string{"EV1-B", "EV2-A", "EV3-A", "EV4-A"},
+			wantChanged:   3,
+			wantMarkets: map[string]string{"EV1-A": "active", "EV1-B": "settled", "EV1-C": "active",
+				"EV2-A": "closed", "EV2-B": "active", "EV3-A": "settled", "EV4-A": "active"},
+			wantEvents: map[string]string{"EV1": "active", "EV2": "active", "EV3": "settled", "EV4": "active"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newTestDB(t)
+			provider := db.Provider{Name: "kalshi"}
+			database.Create(&provider)
+			for _, m := range markets {
+				m.ProviderID, m.ExternalID, m.Status = provider.ID, m.Ticker, "active"
+				database.Create(&m)
+			}
+			for _, ev := range []string{"EV1", "EV2", "EV3", "EV4"} {
+				database.Create(&db.Event{ProviderID: provider.ID, ExternalID: ev, Status: "active"})
+			}
+
+			var requested []string
+			syncer := &Syncer{DB: database, KClient: newKalshiStub(t, func(w http.ResponseWriter, r *http.Request) {
+				var found []types.MarketData
+				for _, ticker := range strings.Split(r.URL.Query().Get("tickers"), ",") {
+					requested = append(requested, ticker)
+					if m, ok := kalshiMarkets[ticker]; ok {
+						found = append(found, m)
+					}
+				}
+				json.NewEncoder(w).Encode(map[string]any{"markets": found})
+			})}
+
+			if changed := syncer.reconcileMarkets(provider.ID, tt.seenSince); changed != tt.wantChanged {
+				t.Errorf("%d markets changed, want %d", changed, tt.wantChanged)
+			}
+
+			sort.Strings(requested)
+			if strings.Join(requested, ",") != strings.Join(tt.wantRequested, ",") {
+				t.Errorf("looked up %v, want %v", requested, tt.wantRequested)
+			}
+
+			var stored []db.Market
+			database.Find(&stored)
+			for _, m := range stored {
+				if want := tt.wantMarkets[m.Ticker]; m.Status != want {
+					t.Errorf("market %s is %s, want %s", m.Ticker, m.Status, want)
+				}
+			}
+			var events []db.Event
+			database.Find(&events)
+			for _, e := range events {
+				if want := tt.wantEvents[e.ExternalID]; e.Status != want {
+					t.Errorf("event %s is %s, want %s", e.ExternalID, e.Status, want)
+				}
+			}
+
+			// Results and extended close times come back with the final state
+			var settled, extended db.Market
+			database.Where("ticker = ?", "EV1-B").First(&settled)
+			database.Where("ticker = ?", "EV4-A").First(&extended)
+			if settled.Result != "yes" {
+				t.Errorf("EV1-B result %q, want yes", settled.Result)
+			}
+			if !extended.CloseTime.Equal(later) {
+				t.Errorf("EV4-A closes %v, want the extended %v", extended.CloseTime, later)
+			}
+
+			// The open event's next close is its soonest market still trading
+			var ev1 db.Event
+			database.Where("external_id = ?", "EV1").First(&ev1)
+			if !ev1.ClosestMarketCloseTime.Equal(soon) {
+				t.Errorf("EV1 next close %v, want %v", ev1.ClosestMarketCloseTime, soon)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package sync
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+)
+
+// Implication engines, recorded with each implication and compared pair
+const (
+	EngineRules = "rules"
+	EngineSLM   = "slm"
+	engineCache = "cache" // A cached SLM result was reused
+)
+
+// ruleImplication resolves pairs whose relationship is mechanical from structured
+// market fields, so only ambiguous pairs reach the SLM. Returns nil when no rule
+// applies. A result with no implications means a rule applied and found none.
+func (s *Syncer) ruleImplication(source, target db.Market) *slm.ComparisonResult {
+	var event *db.Event
+	if source.EventTicker != "" && source.EventTicker == target.EventTicker {
+		var e db.Event
+		if err := s.DB.Where("external_id = ?", source.EventTicker).Limit(1).Find(&e).Error; err == nil && e.ExternalID != "" {
+			event = &e
+		}
+	}
+
+	result := resolveByRules(source, target, event)
+	if result != nil {
+		result.MarketID = target.ExternalID
+		result.EventID = target.EventTicker
+		result.ComparedMarketID = source.ExternalID
+		result.ComparedEventID = source.EventTicker
+		result.Engine = EngineRules
+		// Rules only apply where the implication is certain
+		if result.SourceYes != nil {
+			result.SourceYesConfidence = 1
+		}
+		if result.SourceNo != nil {
+			result.SourceNoConfidence = 1
+		}
+	}
+	return result
+}
+
+// resolveByRules applies the rules in order of confidence. event is the event
+// both markets belong to, or nil if they belong to different ones.
+func resolveByRules(source, target db.Market, event *db.Event) *slm.ComparisonResult {
+	// 1. Numeric ladders: markets of one event on the same underlying value
+	if event != nil {
+		if result := strikeImplication(source, target); result != nil {
+			return result
+		}
+	}
+
+	// 2. At most one market of a mutually exclusive event resolves YES
+	if event != nil && event.MutuallyExclusive {
+		return &slm.ComparisonResult{
+			Reason:    fmt.Sprintf("Both markets belong to mutually exclusive event %s, so at most one resolves YES", event.ExternalID),
+			SourceYes: outcomeRef("target_no"),
+		}
+	}
+
+	// 3. Date ladders: the same question with an earlier or later deadline
+	return deadlineImplication(source, target)
+}
+
+// strikeRange is the set of values of a market's underlying for which it
+// resolves YES. Unbounded ends are infinite.
+type strikeRange struct {
+	lo, hi         float64
+	loIncl, hiIncl bool
+}
+
+// marketStrikeRange reads a market's YES range from its strike fields
+func marketStrikeRange(m db.Market) (strikeRange, bool) {
+	inf := math.Inf(1)
+	switch {
+	case m.StrikeType == "greater" && m.FloorStrike != nil:
+		return strikeRange{lo: *m.FloorStrike, hi: inf}, true
+	case m.StrikeType == "greater_or_equal" && m.FloorStrike != nil:
+		return strikeRange{lo: *m.FloorStrike, hi: inf, loIncl: true}, true
+	case m.StrikeType == "less" && m.CapStrike != nil:
+		return strikeRange{lo: -inf, hi: *m.CapStrike}, true
+	case m.StrikeType == "less_or_equal" && m.CapStrike != nil:
+		return strikeRange{lo: -inf, hi: *m.CapStrike, hiIncl: true}, true
+	case m.StrikeType == "between" && m.FloorStrike != nil && m.CapStrike != nil:
+		return strikeRange{lo: *m.FloorStrike, hi: *m.CapStrike, loIncl: true, hiIncl: true}, true
+	}
+	return strikeRange{}, false
+}
+
+// contains reports whether every value in b is also in r
+func (r strikeRange) contains(b strikeRange) bool {
+	loOK := r.lo < b.lo || (r.lo == b.lo && (r.loIncl || !b.loIncl))
+	hiOK := r.hi > b.hi || (r.hi == b.hi && (r.hiIncl || !b.hiIncl))
+	return loOK && hiOK
+}
+
+// overlaps reports whether some value is in both r and b
+func (r strikeRange) overlaps(b strikeRange) bool {
+	lo, loIncl := r.lo, r.loIncl
+	if b.lo > lo || (b.lo == lo && !b.loIncl) {
+		lo, loIncl = b.lo, b.loIncl
+	}
+	hi, hiIncl := r.hi, r.hiIncl
+	if b.hi < hi || (b.hi == hi && !b.hiIncl) {
+		hi, hiIncl = b.hi, b.hiIncl
+	}
+	return lo < hi || (lo == hi && loIncl && hiIncl)
+}
+
+// complement is the values outside r: up to two rays
+func (r strikeRange) complement() []strikeRange {
+	inf := math.Inf(1)
+	var out []strikeRange
+	if !math.IsInf(r.lo, -1) {
+		out = append(out, strikeRange{lo: -inf, hi: r.lo, hiIncl: !r.loIncl})
+	}
+	if !math.IsInf(r.hi, 1) {
+		out = append(out, strikeRange{lo: r.hi, hi: inf, loIncl: !r.hiIncl})
+	}
+	return out
+}
+
+func (r strikeRange) String() string {
+	switch {
+	case math.IsInf(r.hi, 1) && r.loIncl:
+		return fmt.Sprintf(">= %g", r.lo)
+	case math.IsInf(r.hi, 1):
+		return fmt.Sprintf("> %g", r.lo)
+	case math.IsInf(r.lo, -1) && r.hiIncl:
+		return fmt.Sprintf("<= %g", r.hi)
+	case math.IsInf(r.lo, -1):
+		return fmt.Sprintf("< %g", r.hi)
+	}
+	return fmt.Sprintf("between %g and %g", r.lo, r.hi)
+}
+
+// strikeImplication relates two markets on the same value by comparing the
+// ranges for which they resolve YES
+func strikeImplication(source, target db.Market) *slm.ComparisonResult {
+	yesSource, ok := marketStrikeRange(source)
+	if !ok {
+		return nil
+	}
+	yesTarget, ok := marketStrikeRange(target)
+	if !ok {
+		return nil
+	}
+
+	// impliedBy gives the target outcome forced by the value lying in ranges
+	impliedBy := func(ranges []strikeRange) *string {
+		if len(ranges) == 0 {
+			return nil
+		}
+		within, disjoint := true, true
+		for _, r := range ranges {
+			within = within && yesTarget.contains(r)
+			disjoint = disjoint && !yesTarget.overlaps(r)
+		}
+		switch {
+		case within:
+			return outcomeRef("target_yes")
+		case disjoint:
+			return outcomeRef("target_no")
+		}
+		return nil
+	}
+
+	return &slm.ComparisonResult{
+		Reason: fmt.Sprintf("Numeric ladder on one event: source is YES when the value is %s, target when it is %s",
+			yesSource, yesTarget),
+		SourceYes: impliedBy([]strikeRange{yesSource}),
+		SourceNo:  impliedBy(yesSource.complement()),
+	}
+}
+
+var (
+	// deadlineWord introduces the deadline of a cumulative question, as in
+	// "Will X happen by June 30?" or "... before 2026?"
+	deadlineWord = regexp.MustCompile(`(?i)\b(by|before)\b`)
+	// deadlineDate checks that what follows the deadline word is a date
+	deadlineDate = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\b|\b\d{4}\b|\b\d{1,2}/\d{1,2}\b`)
+)
+
+// deadlineQuestion splits a market title of the form "<question> by <date>" and
+// returns the question, normalized for comparison
+func deadlineQuestion(title string) (string, bool) {
+	locs := deadlineWord.FindAllStringIndex(title, -1)
+	if len(locs) == 0 {
+		return "", false
+	}
+	last := locs[len(locs)-1]
+	question := strings.ToLower(strings.Join(strings.Fields(title[:last[0]]), " "))
+	if question == "" || !deadlineDate.MatchString(title[last[1]:]) {
+		return "", false
+	}
+	return question, true
+}
+
+// rawTitle undoes the subtitle processEventBatch appends to a market's title, which
+// on a date ladder is often the deadline itself ("Before 2026")
+func rawTitle(m db.Market) string {
+	subtitle := m.Description
+	if m.YesSubTitle != "" || m.NoSubTitle != "" {
+		subtitle = m.YesSubTitle
+	}
+	if subtitle == "" {
+		return m.Title
+	}
+	return strings.TrimSuffix(m.Title, " "+subtitle)
+}
+
+// deadlineImplication relates two markets of a series asking whether the same
+// thing happens by different deadlines, taken from their close times: happening
+// by the earlier deadline means it happened by the later one too
+func deadlineImplication(source, target db.Market) *slm.ComparisonResult {
+	if source.SeriesTicker == "" || source.SeriesTicker != target.SeriesTicker {
+		return nil
+	}
+	if source.CloseTime.IsZero() || target.CloseTime.IsZero() || source.CloseTime.Equal(target.CloseTime) {
+		return nil
+	}
+	sourceQuestion, ok := deadlineQuestion(rawTitle(source))
+	if !ok {
+		return nil
+	}
+	targetQuestion, ok := deadlineQuestion(rawTitle(target))
+	if !ok || sourceQuestion != targetQuestion {
+		return nil
+	}
+
+	result := &slm.ComparisonResult{
+		Reason: fmt.Sprintf("Deadline ladder: source closes %s, target closes %s",
+			source.CloseTime.Format("2006-01-02"), target.CloseTime.Format("2006-01-02")),
+	}
+	if source.CloseTime.Before(target.CloseTime) {
+		result.SourceYes = outcomeRef("target_yes")
+	} else {
+		result.SourceNo = outcomeRef("target_no")
+	}
+	return result
+}
+
+func outcomeRef(outcome string) *string {
+	return &outcome
+}
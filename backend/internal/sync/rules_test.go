@@ -0,0 +1,212 @@
+package sync
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+)
+
+var inf = math.Inf(1)
+
+func TestStrikeRangeContains(t *testing.T) {
+	tests := []struct {
+		name string
+		r, b strikeRange
+		want bool
+	}{
+		{"ray contains higher ray", strikeRange{lo: 3, hi: inf}, strikeRange{lo: 4, hi: inf}, true},
+		{"ray misses lower ray", strikeRange{lo: 4, hi: inf}, strikeRange{lo: 3, hi: inf}, false},
+		{"> 3 misses >= 3", strikeRange{lo: 3, hi: inf}, strikeRange{lo: 3, hi: inf, loIncl: true}, false},
+		{">= 3 contains > 3", strikeRange{lo: 3, hi: inf, loIncl: true}, strikeRange{lo: 3, hi: inf}, true},
+		{"ray contains bucket", strikeRange{lo: 3, hi: inf}, strikeRange{lo: 3.25, hi: 3.5, loIncl: true, hiIncl: true}, true},
+		{"bucket misses ray", strikeRange{lo: 3.25, hi: 3.5, loIncl: true, hiIncl: true}, strikeRange{lo: 3.25, hi: inf}, false},
+		{"bucket contains itself", strikeRange{lo: 1, hi: 2, loIncl: true, hiIncl: true}, strikeRange{lo: 1, hi: 2, loIncl: true, hiIncl: true}, true},
+		{"< 5 contains <= 4", strikeRange{lo: -inf, hi: 5}, strikeRange{lo: -inf, hi: 4, hiIncl: true}, true},
+		{"< 5 misses <= 5", strikeRange{lo: -inf, hi: 5}, strikeRange{lo: -inf, hi: 5, hiIncl: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.r.contains(tt.b); got != tt.want {
+			t.Errorf("%s: (%s).contains(%s) = %v, want %v", tt.name, tt.r, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStrikeRangeOverlaps(t *testing.T) {
+	tests := []struct {
+		name string
+		r, b strikeRange
+		want bool
+	}{
+		{"rays in the same direction", strikeRange{lo: 3, hi: inf}, strikeRange{lo: 5, hi: inf}, true},
+		{"opposite rays apart", strikeRange{lo: 5, hi: inf}, strikeRange{lo: -inf, hi: 3}, false},
+		{"opposite rays crossing", strikeRange{lo: 3, hi: inf}, strikeRange{lo: -inf, hi: 5}, true},
+		{"touching, both inclusive", strikeRange{lo: 3, hi: inf, loIncl: true}, strikeRange{lo: -inf, hi: 3, hiIncl: true}, true},
+		{"touching, one exclusive", strikeRange{lo: 3, hi: inf}, strikeRange{lo: -inf, hi: 3, hiIncl: true}, false},
+		{"adjacent buckets", strikeRange{lo: 1, hi: 2, loIncl: true}, strikeRange{lo: 2, hi: 3, loIncl: true}, false},
+		{"nested buckets", strikeRange{lo: 1, hi: 4, loIncl: true, hiIncl: true}, strikeRange{lo: 2, hi: 3, loIncl: true, hiIncl: true}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.r.overlaps(tt.b); got != tt.want {
+			t.Errorf("%s: (%s).overlaps(%s) = %v, want %v", tt.name, tt.r, tt.b, got, tt.want)
+		}
+		if got := tt.b.overlaps(tt.r); got != tt.want {
+			t.Errorf("%s: overlaps is not symmetric", tt.name)
+		}
+	}
+}
+
+func TestStrikeRangeComplement(t *testing.T) {
+	tests := []struct {
+		name string
+		r    strikeRange
+		want []strikeRange
+	}{
+		{"> 3", strikeRange{lo: 3, hi: inf}, []strikeRange{{lo: -inf, hi: 3, hiIncl: true}}},
+		{">= 3", strikeRange{lo: 3, hi: inf, loIncl: true}, []strikeRange{{lo: -inf, hi: 3}}},
+		{"< 5", strikeRange{lo: -inf, hi: 5}, []strikeRange{{lo: 5, hi: inf, loIncl: true}}},
+		{"bucket", strikeRange{lo: 1, hi: 2, loIncl: true, hiIncl: true},
+			[]strikeRange{{lo: -inf, hi: 1}, {lo: 2, hi: inf}}},
+		{"everything", strikeRange{lo: -inf, hi: inf}, nil},
+	}
+	for _, tt := range tests {
+		got := tt.r.complement()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: complement = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: complement = %v, want %v", tt.name, got, tt.want)
+			}
+			if got[i].overlaps(tt.r) {
+				t.Errorf("%s: complement %s overlaps the range", tt.name, got[i])
+			}
+		}
+	}
+}
+
+func strike(strikeType string, floor, cap float64) db.Market {
+	m := db.Market{EventTicker: "KXFED-26DEC", StrikeType: strikeType}
+	if floor != 0 {
+		m.FloorStrike = &floor
+	}
+	if cap != 0 {
+		m.CapStrike = &cap
+	}
+	return m
+}
+
+func deadline(title, subtitle string, closes time.Time) db.Market {
+	// Titles are stored with the subtitle appended, as processEventBatch does
+	return db.Market{
+		SeriesTicker: "KXSTARSHIP",
+		Title:        title + " " + subtitle,
+		YesSubTitle:  subtitle,
+		CloseTime:    closes,
+	}
+}
+
+func TestResolveByRules(t *testing.T) {
+	event := &db.Event{ExternalID: "KXFED-26DEC"}
+	exclusive := &db.Event{ExternalID: "KXFED-26DEC", MutuallyExclusive: true}
+	june := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	december := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		source, target db.Market
+		event          *db.Event
+		applies        bool
+		yes, no        string // Implied target outcome, "" for none
+	}{
+		{"higher strike implies lower", strike("greater", 4, 0), strike("greater", 3, 0), event, true, "target_yes", ""},
+		{"lower strike failing implies higher fails", strike("greater", 3, 0), strike("greater", 4, 0), event, true, "", "target_no"},
+		{"bucket implies its ray", strike("between", 3.25, 3.5), strike("greater", 3, 0), event, true, "target_yes", ""},
+		{"ray below bucket excludes it", strike("less", 0, 3), strike("between", 3.25, 3.5), event, true, "target_no", ""},
+		{"disjoint buckets", strike("between", 3, 3.24), strike("between", 3.25, 3.5), event, true, "target_no", ""},
+		{"complementary rays", strike("greater", 3, 0), strike("less_or_equal", 0, 3), event, true, "target_no", "target_yes"},
+		{"strikes need a shared event", strike("greater", 4, 0), strike("greater", 3, 0), nil, false, "", ""},
+		{"mutually exclusive event", db.Market{}, db.Market{}, exclusive, true, "target_no", ""},
+		{"earlier deadline implies later",
+			deadline("Will Starship reach orbit before Jul 1, 2026?", "Before Jul 1, 2026", june),
+			deadline("Will Starship reach orbit before Jan 1, 2027?", "Before Jan 1, 2027", december),
+			nil, true, "target_yes", ""},
+		{"later deadline failing implies earlier fails",
+			deadline("Will Starship reach orbit before Jan 1, 2027?", "Before Jan 1, 2027", december),
+			deadline("Will Starship reach orbit before Jul 1, 2026?", "Before Jul 1, 2026", june),
+			nil, true, "", "target_no"},
+		{"different questions",
+			deadline("Will Starship reach orbit before Jul 1, 2026?", "Before Jul 1, 2026", june),
+			deadline("Will Starship land on Mars before Jan 1, 2027?", "Before Jan 1, 2027", december),
+			nil, false, "", ""},
+		{"not a deadline",
+			deadline("Will Starship reach orbit?", "Yes", june),
+			deadline("Will Starship reach orbit?", "Yes", december),
+			nil, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := resolveByRules(tt.source, tt.target, tt.event)
+			if (result != nil) != tt.applies {
+				t.Fatalf("applies = %v, want %v", result != nil, tt.applies)
+			}
+			if result == nil {
+				return
+			}
+			if got := outcomeOrEmpty(result.SourceYes); got != tt.yes {
+				t.Errorf("source YES implies %q, want %q (%s)", got, tt.yes, result.Reason)
+			}
+			if got := outcomeOrEmpty(result.SourceNo); got != tt.no {
+				t.Errorf("source NO implies %q, want %q (%s)", got, tt.no, result.Reason)
+			}
+		})
+	}
+}
+
+func TestDeadlineQuestion(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+		ok    bool
+	}{
+		{"Will Starship reach orbit before Jul 1, 2026?", "will starship reach orbit", true},
+		{"Will the Fed cut rates by  June 30?", "will the fed cut rates", true},
+		{"Will X happen by 12/31?", "will x happen", true},
+		{"Will X be bought by Y before 2027?", "will x be bought by y", true},
+		{"Will X be bought by Y?", "", false},
+		{"Before 2026", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := deadlineQuestion(tt.title)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("deadlineQuestion(%q) = %q, %v, want %q, %v", tt.title, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRawTitle(t *testing.T) {
+	tests := []struct {
+		market db.Market
+		want   string
+	}{
+		{db.Market{Title: "Will X happen before 2026? Before 2026", YesSubTitle: "Before 2026"}, "Will X happen before 2026?"},
+		{db.Market{Title: "Fed rate? Above 4%", YesSubTitle: "Above 4%", NoSubTitle: "4% or below"}, "Fed rate?"},
+		{db.Market{Title: "Who wins? Team A", Description: "Team A"}, "Who wins?"},
+		{db.Market{Title: "Plain title"}, "Plain title"},
+	}
+	for _, tt := range tests {
+		if got := rawTitle(tt.market); got != tt.want {
+			t.Errorf("rawTitle(%q) = %q, want %q", tt.market.Title, got, tt.want)
+		}
+	}
+}
+
+func outcomeOrEmpty(outcome *string) string {
+	if outcome == nil {
+		return ""
+	}
+	return *outcome
+}
@@ -1,24 +1,64 @@
 package sync
 
 import (
-	"log"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"backend/internal/db"
 	"backend/internal/embeddings"
+	"backend/internal/fees"
 	"backend/internal/kalshi"
 	"backend/internal/slm"
+	"backend/internal/vectorstore"
 
 	"gorm.io/gorm"
 )
 
+// ErrNoKalshiClient is returned by work that needs the exchange when no client is configured
+var ErrNoKalshiClient = errors.New("Kalshi client not configured")
+
+// ErrNoSLMService is returned by analysis when no SLM service is configured
+var ErrNoSLMService = errors.New("SLM service not configured")
+
+// Progress receives counters as long-running work advances, so a caller such as
+// the job scheduler can report how far it has got
+type Progress interface {
+	Add(key string, n int)
+}
+
+// noProgress discards the counters of callers that don't track them
+type noProgress struct{}
+
+func (noProgress) Add(string, int) {}
+
+func orNoProgress(progress Progress) Progress {
+	if progress == nil {
+		return noProgress{}
+	}
+	return progress
+}
+
 type Syncer struct {
 	DB               *gorm.DB
 	KClient          *kalshi.Client
 	EmbeddingService embeddings.Service
 	SLMService       slm.Service
 	Redis            *db.Redis
+	Fees             *fees.Model
+	Vectors          vectorstore.Store
 	LastEventSync    time.Time
+	// PaperStartingBalance is the virtual cash, in cents, the paper account started with
+	PaperStartingBalance int64
+	// ComparisonWorkers is how many market pairs are compared at once; it should
+	// match how many requests the SLM server handles in parallel
+	ComparisonWorkers int
+	// ComparisonBudget caps what one comparison cycle may spend
+	ComparisonBudget ComparisonBudget
+
+	buildingIndex     atomic.Bool
+	syncingEvents     atomic.Bool
+	refreshingMarkets atomic.Bool
 }
 
 func NewSyncer(database *gorm.DB, kClient *kalshi.Client, embeddingService embeddings.Service, slmService slm.Service, rdb *db.Redis) *Syncer {
@@ -28,33 +68,11 @@ func NewSyncer(database *gorm.DB, kClient *kalshi.Client, embeddingService embed
 		EmbeddingService: embeddingService,
 		SLMService:       slmService,
 		Redis:            rdb,
-	}
-}
-
-// RunCycle performs the market sync and analysis
-func (s *Syncer) RunCycle() {
-	// 1. Sync events (daily check inside)
-	s.SyncEvents()
-
-	// 2. Analyze related markets for upcoming events
-	if s.EmbeddingService != nil {
-		// Check global analysis cooldown
-		runAnalysis := true
-		if s.Redis != nil {
-			if _, err := s.Redis.Get("analysis:global_cooldown"); err == nil {
-				runAnalysis = false
-				log.Println("Skipping analysis: Global cooldown active")
-			}
-		}
-
-		if runAnalysis {
-			s.AnalyzeRelatedMarkets()
-			// Set global cooldown
-			if s.Redis != nil {
-				s.Redis.AddWithTTL("analysis:global_cooldown", "1", 3*time.Hour)
-			}
-		}
-	} else {
-		log.Println("Skipping related markets analysis: Embedding service not available")
+		Fees:             fees.NewModel(),
+		Vectors:          vectorstore.New(database),
+		// $1,000 unless overridden by the caller
+		PaperStartingBalance: 100000,
+		ComparisonWorkers:    DefaultComparisonWorkers,
+		ComparisonBudget:     ComparisonBudget{Time: DefaultComparisonTimeBudget},
 	}
 }
@@ -0,0 +1,365 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/slm"
+	"backend/internal/trader"
+
+	"gorm.io/gorm/clause"
+)
+
+// ReconcileSettlements books the outcome of resolved markets: positions are closed,
+// executions and opportunities get their realized P&L, and stored implications are
+// graded against how both of their markets actually resolved. Returns how many
+// markets were settled.
+func (s *Syncer) ReconcileSettlements(ctx context.Context, progress Progress) (int, error) {
+	progress = orNoProgress(progress)
+	if s.KClient == nil {
+		return 0, ErrNoKalshiClient
+	}
+
+	log.Println("Starting settlement reconciliation...")
+
+	var provider db.Provider
+	if err := s.DB.Where("name = ?", "kalshi").FirstOrCreate(&provider, db.Provider{Name: "kalshi"}).Error; err != nil {
+		return 0, fmt.Errorf("failed to get/create provider: %w", err)
+	}
+
+	// 1. Record what the exchange paid us
+	newest, pullErr := s.pullSettlements(provider.LastSettlementSync)
+	if pullErr != nil {
+		log.Printf("Failed to pull settlements: %v", pullErr)
+	}
+
+	// 2. Resolve every market we hold, traded or made a claim about
+	settled := 0
+	for _, ticker := range s.unresolvedTickers() {
+		if ctx.Err() != nil {
+			break // Book what has resolved so far
+		}
+		if s.resolveMarket(provider.ID, ticker) {
+			settled++
+			progress.Add("markets_settled", 1)
+		}
+	}
+
+	// 3. Roll execution P&L up to opportunities and grade implications
+	s.settleOpportunities()
+	s.gradeImplications()
+
+	if newest.After(provider.LastSettlementSync) {
+		if err := s.DB.Model(&provider).Update("last_settlement_sync", newest).Error; err != nil {
+			log.Printf("Failed to update provider last settlement sync: %v", err)
+		}
+	}
+
+	log.Printf("Settlement reconciliation complete. Markets settled: %d", settled)
+	if err := ctx.Err(); err != nil {
+		return settled, err
+	}
+	if pullErr != nil {
+		return settled, fmt.Errorf("failed to pull settlements: %w", pullErr)
+	}
+	return settled, nil
+}
+
+// pullSettlements stores the exchange's settlement records newer than since
+// and returns the newest settlement time seen
+func (s *Syncer) pullSettlements(since time.Time) (time.Time, error) {
+	newest := since
+	var minTs int64
+	if !since.IsZero() {
+		minTs = since.Unix()
+	}
+
+	cursor := ""
+	for {
+		resp, err := s.KClient.GetSettlements(100, cursor, minTs)
+		if err != nil {
+			return newest, err
+		}
+
+		for _, st := range resp.Settlements {
+			record := db.Settlement{
+				Ticker:       st.Ticker,
+				MarketResult: st.MarketResult,
+				YesCount:     st.YesCount,
+				NoCount:      st.NoCount,
+				Revenue:      st.Revenue,
+				SettledAt:    st.SettledTime,
+			}
+			if err := s.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "ticker"}},
+				DoUpdates: clause.AssignmentColumns([]string{"market_result", "yes_count", "no_count", "revenue", "settled_at"}),
+			}).Create(&record).Error; err != nil {
+				log.Printf("Failed to save settlement for %s: %v", st.Ticker, err)
+			}
+			if st.SettledTime.After(newest) {
+				newest = st.SettledTime
+			}
+		}
+
+		if resp.Cursor == "" {
+			return newest, nil
+		}
+		cursor = resp.Cursor
+	}
+}
+
+// unresolvedTickers lists markets with open positions, unsettled executions,
+// ungraded implications or unprocessed exchange settlements
+func (s *Syncer) unresolvedTickers() []string {
+	queries := []string{
+		"SELECT ticker FROM positions WHERE status = 'open'",
+		"SELECT ticker FROM paper_positions WHERE status = 'open'",
+		"SELECT ticker FROM fills WHERE settled = false",
+		"SELECT ticker FROM paper_fills WHERE settled = false",
+		"SELECT source_ticker FROM market_implications WHERE verdict = ''",
+		"SELECT target_ticker FROM market_implications WHERE verdict = ''",
+		"SELECT ticker FROM settlements WHERE ticker NOT IN (SELECT ticker FROM markets WHERE settled_at IS NOT NULL)",
+	}
+
+	seen := make(map[string]bool)
+	var tickers []string
+	for _, q := range queries {
+		var rows []string
+		if err := s.DB.Raw(q).Scan(&rows).Error; err != nil {
+			log.Printf("Failed to collect unresolved markets: %v", err)
+			continue
+		}
+		for _, t := range rows {
+			if !seen[t] {
+				seen[t] = true
+				tickers = append(tickers, t)
+			}
+		}
+	}
+	return tickers
+}
+
+// resolveMarket settles a market's ledgers if it has resolved, fetching the
+// result from Kalshi when we don't have it yet. Returns true if newly settled.
+func (s *Syncer) resolveMarket(providerID uint, ticker string) bool {
+	var market db.Market
+	s.DB.Where("ticker = ?", ticker).Limit(1).Find(&market)
+
+	newlySettled := false
+	if market.SettledAt == nil {
+		// Markets still trading can't have a result yet
+		if !market.CloseTime.IsZero() && market.CloseTime.After(time.Now()) {
+			return false
+		}
+
+		time.Sleep(50 * time.Millisecond) // Rate limit protection
+		data, err := s.KClient.GetMarket(ticker)
+		if err != nil {
+			log.Printf("Failed to fetch result for %s: %v", ticker, err)
+			return false
+		}
+		if data.Result != "yes" && data.Result != "no" {
+			return false
+		}
+
+		settledAt := data.SettlementTs
+		if settledAt.IsZero() {
+			settledAt = time.Now()
+		}
+		yesPayout := data.SettlementValue
+		if data.Result == "yes" && yesPayout == 0 {
+			yesPayout = 100
+		}
+
+		res := s.DB.Model(&db.Market{}).Where("ticker = ?", ticker).Updates(map[string]interface{}{
+			"status":           "settled",
+			"result":           data.Result,
+			"settlement_value": yesPayout,
+			"settled_at":       settledAt,
+		})
+		if res.Error != nil {
+			log.Printf("Failed to mark %s settled: %v", ticker, res.Error)
+			return false
+		}
+		if res.RowsAffected == 0 {
+			// We hold or were paid for a market the event sync never stored (e.g. one
+			// traded by hand), so store it settled or it would be fetched on every run
+			if err := s.DB.Create(&db.Market{
+				ProviderID:      providerID,
+				ExternalID:      ticker,
+				Ticker:          ticker,
+				EventTicker:     data.EventTicker,
+				Title:           data.Title,
+				Rules:           strings.TrimSpace(data.RulesPrimary + "\n" + data.RulesSecondary),
+				Status:          "settled",
+				CloseTime:       data.CloseTime,
+				Result:          data.Result,
+				SettlementValue: yesPayout,
+				SettledAt:       &settledAt,
+				LastDataUpdate:  time.Now(),
+			}).Error; err != nil {
+				log.Printf("Failed to store settled market %s: %v", ticker, err)
+				return false
+			}
+		}
+
+		market.Result = data.Result
+		market.SettlementValue = yesPayout
+		market.SettledAt = &settledAt
+		newlySettled = true
+	}
+
+	for _, ledger := range []trader.Ledger{trader.LiveLedger, trader.PaperLedger} {
+		if err := ledger.Settle(s.DB, ticker, market.SettlementValue, *market.SettledAt); err != nil {
+			log.Printf("Failed to settle %s in %s: %v", ticker, ledger.Positions, err)
+		}
+	}
+	return newlySettled
+}
+
+// settleOpportunities computes realized P&L for traded opportunities whose legs have all settled
+func (s *Syncer) settleOpportunities() {
+	var opportunities []db.ArbitrageOpportunity
+	if err := s.DB.Preload("Legs").
+		Where("status IN ? AND settled_at IS NULL", []string{"executed", "partial"}).
+		Find(&opportunities).Error; err != nil {
+		log.Printf("Failed to fetch opportunities to settle: %v", err)
+		return
+	}
+
+	for _, opp := range opportunities {
+		legTickers := make(map[string]bool)
+		for _, leg := range opp.Legs {
+			legTickers[leg.Ticker] = true
+		}
+		tickers := make([]string, 0, len(legTickers))
+		for t := range legTickers {
+			tickers = append(tickers, t)
+		}
+
+		var settledMarkets int64
+		s.DB.Model(&db.Market{}).Where("ticker IN ? AND settled_at IS NOT NULL", tickers).Count(&settledMarkets)
+		if int(settledMarkets) < len(tickers) {
+			continue
+		}
+
+		var total float64
+		for _, ledger := range []trader.Ledger{trader.LiveLedger, trader.PaperLedger} {
+			pnl, err := ledger.OpportunityPnL(s.DB, opp.ID)
+			if err != nil {
+				log.Printf("Failed to compute P&L for opportunity %d: %v", opp.ID, err)
+				continue
+			}
+			total += pnl
+		}
+
+		now := time.Now()
+		if err := s.DB.Model(&opp).Updates(map[string]interface{}{
+			"realized_pnl": total,
+			"settled_at":   now,
+		}).Error; err != nil {
+			log.Printf("Failed to settle opportunity %d: %v", opp.ID, err)
+			continue
+		}
+		log.Printf("Opportunity %d settled: realized %.0f¢", opp.ID, total)
+	}
+}
+
+// gradeImplications checks stored implications against resolved outcomes.
+// An implication whose premise did not occur is untested rather than correct.
+func (s *Syncer) gradeImplications() {
+	var implications []db.MarketImplication
+	if err := s.DB.Where("verdict = ?", "").Find(&implications).Error; err != nil {
+		log.Printf("Failed to fetch implications to grade: %v", err)
+		return
+	}
+
+	results := make(map[string]string)
+	resultFor := func(ticker string) string {
+		if r, ok := results[ticker]; ok {
+			return r
+		}
+		var m db.Market
+		s.DB.Where("ticker = ? AND settled_at IS NOT NULL", ticker).Limit(1).Find(&m)
+		results[ticker] = m.Result
+		return m.Result
+	}
+
+	counts := make(map[string]int)
+	for _, imp := range implications {
+		sourceResult := resultFor(imp.SourceTicker)
+		targetResult := resultFor(imp.TargetTicker)
+		if sourceResult == "" || targetResult == "" {
+			continue
+		}
+
+		verdict := "untested"
+		if sourceResult == imp.SourceOutcome {
+			verdict = "incorrect"
+			if targetResult == imp.TargetOutcome {
+				verdict = "correct"
+			}
+		}
+
+		now := time.Now()
+		if err := s.DB.Model(&imp).Updates(map[string]interface{}{
+			"verdict":      verdict,
+			"evaluated_at": now,
+		}).Error; err != nil {
+			log.Printf("Failed to grade implication %d: %v", imp.ID, err)
+			continue
+		}
+		counts[verdict]++
+	}
+
+	if len(counts) > 0 {
+		log.Printf("Graded implications: %d correct, %d incorrect, %d untested",
+			counts["correct"], counts["incorrect"], counts["untested"])
+	}
+}
+
+// saveImplications stores the implications from a comparison so they can be
+// graded once both markets resolve
+func (s *Syncer) saveImplications(source, target db.Market, result *slm.ComparisonResult) {
+	implications := []struct {
+		sourceOutcome string
+		targetOutcome *string
+		confidence    float64
+	}{
+		{"yes", result.SourceYes, result.SourceYesConfidence},
+		{"no", result.SourceNo, result.SourceNoConfidence},
+	}
+	engine := result.Engine
+	if engine == "" {
+		engine = EngineSLM
+	}
+
+	for _, imp := range implications {
+		if imp.targetOutcome == nil {
+			continue
+		}
+
+		record := db.MarketImplication{
+			SourceTicker:  source.ExternalID,
+			TargetTicker:  target.ExternalID,
+			SourceOutcome: imp.sourceOutcome,
+			TargetOutcome: outcomeSide(*imp.targetOutcome),
+			Reason:        result.Reason,
+			Engine:        engine,
+		}
+		if imp.confidence > 0 {
+			confidence := imp.confidence
+			record.Confidence = &confidence
+		}
+		if err := s.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "source_ticker"}, {Name: "target_ticker"}, {Name: "source_outcome"}},
+			DoUpdates: clause.AssignmentColumns([]string{"target_outcome", "reason", "engine", "confidence", "updated_at"}),
+		}).Create(&record).Error; err != nil {
+			log.Printf("Failed to save implication %s -> %s: %v", source.ExternalID, target.ExternalID, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/kalshi/types"
+	"backend/internal/trader"
+)
+
+func TestReconcileSettlements(t *testing.T) {
+	database := newTestDB(t)
+	provider := db.Provider{Name: "kalshi"}
+	database.Create(&provider)
+
+	closed := time.Now().Add(-24 * time.Hour)
+	for _, m := range []db.Market{
+		{ProviderID: provider.ID, ExternalID: "KXA-1", Ticker: "KXA-1", Status: "closed", CloseTime: closed},
+		{ProviderID: provider.ID, ExternalID: "KXB-1", Ticker: "KXB-1", Status: "closed", CloseTime: closed},
+		{ProviderID: provider.ID, ExternalID: "KXC-1", Ticker: "KXC-1", Status: "active", CloseTime: time.Now().Add(24 * time.Hour)},
+	} {
+		database.Create(&m)
+	}
+
+	// A hedge bought on paper: NO on KXA-1 at 45¢ and YES on KXB-1 at 50¢, 18¢ of fees each
+	fills := []db.Fill{
+		{Ticker: "KXA-1", Side: "no", Price: 45},
+		{Ticker: "KXB-1", Side: "yes", Price: 50},
+		{Ticker: "KXC-1", Side: "yes", Price: 30}, // Still trading, so the opportunity can't settle yet
+	}
+	opp := db.ArbitrageOpportunity{StrategyType: "implication", Status: "executed"}
+	for _, f := range fills {
+		opp.Legs = append(opp.Legs, db.OpportunityLeg{Ticker: f.Ticker, Side: f.Side, Action: "buy", Price: f.Price, Count: 10})
+	}
+	database.Create(&opp)
+	for i, leg := range fills {
+		order := db.Order{OpportunityID: opp.ID, ClientOrderID: leg.Ticker, Ticker: leg.Ticker, Side: leg.Side,
+			Action: "buy", Price: leg.Price, Count: 10, FilledCount: 10, Status: "executed"}
+		database.Table(trader.PaperLedger.Orders).Create(&order)
+
+		leg.OrderID = order.ID
+		leg.ExternalID = order.ClientOrderID
+		leg.Action = "buy"
+		leg.Count = 10
+		leg.Fee = 18
+		leg.FilledAt = closed.Add(-time.Duration(i) * time.Hour)
+		if err := trader.PaperLedger.BookFill(database, leg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var marketRequests atomic.Int64
+	results := map[string]types.MarketData{
+		"KXA-1": {Ticker: "KXA-1", Status: "finalized", Result: "yes"},
+		"KXB-1": {Ticker: "KXB-1", Status: "finalized", Result: "yes"},
+		"KXZ-9": {Ticker: "KXZ-9", EventTicker: "KXZ", Title: "Traded by hand", Status: "finalized", Result: "no", CloseTime: closed},
+	}
+	syncer := &Syncer{DB: database, KClient: newKalshiStub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/portfolio/settlements"):
+			// A payout for a market the event sync never stored
+			json.NewEncoder(w).Encode(map[string]any{"settlements": []types.Settlement{
+				{Ticker: "KXZ-9", MarketResult: "no", NoCount: 5, Revenue: 500, SettledTime: closed},
+			}})
+		case strings.Contains(r.URL.Path, "/markets/"):
+			marketRequests.Add(1)
+			serveMarkets(results)(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})}
+
+	settled, err := syncer.ReconcileSettlements(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled != 3 {
+		t.Errorf("settled %d markets, want 3", settled)
+	}
+
+	// 1. The unknown market is stored as settled
+	var unknown db.Market
+	if err := database.Where("ticker = ?", "KXZ-9").First(&unknown).Error; err != nil {
+		t.Fatalf("settled market KXZ-9 wasn't stored: %v", err)
+	}
+	if unknown.Status != "settled" || unknown.Result != "no" || unknown.SettledAt == nil || unknown.ProviderID != provider.ID {
+		t.Errorf("stored KXZ-9 as %+v, want a settled NO market", unknown)
+	}
+
+	// 2. Positions on resolved markets are closed at the payout; the one still trading stays open
+	positions := map[string]db.Position{}
+	var rows []db.Position
+	database.Table(trader.PaperLedger.Positions).Find(&rows)
+	for _, p := range rows {
+		positions[p.Ticker] = p
+	}
+	if p := positions["KXA-1"]; p.Status != "closed" || p.RealizedPnL != -468 {
+		t.Errorf("KXA-1 position %s with P&L %.0f, want closed at -468", p.Status, p.RealizedPnL)
+	}
+	if p := positions["KXB-1"]; p.Status != "closed" || p.RealizedPnL != 482 {
+		t.Errorf("KXB-1 position %s with P&L %.0f, want closed at 482", p.Status, p.RealizedPnL)
+	}
+	if p := positions["KXC-1"]; p.Status != "open" {
+		t.Errorf("KXC-1 position is %s, want open until the market resolves", p.Status)
+	}
+
+	// 3. The opportunity waits for its last leg
+	database.First(&opp, opp.ID)
+	if opp.SettledAt != nil {
+		t.Fatalf("opportunity settled with a leg still trading")
+	}
+
+	// 4. Once the last leg resolves the opportunity realizes the P&L of every fill
+	results["KXC-1"] = types.MarketData{Ticker: "KXC-1", Status: "finalized", Result: "no"}
+	database.Model(&db.Market{}).Where("ticker = ?", "KXC-1").Update("close_time", closed)
+	before := marketRequests.Load()
+
+	settled, err = syncer.ReconcileSettlements(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled != 1 {
+		t.Errorf("settled %d markets on the second run, want 1", settled)
+	}
+	if requests := marketRequests.Load() - before; requests != 1 {
+		t.Errorf("fetched %d market results on the second run, want only KXC-1", requests)
+	}
+
+	database.First(&opp, opp.ID)
+	// -468 + 482 for the hedge, then (0 - 30) × 10 - 18 for the losing YES on KXC-1
+	if opp.SettledAt == nil || opp.RealizedPnL != -304 {
+		t.Errorf("opportunity realized %.0f¢ (settled %v), want -304", opp.RealizedPnL, opp.SettledAt != nil)
+	}
+
+	// 5. Nothing is left to resolve
+	if tickers := syncer.unresolvedTickers(); len(tickers) != 0 {
+		t.Errorf("unresolved tickers %v after every market settled", tickers)
+	}
+}
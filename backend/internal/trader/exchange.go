@@ -0,0 +1,36 @@
+package trader
+
+import "github.com/google/uuid"
+
+// ClientOrderPrefix starts the client_order_id of every order the trader submits,
+// so the reconciler can tell its orders apart from ones placed by hand
+const ClientOrderPrefix = "arb-"
+
+// newClientOrderID returns a unique client order ID tagged with ClientOrderPrefix
+func newClientOrderID() string {
+	return ClientOrderPrefix + uuid.NewString()
+}
+
+// OrderRequest describes a single order the trader wants to place
+type OrderRequest struct {
+	OpportunityID uint
+	Ticker        string
+	Side          string // yes, no
+	Action        string // buy, sell
+	Price         int    // Limit price in cents
+	Count         int
+}
+
+// OrderResult reports what happened to a placed order
+type OrderResult struct {
+	OrderID     uint // Local order ID in the exchange's ledger
+	Status      string
+	FilledCount int
+}
+
+// Exchange routes orders to a venue. Only the paper matching engine exists so far.
+// Orders are immediate-or-cancel, so PlaceOrder returns once the order is final.
+type Exchange interface {
+	PlaceOrder(req OrderRequest) (*OrderResult, error)
+	Ledger() Ledger
+}
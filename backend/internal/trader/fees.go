@@ -0,0 +1,43 @@
+package trader
+
+import (
+	"backend/internal/db"
+	"backend/internal/fees"
+
+	"gorm.io/gorm"
+)
+
+// feeMarket loads the fee-relevant attributes of a market. Markets that haven't been
+// synced yet fall back to the series implied by their ticker.
+func feeMarket(database *gorm.DB, ticker string) fees.Market {
+	var m db.Market
+	database.Where("ticker = ?", ticker).Limit(1).Find(&m)
+
+	return fees.Market{
+		Ticker:             ticker,
+		SeriesTicker:       m.SeriesTicker,
+		FeeWaiverExpiresAt: m.FeeWaiverExpiresAt,
+	}
+}
+
+// orderFeeTracker attributes a single order's rounded fee across its fills,
+// keeping taker and maker executions on their own schedules
+type orderFeeTracker struct {
+	taker *fees.OrderFees
+	maker *fees.OrderFees
+}
+
+func newOrderFeeTracker(model *fees.Model, market fees.Market) *orderFeeTracker {
+	return &orderFeeTracker{
+		taker: model.NewOrder(market, fees.Taker),
+		maker: model.NewOrder(market, fees.Maker),
+	}
+}
+
+// add returns the fee in cents attributable to a fill
+func (t *orderFeeTracker) add(count, priceCents int, isTaker bool) int {
+	if isTaker {
+		return t.taker.Add(count, priceCents)
+	}
+	return t.maker.Add(count, priceCents)
+}
@@ -0,0 +1,133 @@
+package trader
+
+import (
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// Ledger names the tables an exchange books its orders, fills and positions into
+type Ledger struct {
+	Orders    string
+	Fills     string
+	Positions string
+}
+
+var (
+	LiveLedger  = Ledger{Orders: "orders", Fills: "fills", Positions: "positions"}
+	PaperLedger = Ledger{Orders: "paper_orders", Fills: "paper_fills", Positions: "paper_positions"}
+)
+
+// BookFill records a fill and updates the position it affects
+func (l Ledger) BookFill(tx *gorm.DB, fill db.Fill) error {
+	if err := tx.Table(l.Fills).Create(&fill).Error; err != nil {
+		return err
+	}
+
+	var pos db.Position
+	res := tx.Table(l.Positions).Where("ticker = ? AND side = ?", fill.Ticker, fill.Side).Limit(1).Find(&pos)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		pos = db.Position{
+			Ticker:   fill.Ticker,
+			Side:     fill.Side,
+			Status:   "open",
+			OpenedAt: fill.FilledAt,
+		}
+	}
+
+	applyFill(&pos, fill)
+
+	return tx.Table(l.Positions).Save(&pos).Error
+}
+
+// applyFill updates a position's size, average cost and realized P&L for a fill.
+// Fees are charged to realized P&L as they are paid.
+func applyFill(pos *db.Position, fill db.Fill) {
+	pos.FeesPaid += fill.Fee
+
+	switch fill.Action {
+	case "buy":
+		if pos.Status == "closed" {
+			pos.Status = "open"
+			pos.OpenedAt = fill.FilledAt
+			pos.ClosedAt = nil
+		}
+		cost := pos.AvgPrice*float64(pos.Count) + float64(fill.Price*fill.Count)
+		pos.Count += fill.Count
+		pos.AvgPrice = cost / float64(pos.Count)
+		pos.RealizedPnL -= float64(fill.Fee)
+	case "sell":
+		closed := min(fill.Count, pos.Count)
+		pos.RealizedPnL += (float64(fill.Price)-pos.AvgPrice)*float64(closed) - float64(fill.Fee)
+		pos.Count -= closed
+		if pos.Count == 0 {
+			closedAt := fill.FilledAt
+			pos.Status = "closed"
+			pos.ClosedAt = &closedAt
+			pos.AvgPrice = 0
+		}
+	}
+}
+
+// Settle marks every unsettled fill on a resolved market with its realized P&L and
+// closes the positions held in it. yesPayout is the cents paid per YES contract.
+func (l Ledger) Settle(database *gorm.DB, ticker string, yesPayout int, settledAt time.Time) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		// 1. Realize each execution against the settlement value
+		var fills []db.Fill
+		if err := tx.Table(l.Fills).Where("ticker = ? AND settled = ?", ticker, false).Find(&fills).Error; err != nil {
+			return err
+		}
+		for _, f := range fills {
+			pnl := float64((sidePayout(f.Side, yesPayout)-f.Price)*f.Count - f.Fee)
+			if f.Action == "sell" {
+				pnl = float64((f.Price-sidePayout(f.Side, yesPayout))*f.Count - f.Fee)
+			}
+			if err := tx.Table(l.Fills).Where("id = ?", f.ID).
+				Updates(map[string]interface{}{"realized_pnl": pnl, "settled": true}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 2. Close open positions at the payout
+		var positions []db.Position
+		if err := tx.Table(l.Positions).Where("ticker = ? AND status = ?", ticker, "open").Find(&positions).Error; err != nil {
+			return err
+		}
+		for _, pos := range positions {
+			pos.RealizedPnL += (float64(sidePayout(pos.Side, yesPayout)) - pos.AvgPrice) * float64(pos.Count)
+			pos.Count = 0
+			pos.AvgPrice = 0
+			pos.Status = "closed"
+			pos.ClosedAt = &settledAt
+			if err := tx.Table(l.Positions).Save(&pos).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// OpportunityPnL sums the realized P&L of every execution placed for an opportunity
+func (l Ledger) OpportunityPnL(database *gorm.DB, opportunityID uint) (float64, error) {
+	var pnl float64
+	err := database.Table(l.Fills).
+		Select("COALESCE(SUM("+l.Fills+".realized_pnl), 0)").
+		Joins("JOIN "+l.Orders+" ON "+l.Orders+".id = "+l.Fills+".order_id").
+		Where(l.Orders+".opportunity_id = ?", opportunityID).
+		Scan(&pnl).Error
+	return pnl, err
+}
+
+// sidePayout returns the cents paid per contract on a side given the YES payout
+func sidePayout(side string, yesPayout int) int {
+	if side == "no" {
+		return 100 - yesPayout
+	}
+	return yesPayout
+}
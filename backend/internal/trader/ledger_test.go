@@ -0,0 +1,83 @@
+package trader
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+)
+
+func TestApplyFill(t *testing.T) {
+	opened := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := opened.Add(time.Hour)
+	fill := func(action string, count, price, fee int, at time.Time) db.Fill {
+		return db.Fill{Ticker: "T", Side: "yes", Action: action, Count: count, Price: price, Fee: fee, FilledAt: at}
+	}
+
+	tests := []struct {
+		name  string
+		start db.Position
+		fills []db.Fill
+		want  db.Position
+	}{
+		{
+			name:  "first buy opens at its price",
+			start: db.Position{Status: "open", OpenedAt: opened},
+			fills: []db.Fill{fill("buy", 10, 40, 2, opened)},
+			want:  db.Position{Count: 10, AvgPrice: 40, RealizedPnL: -2, FeesPaid: 2, Status: "open", OpenedAt: opened},
+		},
+		{
+			name:  "buys average their cost",
+			start: db.Position{Status: "open", OpenedAt: opened},
+			fills: []db.Fill{fill("buy", 10, 40, 2, opened), fill("buy", 30, 60, 5, opened)},
+			want:  db.Position{Count: 40, AvgPrice: 55, RealizedPnL: -7, FeesPaid: 7, Status: "open", OpenedAt: opened},
+		},
+		{
+			name:  "partial sell realizes against the average",
+			start: db.Position{Count: 10, AvgPrice: 40, Status: "open", OpenedAt: opened},
+			fills: []db.Fill{fill("sell", 4, 50, 1, later)},
+			want:  db.Position{Count: 6, AvgPrice: 40, RealizedPnL: 39, FeesPaid: 1, Status: "open", OpenedAt: opened},
+		},
+		{
+			name:  "selling everything closes the position",
+			start: db.Position{Count: 10, AvgPrice: 40, Status: "open", OpenedAt: opened},
+			fills: []db.Fill{fill("sell", 10, 30, 1, later)},
+			want:  db.Position{Count: 0, AvgPrice: 0, RealizedPnL: -101, FeesPaid: 1, Status: "closed", OpenedAt: opened, ClosedAt: &later},
+		},
+		{
+			name:  "overselling only closes what is held",
+			start: db.Position{Count: 5, AvgPrice: 40, Status: "open", OpenedAt: opened},
+			fills: []db.Fill{fill("sell", 8, 50, 0, later)},
+			want:  db.Position{Count: 0, AvgPrice: 0, RealizedPnL: 50, Status: "closed", OpenedAt: opened, ClosedAt: &later},
+		},
+		{
+			name:  "buying into a closed position reopens it",
+			start: db.Position{RealizedPnL: 50, FeesPaid: 3, Status: "closed", OpenedAt: opened, ClosedAt: &opened},
+			fills: []db.Fill{fill("buy", 2, 70, 1, later)},
+			want:  db.Position{Count: 2, AvgPrice: 70, RealizedPnL: 49, FeesPaid: 4, Status: "open", OpenedAt: later},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := tt.start
+			for _, f := range tt.fills {
+				applyFill(&pos, f)
+			}
+			if pos.Count != tt.want.Count || math.Abs(pos.AvgPrice-tt.want.AvgPrice) > 1e-9 ||
+				math.Abs(pos.RealizedPnL-tt.want.RealizedPnL) > 1e-9 || pos.FeesPaid != tt.want.FeesPaid {
+				t.Errorf("count %d avg %.2f pnl %.2f fees %d, want count %d avg %.2f pnl %.2f fees %d",
+					pos.Count, pos.AvgPrice, pos.RealizedPnL, pos.FeesPaid,
+					tt.want.Count, tt.want.AvgPrice, tt.want.RealizedPnL, tt.want.FeesPaid)
+			}
+			if pos.Status != tt.want.Status || !pos.OpenedAt.Equal(tt.want.OpenedAt) {
+				t.Errorf("status %s opened %v, want %s opened %v", pos.Status, pos.OpenedAt, tt.want.Status, tt.want.OpenedAt)
+			}
+			if (pos.ClosedAt == nil) != (tt.want.ClosedAt == nil) ||
+				(pos.ClosedAt != nil && !pos.ClosedAt.Equal(*tt.want.ClosedAt)) {
+				t.Errorf("closed at %v, want %v", pos.ClosedAt, tt.want.ClosedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package trader
+
+import (
+	"encoding/json"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// MarkedPosition is an open position valued at the best bid we could sell into
+type MarkedPosition struct {
+	db.Position
+	Mark float64 // Cents per contract
+}
+
+// Value returns the position's market value in cents
+func (p MarkedPosition) Value() float64 {
+	return p.Mark * float64(p.Count)
+}
+
+// CostBasis returns what was paid for the contracts still held, in cents
+func (p MarkedPosition) CostBasis() float64 {
+	return p.AvgPrice * float64(p.Count)
+}
+
+// UnrealizedPnL returns the position's paper profit in cents
+func (p MarkedPosition) UnrealizedPnL() float64 {
+	return p.Value() - p.CostBasis()
+}
+
+// MarkPositions values a ledger's open positions against their latest orderbook snapshots.
+// Positions without a usable snapshot are marked at cost.
+func (l Ledger) MarkPositions(database *gorm.DB) ([]MarkedPosition, error) {
+	var positions []db.Position
+	if err := database.Table(l.Positions).Where("status = ? AND count > 0", "open").Find(&positions).Error; err != nil {
+		return nil, err
+	}
+
+	marked := make([]MarkedPosition, len(positions))
+	for i, pos := range positions {
+		marked[i] = MarkedPosition{Position: pos, Mark: pos.AvgPrice}
+
+		snapshot, err := LatestOrderbook(database, pos.Ticker)
+		if err != nil {
+			continue
+		}
+		if bid, ok := bestBid(snapshot, pos.Side); ok {
+			marked[i].Mark = float64(bid)
+		}
+	}
+
+	return marked, nil
+}
+
+// bestBid returns the highest resting bid on one side of a snapshot
+func bestBid(snapshot *db.OrderbookSnapshot, side string) (int, bool) {
+	raw := snapshot.Yes
+	if side == "no" {
+		raw = snapshot.No
+	}
+
+	var levels [][2]int
+	if err := json.Unmarshal([]byte(raw), &levels); err != nil || len(levels) == 0 {
+		return 0, false
+	}
+
+	best := levels[0][0]
+	for _, l := range levels[1:] {
+		if l[0] > best {
+			best = l[0]
+		}
+	}
+	return best, true
+}
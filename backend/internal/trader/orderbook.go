@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/kalshi"
+
+	"gorm.io/gorm"
+)
+
+// level is a single price level an order can trade against, in the order's own side prices
+type level struct {
+	Price    int
+	Quantity int
+}
+
+// CaptureOrderbook fetches the current book for a ticker and stores it as a snapshot
+func CaptureOrderbook(database *gorm.DB, kClient *kalshi.Client, ticker string) (*db.OrderbookSnapshot, error) {
+	book, err := kClient.GetOrderbook(ticker, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch orderbook for %s: %w", ticker, err)
+	}
+
+	yesBytes, err := json.Marshal(book.Yes)
+	if err != nil {
+		return nil, err
+	}
+	noBytes, err := json.Marshal(book.No)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := db.OrderbookSnapshot{
+		Ticker:     ticker,
+		Yes:        string(yesBytes),
+		No:         string(noBytes),
+		CapturedAt: time.Now(),
+	}
+	if err := database.Create(&snapshot).Error; err != nil {
+		return nil, fmt.Errorf("failed to save orderbook snapshot for %s: %w", ticker, err)
+	}
+
+	return &snapshot, nil
+}
+
+// LatestOrderbook returns the most recent snapshot captured for a ticker
+func LatestOrderbook(database *gorm.DB, ticker string) (*db.OrderbookSnapshot, error) {
+	var snapshot db.OrderbookSnapshot
+	if err := database.Where("ticker = ?", ticker).Order("captured_at DESC").First(&snapshot).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// takeableLevels returns the levels an order can trade against, best price first.
+// Buys lift the opposite side's bids (a NO bid at p is a YES ask at 100-p);
+// sells hit the bids on their own side.
+func takeableLevels(snapshot *db.OrderbookSnapshot, side, action string) ([]level, error) {
+	var yesBids, noBids [][2]int
+	if err := json.Unmarshal([]byte(snapshot.Yes), &yesBids); err != nil {
+		return nil, fmt.Errorf("invalid yes levels in snapshot %d: %w", snapshot.ID, err)
+	}
+	if err := json.Unmarshal([]byte(snapshot.No), &noBids); err != nil {
+		return nil, fmt.Errorf("invalid no levels in snapshot %d: %w", snapshot.ID, err)
+	}
+
+	sameSide, otherSide := yesBids, noBids
+	if side == "no" {
+		sameSide, otherSide = noBids, yesBids
+	}
+
+	var levels []level
+	if action == "buy" {
+		for _, l := range otherSide {
+			levels = append(levels, level{Price: 100 - l[0], Quantity: l[1]})
+		}
+		sort.Slice(levels, func(i, j int) bool { return levels[i].Price < levels[j].Price })
+	} else {
+		for _, l := range sameSide {
+			levels = append(levels, level{Price: l[0], Quantity: l[1]})
+		}
+		sort.Slice(levels, func(i, j int) bool { return levels[i].Price > levels[j].Price })
+	}
+
+	return levels, nil
+}
@@ -0,0 +1,77 @@
+package trader
+
+import (
+	"reflect"
+	"testing"
+
+	"backend/internal/db"
+)
+
+func TestTakeableLevels(t *testing.T) {
+	// YES bids at 40¢ and 42¢, NO bids at 55¢ and 57¢: YES asks are 43¢ and 45¢
+	snapshot := &db.OrderbookSnapshot{
+		Yes: "[[40, 10], [42, 5]]",
+		No:  "[[55, 20], [57, 3]]",
+	}
+
+	tests := []struct {
+		name   string
+		side   string
+		action string
+		want   []level
+	}{
+		{"buy yes lifts no bids", "yes", "buy", []level{{43, 3}, {45, 20}}},
+		{"buy no lifts yes bids", "no", "buy", []level{{58, 5}, {60, 10}}},
+		{"sell yes hits yes bids", "yes", "sell", []level{{42, 5}, {40, 10}}},
+		{"sell no hits no bids", "no", "sell", []level{{57, 3}, {55, 20}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := takeableLevels(snapshot, tt.side, tt.action)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("levels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakeableLevelsEdges(t *testing.T) {
+	empty := &db.OrderbookSnapshot{Yes: "[]", No: "null"}
+	levels, err := takeableLevels(empty, "yes", "buy")
+	if err != nil || len(levels) != 0 {
+		t.Errorf("empty book: levels = %v, err = %v", levels, err)
+	}
+
+	for _, snapshot := range []*db.OrderbookSnapshot{
+		{Yes: "not json", No: "[]"},
+		{Yes: "[]", No: "{"},
+	} {
+		if _, err := takeableLevels(snapshot, "yes", "buy"); err == nil {
+			t.Errorf("snapshot %+v: expected an error", snapshot)
+		}
+	}
+}
+
+func TestCrosses(t *testing.T) {
+	tests := []struct {
+		action string
+		level  int
+		limit  int
+		want   bool
+	}{
+		{"buy", 40, 45, true},
+		{"buy", 45, 45, true},
+		{"buy", 46, 45, false},
+		{"sell", 50, 45, true},
+		{"sell", 45, 45, true},
+		{"sell", 44, 45, false},
+	}
+	for _, tt := range tests {
+		if got := crosses(tt.action, tt.level, tt.limit); got != tt.want {
+			t.Errorf("crosses(%s, level %d, limit %d) = %v, want %v", tt.action, tt.level, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/fees"
+	"backend/internal/kalshi"
+
+	"gorm.io/gorm"
+)
+
+// PaperExchange is a simulated matching engine that fills orders against captured
+// orderbook snapshots and books the results into the paper ledger
+type PaperExchange struct {
+	DB      *gorm.DB
+	KClient *kalshi.Client // Optional: refreshes the book after the simulated latency
+	// Latency is the simulated delay between submitting an order and it reaching the book
+	Latency time.Duration
+	// FillRatio is the share of each displayed level we assume we can take (0-1].
+	// Values below 1 model queue competition and produce partial fills.
+	FillRatio float64
+	Fees      *fees.Model
+}
+
+// NewPaperExchange creates a paper matching engine
+func NewPaperExchange(database *gorm.DB, kClient *kalshi.Client, latency time.Duration, fillRatio float64) *PaperExchange {
+	if fillRatio <= 0 || fillRatio > 1 {
+		fillRatio = 1
+	}
+	return &PaperExchange{
+		DB:        database,
+		KClient:   kClient,
+		Latency:   latency,
+		FillRatio: fillRatio,
+		Fees:      fees.NewModel(),
+	}
+}
+
+func (p *PaperExchange) Ledger() Ledger {
+	return PaperLedger
+}
+
+// PlaceOrder simulates an immediate-or-cancel limit order
+func (p *PaperExchange) PlaceOrder(req OrderRequest) (*OrderResult, error) {
+	// 1. Record the order
+	order := db.Order{
+		OpportunityID: req.OpportunityID,
+		ClientOrderID: newClientOrderID(),
+		Ticker:        req.Ticker,
+		Side:          req.Side,
+		Action:        req.Action,
+		Price:         req.Price,
+		Count:         req.Count,
+		Status:        "pending",
+	}
+	if err := p.DB.Table(PaperLedger.Orders).Create(&order).Error; err != nil {
+		return nil, fmt.Errorf("failed to record paper order: %w", err)
+	}
+
+	// 2. Simulate the trip to the exchange
+	time.Sleep(p.Latency)
+
+	// 3. Load the book as it looks when the order arrives
+	snapshot, err := p.currentBook(req.Ticker)
+	if err != nil {
+		p.failOrder(&order, err)
+		return nil, err
+	}
+
+	levels, err := takeableLevels(snapshot, req.Side, req.Action)
+	if err != nil {
+		p.failOrder(&order, err)
+		return nil, err
+	}
+
+	// 4. Match and book, canceling any remainder. Crossing orders always take liquidity.
+	now := time.Now()
+	orderFees := p.Fees.NewOrder(feeMarket(p.DB, req.Ticker), fees.Taker)
+	err = p.DB.Transaction(func(tx *gorm.DB) error {
+		remaining := req.Count
+		for i, l := range levels {
+			if remaining == 0 || !crosses(req.Action, l.Price, req.Price) {
+				break
+			}
+
+			available := int(math.Floor(float64(l.Quantity) * p.FillRatio))
+			qty := min(remaining, available)
+			if qty <= 0 {
+				continue
+			}
+
+			fill := db.Fill{
+				OrderID:    order.ID,
+				ExternalID: fmt.Sprintf("paper-%d-%d", order.ID, i),
+				Ticker:     req.Ticker,
+				Side:       req.Side,
+				Action:     req.Action,
+				Price:      l.Price,
+				Count:      qty,
+				IsTaker:    true,
+				Fee:        orderFees.Add(qty, l.Price),
+				FilledAt:   now,
+			}
+			if err := PaperLedger.BookFill(tx, fill); err != nil {
+				return err
+			}
+			remaining -= qty
+		}
+
+		order.FilledCount = req.Count - remaining
+		order.Status = "canceled"
+		if remaining == 0 {
+			order.Status = "executed"
+		}
+		return tx.Table(PaperLedger.Orders).Save(&order).Error
+	})
+	if err != nil {
+		p.failOrder(&order, err)
+		return nil, fmt.Errorf("failed to book paper fills: %w", err)
+	}
+
+	log.Printf("[Paper] %s %d %s %s @ %d¢: filled %d (snapshot %d)",
+		req.Action, req.Count, req.Side, req.Ticker, req.Price, order.FilledCount, snapshot.ID)
+
+	return &OrderResult{
+		OrderID:     order.ID,
+		Status:      order.Status,
+		FilledCount: order.FilledCount,
+	}, nil
+}
+
+// currentBook captures a fresh snapshot when a client is available, otherwise
+// falls back to the latest stored one (useful for offline replays)
+func (p *PaperExchange) currentBook(ticker string) (*db.OrderbookSnapshot, error) {
+	if p.KClient != nil {
+		snapshot, err := CaptureOrderbook(p.DB, p.KClient, ticker)
+		if err == nil {
+			return snapshot, nil
+		}
+		log.Printf("[Paper] Failed to refresh book for %s, using latest snapshot: %v", ticker, err)
+	}
+
+	snapshot, err := LatestOrderbook(p.DB, ticker)
+	if err != nil {
+		return nil, fmt.Errorf("no orderbook snapshot for %s: %w", ticker, err)
+	}
+	return snapshot, nil
+}
+
+func (p *PaperExchange) failOrder(order *db.Order, cause error) {
+	order.Status = "failed"
+	order.Error = cause.Error()
+	if err := p.DB.Table(PaperLedger.Orders).Save(order).Error; err != nil {
+		log.Printf("[Paper] Failed to mark order %d as failed: %v", order.ID, err)
+	}
+}
+
+// crosses reports whether a level's price is acceptable for an order's limit
+func crosses(action string, levelPrice, limit int) bool {
+	if action == "buy" {
+		return levelPrice <= limit
+	}
+	return levelPrice >= limit
+}
@@ -0,0 +1,145 @@
+package trader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/fees"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	sqlite_vec.Auto()
+	os.Exit(m.Run())
+}
+
+// newTestDB migrates a throwaway SQLite database
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "trader.db"))
+	database, err := db.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.MigrateUp(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestPaperExchangeFillRatio(t *testing.T) {
+	// YES asks: 10 at 43¢, 20 at 45¢, 50 at 48¢
+	book := db.OrderbookSnapshot{
+		Ticker:     "KXTEST-26-T1",
+		Yes:        "[[30, 100]]",
+		No:         "[[52, 50], [55, 20], [57, 10]]",
+		CapturedAt: time.Now(),
+	}
+
+	tests := []struct {
+		name       string
+		fillRatio  float64
+		count      int
+		limit      int
+		wantStatus string
+		wantFills  []int // Contracts per fill, best price first
+	}{
+		{"full book fills across levels", 1, 25, 45, "executed", []int{10, 15}},
+		{"limit stops at its price", 1, 40, 45, "canceled", []int{10, 20}},
+		{"half of each level", 0.5, 40, 48, "executed", []int{5, 10, 25}},
+		{"ratio rounds down per level", 0.25, 40, 48, "canceled", []int{2, 5, 12}},
+		{"nothing at the limit", 1, 5, 40, "canceled", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newTestDB(t)
+			snapshot := book
+			if err := database.Create(&snapshot).Error; err != nil {
+				t.Fatal(err)
+			}
+			exchange := NewPaperExchange(database, nil, 0, tt.fillRatio)
+			exchange.Fees = &fees.Model{Default: fees.Schedule{}, Now: time.Now}
+
+			result, err := exchange.PlaceOrder(OrderRequest{Ticker: book.Ticker, Side: "yes", Action: "buy", Price: tt.limit, Count: tt.count})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantFilled := 0
+			for _, n := range tt.wantFills {
+				wantFilled += n
+			}
+			if result.Status != tt.wantStatus || result.FilledCount != wantFilled {
+				t.Errorf("status %s filled %d, want %s filled %d", result.Status, result.FilledCount, tt.wantStatus, wantFilled)
+			}
+
+			var fills []db.Fill
+			database.Table(PaperLedger.Fills).Order("price").Find(&fills)
+			if len(fills) != len(tt.wantFills) {
+				t.Fatalf("%d fills, want %d", len(fills), len(tt.wantFills))
+			}
+			cost := 0
+			for i, f := range fills {
+				if f.Count != tt.wantFills[i] {
+					t.Errorf("fill %d: %d contracts at %d¢, want %d", i, f.Count, f.Price, tt.wantFills[i])
+				}
+				cost += f.Count * f.Price
+			}
+
+			// The position holds everything filled at its average price
+			var pos db.Position
+			database.Table(PaperLedger.Positions).Where("ticker = ? AND side = ?", book.Ticker, "yes").Limit(1).Find(&pos)
+			if pos.Count != wantFilled {
+				t.Errorf("position holds %d, want %d", pos.Count, wantFilled)
+			}
+			if wantFilled > 0 && pos.AvgPrice != float64(cost)/float64(wantFilled) {
+				t.Errorf("average price %.2f, want %.2f", pos.AvgPrice, float64(cost)/float64(wantFilled))
+			}
+
+			// Nothing lands in the live ledger
+			var live int64
+			database.Table(LiveLedger.Orders).Count(&live)
+			if live != 0 {
+				t.Errorf("%d live orders recorded", live)
+			}
+		})
+	}
+}
+
+func TestPaperExchangeFees(t *testing.T) {
+	database := newTestDB(t)
+	database.Create(&db.OrderbookSnapshot{Ticker: "KXTEST-26-T1", Yes: "[]", No: "[[50, 5], [49, 5]]", CapturedAt: time.Now()})
+	exchange := NewPaperExchange(database, nil, 0, 1)
+	exchange.Fees = &fees.Model{Default: fees.DefaultSchedule, Now: time.Now}
+
+	if _, err := exchange.PlaceOrder(OrderRequest{Ticker: "KXTEST-26-T1", Side: "yes", Action: "buy", Price: 51, Count: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// One order across two fills is rounded once: 0.07 × 10 × ~0.25 = 17.5¢ -> 18¢
+	var pos db.Position
+	database.Table(PaperLedger.Positions).Limit(1).Find(&pos)
+	if pos.FeesPaid != 18 || pos.RealizedPnL != -18 {
+		t.Errorf("fees %d¢, realized %.0f¢, want 18¢ and -18¢", pos.FeesPaid, pos.RealizedPnL)
+	}
+}
+
+func TestPaperExchangeWithoutBook(t *testing.T) {
+	database := newTestDB(t)
+	exchange := NewPaperExchange(database, nil, 0, 1)
+
+	if _, err := exchange.PlaceOrder(OrderRequest{Ticker: "KXNONE", Side: "yes", Action: "buy", Price: 50, Count: 1}); err == nil {
+		t.Fatal("expected an error without a snapshot")
+	}
+	var order db.Order
+	database.Table(PaperLedger.Orders).Limit(1).Find(&order)
+	if order.Status != "failed" || order.Error == "" {
+		t.Errorf("order status %s error %q, want failed with an error", order.Status, order.Error)
+	}
+}
@@ -0,0 +1,480 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/fees"
+	"backend/internal/kalshi"
+	kalshiTypes "backend/internal/kalshi/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultReconcileInterval = time.Minute
+	// DefaultReconcileLookback is how far back exchange orders and fills are compared
+	DefaultReconcileLookback = 24 * time.Hour
+	// DefaultStuckAfter is how long an order or opportunity may stay pending before it is repaired
+	DefaultStuckAfter = 2 * time.Minute
+)
+
+// Reconciler repairs the ledger after crashes and dropped responses. Live orders are
+// compared against what the exchange reports; paper orders against the paper fill log.
+//
+// Exchange orders we have no record of are orphans. Those tagged with ClientOrderPrefix
+// were submitted by the trader, so any left resting are canceled. Orders placed on the
+// account by hand are left alone. Either way their fills are adopted into the ledger.
+type Reconciler struct {
+	DB         *gorm.DB
+	KClient    *kalshi.Client
+	Fees       *fees.Model
+	Ledger     Ledger
+	Interval   time.Duration
+	Lookback   time.Duration
+	StuckAfter time.Duration
+}
+
+// ReconcileStats counts the repairs made by a reconciliation pass
+type ReconcileStats struct {
+	OrdersUpdated         int
+	OrdersFailed          int
+	OrphansCanceled       int
+	OrphansAdopted        int
+	FillsBooked           int
+	OpportunitiesRepaired int
+}
+
+// NewReconciler creates a reconciler for the given ledger. kClient is only
+// needed to reconcile the live ledger.
+func NewReconciler(database *gorm.DB, kClient *kalshi.Client, ledger Ledger) *Reconciler {
+	return &Reconciler{
+		DB:         database,
+		KClient:    kClient,
+		Fees:       fees.NewModel(),
+		Ledger:     ledger,
+		Interval:   DefaultReconcileInterval,
+		Lookback:   DefaultReconcileLookback,
+		StuckAfter: DefaultStuckAfter,
+	}
+}
+
+// Run reconciles on startup and then on every interval until the context is canceled
+func (r *Reconciler) Run(ctx context.Context) {
+	r.Reconcile()
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Reconcile()
+		}
+	}
+}
+
+// Reconcile runs a single reconciliation pass
+func (r *Reconciler) Reconcile() ReconcileStats {
+	var stats ReconcileStats
+
+	var err error
+	if r.Ledger == PaperLedger {
+		err = r.reconcilePaperOrders(&stats)
+	} else if r.KClient != nil {
+		err = r.reconcileOrders(&stats)
+	}
+	if err != nil {
+		log.Printf("Order reconciliation failed: %v", err)
+	}
+	r.repairOpportunities(&stats)
+
+	if stats != (ReconcileStats{}) {
+		log.Printf("Reconciliation: %d orders updated, %d failed, %d orphans canceled, %d adopted, %d fills backfilled, %d opportunities repaired",
+			stats.OrdersUpdated, stats.OrdersFailed, stats.OrphansCanceled, stats.OrphansAdopted, stats.FillsBooked, stats.OpportunitiesRepaired)
+	}
+	return stats
+}
+
+// reconcileOrders brings local orders and fills in line with the exchange
+func (r *Reconciler) reconcileOrders(stats *ReconcileStats) error {
+	now := time.Now()
+
+	// 1. Load our orders in the window, plus any still open however old they are
+	since := now.Add(-r.Lookback)
+	var oldestOpen db.Order
+	r.DB.Table(r.Ledger.Orders).Where("status IN ?", []string{"pending", "resting"}).
+		Order("created_at ASC").Limit(1).Find(&oldestOpen)
+	if oldestOpen.ID != 0 && oldestOpen.CreatedAt.Before(since) {
+		since = oldestOpen.CreatedAt
+	}
+	since = since.Add(-time.Minute) // Allow for clock skew with the exchange
+
+	var local []db.Order
+	if err := r.DB.Table(r.Ledger.Orders).
+		Where("created_at >= ? OR status IN ?", since, []string{"pending", "resting"}).
+		Find(&local).Error; err != nil {
+		return fmt.Errorf("failed to load local orders: %w", err)
+	}
+
+	byClientID := make(map[string]*db.Order, len(local))
+	byExternalID := make(map[string]*db.Order, len(local))
+	for i := range local {
+		byClientID[local[i].ClientOrderID] = &local[i]
+		if local[i].ExternalID != "" {
+			byExternalID[local[i].ExternalID] = &local[i]
+		}
+	}
+
+	// 2. Fetch the exchange's view of the same window
+	remote, err := r.fetchOrders(since.Unix())
+	if err != nil {
+		return err
+	}
+	fills, err := r.fetchFills(since.Unix())
+	if err != nil {
+		return err
+	}
+	fillsByOrder := make(map[string][]kalshiTypes.Fill)
+	for _, f := range fills {
+		fillsByOrder[f.OrderID] = append(fillsByOrder[f.OrderID], f)
+	}
+
+	// 3. Match every exchange order to our record of it
+	seen := make(map[uint]bool)
+	for _, ro := range remote {
+		order, known := byClientID[ro.ClientOrderID]
+		if !known {
+			// Adopted orders placed by hand may carry no client order ID
+			order, known = byExternalID[ro.OrderID]
+		}
+		if !known {
+			order = r.adoptOrphan(ro, stats)
+			if order == nil {
+				continue
+			}
+		} else {
+			seen[order.ID] = true
+			// We only submit immediate-or-cancel orders, so anything of ours resting was left behind
+			if ro.Status == "resting" && isTraderOrder(ro) {
+				if canceled := r.cancel(ro); canceled != nil {
+					ro = *canceled
+				}
+			}
+			if r.syncOrder(order, ro) {
+				stats.OrdersUpdated++
+			}
+		}
+
+		// 4. Backfill fills the exchange has and we don't
+		booked := r.countFills(order.ID)
+		if err := bookOrderFills(r.DB, r.Fees, r.Ledger, *order, fillsByOrder[ro.OrderID]); err != nil {
+			log.Printf("Failed to backfill fills for order %s: %v", ro.OrderID, err)
+			continue
+		}
+		stats.FillsBooked += int(r.countFills(order.ID) - booked)
+	}
+
+	// 5. Orders the exchange never saw were lost before or during submission
+	for i := range local {
+		order := &local[i]
+		if seen[order.ID] || order.Status != "pending" || now.Sub(order.CreatedAt) < r.StuckAfter {
+			continue
+		}
+		if err := r.DB.Table(r.Ledger.Orders).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"status": "failed",
+			"error":  "reconciler: order not found on exchange",
+		}).Error; err != nil {
+			log.Printf("Failed to mark order %d failed: %v", order.ID, err)
+			continue
+		}
+		log.Printf("Order %d (%s %s) never reached the exchange, marked failed", order.ID, order.Side, order.Ticker)
+		stats.OrdersFailed++
+	}
+
+	return nil
+}
+
+// adoptOrphan records an exchange order we have no record of so its fills land in the
+// ledger. Orders the trader submitted are canceled if resting and only kept if they
+// traded; orders placed by hand are never canceled and always tracked.
+func (r *Reconciler) adoptOrphan(ro kalshiTypes.Order, stats *ReconcileStats) *db.Order {
+	ours := isTraderOrder(ro)
+	if ours && ro.Status == "resting" {
+		if canceled := r.cancel(ro); canceled != nil {
+			ro = *canceled
+			stats.OrphansCanceled++
+		}
+	}
+	if ours && ro.FillCount == 0 {
+		return nil
+	}
+
+	// Orphans can't be tied to an opportunity, but their positions are still ours
+	order := db.Order{
+		ClientOrderID: ro.ClientOrderID,
+		ExternalID:    ro.OrderID,
+		Ticker:        ro.Ticker,
+		Side:          ro.Side,
+		Action:        ro.Action,
+		Price:         ro.YesPrice,
+		Count:         ro.InitialCount,
+		FilledCount:   ro.FillCount,
+		Status:        ro.Status,
+		Error:         "reconciler: adopted orphaned exchange order",
+	}
+	if !ours {
+		order.Error = "reconciler: adopted exchange order placed outside the trader"
+	}
+	if ro.Side == "no" {
+		order.Price = ro.NoPrice
+	}
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = "orphan-" + ro.OrderID
+	}
+
+	if err := r.DB.Table(r.Ledger.Orders).Create(&order).Error; err != nil {
+		log.Printf("Failed to adopt orphaned order %s: %v", ro.OrderID, err)
+		return nil
+	}
+	log.Printf("Adopted orphaned order %s (%s %s, %d filled, placed by trader: %t)", ro.OrderID, ro.Side, ro.Ticker, ro.FillCount, ours)
+	stats.OrphansAdopted++
+	return &order
+}
+
+// isTraderOrder reports whether an exchange order was submitted by the trader
+func isTraderOrder(ro kalshiTypes.Order) bool {
+	return strings.HasPrefix(ro.ClientOrderID, ClientOrderPrefix)
+}
+
+// syncOrder copies the exchange's status onto our order. Returns true if anything changed.
+func (r *Reconciler) syncOrder(order *db.Order, ro kalshiTypes.Order) bool {
+	if order.ExternalID == ro.OrderID && order.Status == ro.Status && order.FilledCount == ro.FillCount {
+		return false
+	}
+
+	if err := r.DB.Table(r.Ledger.Orders).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"external_id":  ro.OrderID,
+		"status":       ro.Status,
+		"filled_count": ro.FillCount,
+	}).Error; err != nil {
+		log.Printf("Failed to sync order %d: %v", order.ID, err)
+		return false
+	}
+
+	order.ExternalID = ro.OrderID
+	order.Status = ro.Status
+	order.FilledCount = ro.FillCount
+	return true
+}
+
+// reconcilePaperOrders repairs paper orders from the paper fill log. The matching engine
+// books an order's fills and final state together, so an order still pending StuckAfter
+// after submission was interrupted, and its fills, if any, decide how it ended.
+func (r *Reconciler) reconcilePaperOrders(stats *ReconcileStats) error {
+	now := time.Now()
+
+	// 1. Load our orders in the window, plus any still pending however old they are
+	var local []db.Order
+	if err := r.DB.Table(r.Ledger.Orders).
+		Where("created_at >= ? OR status = ?", now.Add(-r.Lookback), "pending").
+		Find(&local).Error; err != nil {
+		return fmt.Errorf("failed to load paper orders: %w", err)
+	}
+
+	for _, order := range local {
+		stuck := order.Status == "pending"
+		if stuck && now.Sub(order.CreatedAt) < r.StuckAfter {
+			continue // Still being matched
+		}
+
+		// 2. The fill log is the record of what traded
+		var filled int64
+		if err := r.DB.Table(r.Ledger.Fills).Where("order_id = ?", order.ID).
+			Select("COALESCE(SUM(count), 0)").Scan(&filled).Error; err != nil {
+			log.Printf("Failed to count fills for paper order %d: %v", order.ID, err)
+			continue
+		}
+		if !stuck && int(filled) == order.FilledCount {
+			continue
+		}
+
+		// 3. Immediate-or-cancel orders end executed, canceled with a partial fill, or failed
+		updates := map[string]interface{}{"filled_count": filled}
+		switch {
+		case int(filled) >= order.Count:
+			updates["status"] = "executed"
+		case filled > 0 || !stuck:
+			updates["status"] = "canceled"
+		default:
+			updates["status"] = "failed"
+			updates["error"] = "reconciler: order never finished matching"
+		}
+		if err := r.DB.Table(r.Ledger.Orders).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
+			log.Printf("Failed to repair paper order %d: %v", order.ID, err)
+			continue
+		}
+
+		if updates["status"] == "failed" {
+			log.Printf("Paper order %d (%s %s) never finished matching, marked failed", order.ID, order.Side, order.Ticker)
+			stats.OrdersFailed++
+		} else {
+			log.Printf("Paper order %d (%s %s) repaired from the fill log: %d filled", order.ID, order.Side, order.Ticker, filled)
+			stats.OrdersUpdated++
+		}
+	}
+	return nil
+}
+
+// cancel cancels a resting exchange order, returning its final state
+func (r *Reconciler) cancel(ro kalshiTypes.Order) *kalshiTypes.Order {
+	canceled, err := r.KClient.CancelOrder(ro.OrderID)
+	if err != nil {
+		log.Printf("Failed to cancel resting order %s: %v", ro.OrderID, err)
+		return nil
+	}
+	log.Printf("Canceled resting order %s (%s %s)", ro.OrderID, ro.Side, ro.Ticker)
+	return canceled
+}
+
+func (r *Reconciler) countFills(orderID uint) int64 {
+	var count int64
+	r.DB.Table(r.Ledger.Fills).Where("order_id = ?", orderID).Count(&count)
+	return count
+}
+
+// repairOpportunities resolves opportunities left pending by a trader that stopped mid-execution.
+// Claims happen before expiry, so one still pending StuckAfter past its expiry is abandoned.
+func (r *Reconciler) repairOpportunities(stats *ReconcileStats) {
+	var opportunities []db.ArbitrageOpportunity
+	if err := r.DB.Preload("Legs").
+		Where("status = ? AND expires_at <= ?", "pending", time.Now().Add(-r.StuckAfter)).
+		Find(&opportunities).Error; err != nil {
+		log.Printf("Failed to fetch stuck opportunities: %v", err)
+		return
+	}
+
+	for _, opp := range opportunities {
+		var open int64
+		r.DB.Table(r.Ledger.Orders).Where("opportunity_id = ? AND status = ?", opp.ID, "pending").Count(&open)
+		if open > 0 {
+			continue // Wait for the order pass to resolve them
+		}
+
+		filledLegs, touchedLegs := 0, 0
+		for _, leg := range opp.Legs {
+			var filled int64
+			r.DB.Table(r.Ledger.Orders).
+				Where("opportunity_id = ? AND ticker = ? AND side = ?", opp.ID, leg.Ticker, leg.Side).
+				Select("COALESCE(SUM(filled_count), 0)").Scan(&filled)
+			if filled > 0 {
+				touchedLegs++
+			}
+			if int(filled) >= leg.Count {
+				filledLegs++
+			}
+		}
+
+		status := opportunityStatus(len(opp.Legs), filledLegs, touchedLegs)
+		if err := r.DB.Model(&db.ArbitrageOpportunity{}).
+			Where("id = ? AND status = ?", opp.ID, "pending").
+			Update("status", status).Error; err != nil {
+			log.Printf("Failed to repair opportunity %d: %v", opp.ID, err)
+			continue
+		}
+		log.Printf("Opportunity %d was stuck pending, marked %s (%d/%d legs fully filled)", opp.ID, status, filledLegs, len(opp.Legs))
+		stats.OpportunitiesRepaired++
+	}
+}
+
+func (r *Reconciler) fetchOrders(minTs int64) ([]kalshiTypes.Order, error) {
+	var orders []kalshiTypes.Order
+	cursor := ""
+	for {
+		resp, err := r.KClient.GetOrders("", 200, cursor, minTs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch exchange orders: %w", err)
+		}
+		orders = append(orders, resp.Orders...)
+
+		if resp.Cursor == "" {
+			return orders, nil
+		}
+		cursor = resp.Cursor
+	}
+}
+
+func (r *Reconciler) fetchFills(minTs int64) ([]kalshiTypes.Fill, error) {
+	var fills []kalshiTypes.Fill
+	cursor := ""
+	for {
+		resp, err := r.KClient.GetFills("", "", 200, cursor, minTs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch exchange fills: %w", err)
+		}
+		fills = append(fills, resp.Fills...)
+
+		if resp.Cursor == "" {
+			return fills, nil
+		}
+		cursor = resp.Cursor
+	}
+}
+
+// bookOrderFills books every fill of an order that isn't in the ledger yet. Fills are
+// replayed oldest first so the order's rounded fee is attributed the same way every time.
+func bookOrderFills(database *gorm.DB, model *fees.Model, ledger Ledger, order db.Order, fills []kalshiTypes.Fill) error {
+	sort.SliceStable(fills, func(i, j int) bool {
+		return fills[i].CreatedTime.Before(fills[j].CreatedTime)
+	})
+
+	orderFees := newOrderFeeTracker(model, feeMarket(database, order.Ticker))
+	for _, f := range fills {
+		fee := orderFees.add(f.Count, fillPrice(f), f.IsTaker)
+		if err := BookKalshiFill(database, ledger, order.ID, f, fee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BookKalshiFill books an exchange fill and its fee into the ledger unless it has already been recorded
+func BookKalshiFill(database *gorm.DB, ledger Ledger, orderID uint, f kalshiTypes.Fill, fee int) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(ledger.Fills).Where("external_id = ?", f.FillID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		return ledger.BookFill(tx, db.Fill{
+			OrderID:    orderID,
+			ExternalID: f.FillID,
+			Ticker:     f.Ticker,
+			Side:       f.Side,
+			Action:     f.Action,
+			Price:      fillPrice(f),
+			Count:      f.Count,
+			IsTaker:    f.IsTaker,
+			Fee:        fee,
+			FilledAt:   f.CreatedTime,
+		})
+	})
+}
+
+// fillPrice returns the price paid for the side that was traded
+func fillPrice(f kalshiTypes.Fill) int {
+	if f.Side == "no" {
+		return f.NoPrice
+	}
+	return f.YesPrice
+}
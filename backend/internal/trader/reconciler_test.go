@@ -0,0 +1,220 @@
+package trader
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/kalshi"
+	kalshiTypes "backend/internal/kalshi/types"
+)
+
+func TestReconcilePaperOrders(t *testing.T) {
+	database := newTestDB(t)
+	old := time.Now().Add(-5 * time.Minute)
+
+	opp := db.ArbitrageOpportunity{StrategyType: "implication", Status: "pending", ExpiresAt: time.Now().Add(-10 * time.Minute),
+		Legs: []db.OpportunityLeg{{Ticker: "KXSTUCK", Side: "yes", Action: "buy", Price: 40, Count: 10}}}
+	database.Create(&opp)
+
+	orders := []struct {
+		order     db.Order
+		fills     []int // Contracts per fill in the paper fill log
+		wantState string
+		wantCount int
+	}{
+		{db.Order{OpportunityID: opp.ID, Ticker: "KXSTUCK", Status: "pending", CreatedAt: old}, nil, "failed", 0},
+		{db.Order{Ticker: "KXNEW", Status: "pending"}, nil, "pending", 0}, // Still matching
+		{db.Order{Ticker: "KXMISSED", Status: "canceled", CreatedAt: old}, []int{6, 4}, "executed", 10},
+		{db.Order{Ticker: "KXPARTIAL", Status: "pending", CreatedAt: old}, []int{4}, "canceled", 4},
+		{db.Order{Ticker: "KXDONE", Status: "executed", FilledCount: 10, CreatedAt: old}, []int{10}, "executed", 10},
+	}
+	for i := range orders {
+		o := &orders[i].order
+		o.ClientOrderID = newClientOrderID()
+		o.Side, o.Action, o.Price, o.Count = "yes", "buy", 40, 10
+		database.Table(PaperLedger.Orders).Create(o)
+		for j, count := range orders[i].fills {
+			database.Table(PaperLedger.Fills).Create(&db.Fill{OrderID: o.ID, ExternalID: fmt.Sprintf("paper-%d-%d", o.ID, j),
+				Ticker: o.Ticker, Side: "yes", Action: "buy", Price: 40, Count: count, IsTaker: true, FilledAt: old})
+		}
+	}
+
+	r := NewReconciler(database, nil, PaperLedger)
+	stats := r.Reconcile()
+	want := ReconcileStats{OrdersUpdated: 2, OrdersFailed: 1, OpportunitiesRepaired: 1}
+	if stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+
+	for _, tt := range orders {
+		var got db.Order
+		database.Table(PaperLedger.Orders).First(&got, tt.order.ID)
+		if got.Status != tt.wantState || got.FilledCount != tt.wantCount {
+			t.Errorf("%s: %s with %d filled, want %s with %d", tt.order.Ticker, got.Status, got.FilledCount, tt.wantState, tt.wantCount)
+		}
+	}
+
+	// The opportunity whose only order never finished matching is abandoned
+	database.First(&opp, opp.ID)
+	if opp.Status != "failed" {
+		t.Errorf("stuck opportunity is %s, want failed", opp.Status)
+	}
+
+	// A second pass finds nothing left to repair
+	if stats := r.Reconcile(); stats != (ReconcileStats{}) {
+		t.Errorf("second pass stats %+v, want none", stats)
+	}
+}
+
+// fakeKalshi serves the portfolio endpoints the reconciler reads and records cancellations
+type fakeKalshi struct {
+	mu       sync.Mutex
+	orders   []kalshiTypes.Order
+	fills    []kalshiTypes.Fill
+	canceled []string
+}
+
+func (f *fakeKalshi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/portfolio/orders/"):
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		for i := range f.orders {
+			if f.orders[i].OrderID == id {
+				f.canceled = append(f.canceled, id)
+				f.orders[i].Status = "canceled"
+				json.NewEncoder(w).Encode(map[string]any{"order": f.orders[i]})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case strings.HasSuffix(r.URL.Path, "/portfolio/orders"):
+		json.NewEncoder(w).Encode(map[string]any{"orders": f.orders})
+	case strings.HasSuffix(r.URL.Path, "/portfolio/fills"):
+		json.NewEncoder(w).Encode(map[string]any{"fills": f.fills})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeKalshiClient(t *testing.T, handler http.Handler) *kalshi.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kalshi.Client{
+		BaseURL:     srv.URL,
+		HTTPClient:  srv.Client(),
+		Credentials: kalshi.AuthCredentials{AccessKey: "test", PrivateKey: key},
+	}
+}
+
+func TestReconcileLiveOrders(t *testing.T) {
+	database := newTestDB(t)
+	old := time.Now().Add(-5 * time.Minute)
+
+	// 1. Our records: one order whose response was lost after it filled, one that
+	// never reached the exchange, and one just submitted
+	local := []db.Order{
+		{ClientOrderID: "arb-filled", Ticker: "KXA", Side: "yes", Action: "buy", Price: 41, Count: 10, Status: "pending", CreatedAt: old},
+		{ClientOrderID: "arb-lost", Ticker: "KXB", Side: "no", Action: "buy", Price: 55, Count: 10, Status: "pending", CreatedAt: old},
+		{ClientOrderID: "arb-new", Ticker: "KXC", Side: "yes", Action: "buy", Price: 20, Count: 10, Status: "pending"},
+	}
+	for i := range local {
+		database.Table(LiveLedger.Orders).Create(&local[i])
+	}
+
+	// 2. The exchange's records, including orphans we never recorded
+	exchange := &fakeKalshi{
+		orders: []kalshiTypes.Order{
+			{OrderID: "ex-filled", ClientOrderID: "arb-filled", Ticker: "KXA", Side: "yes", Action: "buy", Status: "executed", YesPrice: 41, InitialCount: 10, FillCount: 10, CreatedTime: old},
+			// Submitted by a trader that crashed before recording it
+			{OrderID: "ex-ours-resting", ClientOrderID: "arb-crashed", Ticker: "KXD", Side: "yes", Action: "buy", Status: "resting", YesPrice: 10, InitialCount: 5, RemainingCount: 5, CreatedTime: old},
+			{OrderID: "ex-ours-filled", ClientOrderID: "arb-crashed-2", Ticker: "KXE", Side: "no", Action: "buy", Status: "canceled", NoPrice: 60, InitialCount: 5, FillCount: 3, CreatedTime: old},
+			// Placed by hand on the same account
+			{OrderID: "ex-manual", Ticker: "KXF", Side: "yes", Action: "buy", Status: "resting", YesPrice: 30, InitialCount: 8, FillCount: 2, RemainingCount: 6, CreatedTime: old},
+		},
+		fills: []kalshiTypes.Fill{
+			{FillID: "f1", OrderID: "ex-filled", Ticker: "KXA", Side: "yes", Action: "buy", Count: 6, YesPrice: 40, IsTaker: true, CreatedTime: old},
+			{FillID: "f2", OrderID: "ex-filled", Ticker: "KXA", Side: "yes", Action: "buy", Count: 4, YesPrice: 41, IsTaker: true, CreatedTime: old.Add(time.Second)},
+			{FillID: "f3", OrderID: "ex-ours-filled", Ticker: "KXE", Side: "no", Action: "buy", Count: 3, NoPrice: 60, IsTaker: true, CreatedTime: old},
+			{FillID: "f4", OrderID: "ex-manual", Ticker: "KXF", Side: "yes", Action: "buy", Count: 2, YesPrice: 30, CreatedTime: old},
+		},
+	}
+
+	r := NewReconciler(database, newFakeKalshiClient(t, exchange), LiveLedger)
+	stats := r.Reconcile()
+	want := ReconcileStats{OrdersUpdated: 1, OrdersFailed: 1, OrphansCanceled: 1, OrphansAdopted: 2, FillsBooked: 4}
+	if stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+
+	// 3. Only the trader's own orphan is canceled; the manual order keeps resting
+	if len(exchange.canceled) != 1 || exchange.canceled[0] != "ex-ours-resting" {
+		t.Errorf("canceled %v, want only ex-ours-resting", exchange.canceled)
+	}
+
+	orders := map[string]db.Order{}
+	var rows []db.Order
+	database.Table(LiveLedger.Orders).Find(&rows)
+	for _, o := range rows {
+		orders[o.Ticker] = o
+	}
+	checks := []struct {
+		ticker     string
+		wantStatus string
+		wantFilled int
+		wantFills  int64
+	}{
+		{"KXA", "executed", 10, 2}, // Missed fills backfilled
+		{"KXB", "failed", 0, 0},    // Never reached the exchange
+		{"KXC", "pending", 0, 0},   // Too recent to call
+		{"KXE", "canceled", 3, 1},  // The trader's orphan adopted for its fills
+		{"KXF", "resting", 2, 1},   // Manual order tracked but left working
+	}
+	for _, c := range checks {
+		o, ok := orders[c.ticker]
+		if !ok {
+			t.Errorf("%s: no local order", c.ticker)
+			continue
+		}
+		var fills int64
+		database.Table(LiveLedger.Fills).Where("order_id = ?", o.ID).Count(&fills)
+		if o.Status != c.wantStatus || o.FilledCount != c.wantFilled || fills != c.wantFills {
+			t.Errorf("%s: %s with %d filled and %d fills booked, want %s with %d and %d",
+				c.ticker, o.Status, o.FilledCount, fills, c.wantStatus, c.wantFilled, c.wantFills)
+		}
+	}
+	if _, ok := orders["KXD"]; ok {
+		t.Errorf("adopted the canceled orphan that never traded")
+	}
+
+	var pos db.Position
+	database.Table(LiveLedger.Positions).Where("ticker = ?", "KXA").First(&pos)
+	if pos.Count != 10 || pos.AvgPrice != 40.4 {
+		t.Errorf("KXA position %d @ %.1f, want 10 @ 40.4", pos.Count, pos.AvgPrice)
+	}
+
+	// 4. A second pass recognizes everything it adopted and cancels nothing new
+	if stats := r.Reconcile(); stats != (ReconcileStats{}) {
+		t.Errorf("second pass stats %+v, want none", stats)
+	}
+	if len(exchange.canceled) != 1 {
+		t.Errorf("canceled %v on the second pass", exchange.canceled)
+	}
+}
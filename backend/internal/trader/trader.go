@@ -0,0 +1,136 @@
+package trader
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+const DefaultPollInterval = 10 * time.Second
+
+// Trader consumes detected opportunities and routes their legs to an Exchange
+type Trader struct {
+	DB           *gorm.DB
+	Exchange     Exchange
+	PollInterval time.Duration
+}
+
+// NewTrader creates a trader that executes opportunities on the given exchange
+func NewTrader(database *gorm.DB, exchange Exchange) *Trader {
+	return &Trader{
+		DB:           database,
+		Exchange:     exchange,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Run polls for new opportunities until the context is canceled
+func (t *Trader) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.ProcessOpportunities()
+		}
+	}
+}
+
+// ProcessOpportunities executes every detected opportunity that hasn't expired
+func (t *Trader) ProcessOpportunities() {
+	now := time.Now()
+
+	// 1. Expire stale detections so they are never traded on old quotes
+	if err := t.DB.Model(&db.ArbitrageOpportunity{}).
+		Where("status = ? AND expires_at <= ?", "detected", now).
+		Update("status", "ignored").Error; err != nil {
+		log.Printf("Failed to expire stale opportunities: %v", err)
+	}
+
+	// 2. Fetch live opportunities
+	var opportunities []db.ArbitrageOpportunity
+	if err := t.DB.Preload("Legs").
+		Where("status = ? AND expires_at > ?", "detected", now).
+		Order("expected_yield DESC").
+		Find(&opportunities).Error; err != nil {
+		log.Printf("Failed to fetch opportunities: %v", err)
+		return
+	}
+
+	for _, opp := range opportunities {
+		t.executeOpportunity(opp)
+	}
+}
+
+func (t *Trader) executeOpportunity(opp db.ArbitrageOpportunity) {
+	// 1. Claim the opportunity so no other trader instance executes it
+	res := t.DB.Model(&db.ArbitrageOpportunity{}).
+		Where("id = ? AND status = ?", opp.ID, "detected").
+		Update("status", "pending")
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+
+	if len(opp.Legs) == 0 {
+		log.Printf("Opportunity %d has no legs, ignoring", opp.ID)
+		t.setStatus(opp.ID, "ignored")
+		return
+	}
+
+	log.Printf("Executing opportunity %d (%s, yield %.2f%%) on %s ledger",
+		opp.ID, opp.StrategyType, opp.ExpectedYield*100, t.Exchange.Ledger().Orders)
+
+	// 2. Place each leg. The exchange records the book each leg is matched against.
+	filledLegs := 0
+	touchedLegs := 0
+	for _, leg := range opp.Legs {
+		result, err := t.Exchange.PlaceOrder(OrderRequest{
+			OpportunityID: opp.ID,
+			Ticker:        leg.Ticker,
+			Side:          leg.Side,
+			Action:        leg.Action,
+			Price:         leg.Price,
+			Count:         leg.Count,
+		})
+		if err != nil {
+			log.Printf("Failed to place leg %s %s for opportunity %d: %v", leg.Side, leg.Ticker, opp.ID, err)
+			continue
+		}
+		if result.FilledCount > 0 {
+			touchedLegs++
+		}
+		if result.FilledCount == leg.Count {
+			filledLegs++
+		}
+	}
+
+	// 3. Summarize
+	status := opportunityStatus(len(opp.Legs), filledLegs, touchedLegs)
+	t.setStatus(opp.ID, status)
+	log.Printf("Opportunity %d %s (%d/%d legs fully filled)", opp.ID, status, filledLegs, len(opp.Legs))
+}
+
+// opportunityStatus summarizes an execution from how many of its legs were fully and partially filled
+func opportunityStatus(legs, filledLegs, touchedLegs int) string {
+	switch {
+	case filledLegs == legs:
+		return "executed"
+	case touchedLegs == 0:
+		return "failed"
+	default:
+		return "partial"
+	}
+}
+
+func (t *Trader) setStatus(opportunityID uint, status string) {
+	if err := t.DB.Model(&db.ArbitrageOpportunity{}).Where("id = ?", opportunityID).Update("status", status).Error; err != nil {
+		log.Printf("Failed to set opportunity %d status to %s: %v", opportunityID, status, err)
+	}
+}
@@ -0,0 +1,70 @@
+package vectorstore
+
+import (
+	"sort"
+	"strings"
+)
+
+// rrfK damps the contribution of top ranks in reciprocal rank fusion.
+// 60 is the value from the original RRF paper and works well without tuning.
+const rrfK = 60
+
+// Hybrid combines semantic and keyword search with reciprocal rank fusion. Embeddings
+// capture meaning but miss exact tickers, names and numbers ("CPI 3.2%"), which BM25
+// matches directly, so each list contributes 1/(rrfK + rank) for every market it ranks.
+func Hybrid(store Store, vec []float32, query string, k int, filters Filters) ([]Result, error) {
+	// Fuse from deeper lists than we return so markets ranked moderately by both
+	// searches can overtake markets ranked highly by only one
+	depth := max(k*2, 20)
+
+	semantic, err := store.Search(vec, depth, filters)
+	if err != nil {
+		return nil, err
+	}
+	lexical, err := store.SearchText(query, depth, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return Fuse(k, semantic, lexical), nil
+}
+
+// Fuse merges ranked result lists by reciprocal rank fusion and returns the top k.
+// Scores are negated fused ranks so that, like every other Result, lower is better.
+func Fuse(k int, lists ...[]Result) []Result {
+	fused := make(map[uint]float64)
+	for _, list := range lists {
+		for rank, r := range list {
+			fused[r.ID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	results := make([]Result, 0, len(fused))
+	for id, score := range fused {
+		results = append(results, Result{ID: id, Score: -score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score < results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// ftsQuery turns free text into an FTS5 query that matches any of its words.
+// Each word is quoted as a phrase so punctuation can't be read as query syntax,
+// and FTS5 tokenizes it the same way as the indexed text: "3.2%" becomes the
+// phrase "3 2", which only matches the two numbers side by side.
+func ftsQuery(text string) string {
+	words := strings.Fields(text)
+	phrases := make([]string, 0, len(words))
+	for _, w := range words {
+		phrases = append(phrases, `"`+strings.ReplaceAll(w, `"`, `""`)+`"`)
+	}
+	return strings.Join(phrases, " OR ")
+}
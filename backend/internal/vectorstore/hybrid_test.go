@@ -0,0 +1,165 @@
+package vectorstore
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"backend/internal/db"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+)
+
+func TestMain(m *testing.M) {
+	sqlite_vec.Auto()
+	os.Exit(m.Run())
+}
+
+// ranked is a result list with the given market IDs in rank order
+func ranked(ids ...uint) []Result {
+	results := make([]Result, len(ids))
+	for i, id := range ids {
+		results[i] = Result{ID: id, Score: float64(i)}
+	}
+	return results
+}
+
+func TestFuse(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     int
+		lists [][]Result
+		want  []uint
+	}{
+		{"one list keeps its order", 10, [][]Result{ranked(3, 1, 2)}, []uint{3, 1, 2}},
+		{"disjoint lists interleave, ties by ID", 10, [][]Result{ranked(4, 2), ranked(3, 1)}, []uint{3, 4, 1, 2}},
+		{"overlap beats a single top rank", 10, [][]Result{ranked(1, 2), ranked(3, 2)}, []uint{2, 1, 3}},
+		// 1/61 + 1/63 edges out 1/62 + 1/62
+		{"overlapping lists", 10, [][]Result{ranked(1, 2, 3), ranked(3, 2, 5)}, []uint{3, 2, 1, 5}},
+		// With rrfK = 60 fifth in both lists is worth nearly twice first in one
+		{"top ranks are damped", 10, [][]Result{ranked(1, 6, 7, 8, 2), ranked(9, 10, 11, 12, 2)}, []uint{2, 1, 9, 6, 10, 7, 11, 8, 12}},
+		{"top k", 2, [][]Result{ranked(1, 2, 3), ranked(3, 2, 5)}, []uint{3, 2}},
+		{"fewer than k", 5, [][]Result{ranked(1), ranked(1)}, []uint{1}},
+		{"empty lists", 5, [][]Result{nil, ranked()}, []uint{}},
+		{"one list empty", 5, [][]Result{nil, ranked(2, 1)}, []uint{2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := Fuse(tt.k, tt.lists...)
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %v, want IDs %v", results, tt.want)
+			}
+			for i, r := range results {
+				if r.ID != tt.want[i] {
+					t.Fatalf("got %v, want IDs %v", results, tt.want)
+				}
+				if i > 0 && r.Score < results[i-1].Score {
+					t.Errorf("scores out of order: %v", results)
+				}
+			}
+		})
+	}
+}
+
+func TestFuseScores(t *testing.T) {
+	results := Fuse(10, ranked(1, 2), ranked(2))
+	want := map[uint]float64{
+		1: -1.0 / (rrfK + 1),
+		2: -1.0/(rrfK+2) - 1.0/(rrfK+1),
+	}
+	for _, r := range results {
+		if math.Abs(r.Score-want[r.ID]) > 1e-12 {
+			t.Errorf("market %d scored %v, want %v", r.ID, r.Score, want[r.ID])
+		}
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"fed", `"fed"`},
+		{"fed  rate\tcut", `"fed" OR "rate" OR "cut"`},
+		{"CPI 3.2%", `"CPI" OR "3.2%"`},
+		{`say "hello"`, `"say" OR """hello"""`},
+		{`"`, `""""`},
+		{`a"b`, `"a""b"`},
+		// Operators and syntax are searched for as words
+		{"fed AND rates", `"fed" OR "AND" OR "rates"`},
+		{"NOT OR NEAR", `"NOT" OR "OR" OR "NEAR"`},
+		{"rate*", `"rate*"`},
+		{"title:fed", `"title:fed"`},
+		{"-fed +cut ^rate", `"-fed" OR "+cut" OR "^rate"`},
+		{"(fed) {cut}", `"(fed)" OR "{cut}"`},
+		{"NEAR(fed cut)", `"NEAR(fed" OR "cut)"`},
+	}
+	for _, tt := range tests {
+		if got := ftsQuery(tt.text); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTextEscaping(t *testing.T) {
+	database := newTestDB(t)
+	provider := db.Provider{Name: "kalshi"}
+	database.Create(&provider)
+	markets := []db.Market{
+		{ProviderID: provider.ID, ExternalID: "KXCPI-26MAY-T3.2", Ticker: "KXCPI-26MAY-T3.2", Title: `Will CPI be above 3.2% in "May"?`},
+		{ProviderID: provider.ID, ExternalID: "KXFED-26DEC-T4.00", Ticker: "KXFED-26DEC-T4.00", Title: "Will the Fed cut rates and not hike?"},
+	}
+	for i := range markets {
+		if err := database.Create(&markets[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	cpi, fed := markets[0].ID, markets[1].ID
+	store := New(database)
+
+	tests := []struct {
+		query string
+		want  []uint // Matching markets in any order
+	}{
+		{"", nil},
+		{"3.2%", []uint{cpi}},
+		{"3.2", []uint{cpi}},
+		{`"May"`, []uint{cpi}},
+		{`"`, nil},
+		{`May"?`, []uint{cpi}},
+		{"AND", []uint{fed}},
+		{"not", []uint{fed}},
+		{"NOT", []uint{fed}},
+		{"OR NEAR", nil},
+		{"cpi*", []uint{cpi}},
+		{"title:cpi", nil}, // The phrase "title cpi", not a column filter
+		{"-fed", []uint{fed}},
+		{"^fed", []uint{fed}},
+		{"(fed", []uint{fed}},
+		{"NEAR(cpi fed)", []uint{fed}}, // The phrase "near cpi", and "fed"
+		{"cpi fed", []uint{cpi, fed}},
+	}
+	for _, tt := range tests {
+		results, err := store.SearchText(tt.query, 10, Filters{})
+		if err != nil {
+			t.Errorf("SearchText(%q): %v", tt.query, err)
+			continue
+		}
+		got := map[uint]bool{}
+		for _, r := range results {
+			got[r.ID] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("SearchText(%q) = %v, want markets %v", tt.query, results, tt.want)
+			continue
+		}
+		for _, id := range tt.want {
+			if !got[id] {
+				t.Errorf("SearchText(%q) = %v, want markets %v", tt.query, results, tt.want)
+			}
+		}
+	}
+}
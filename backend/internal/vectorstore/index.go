@@ -0,0 +1,139 @@
+package vectorstore
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// Embedding index statuses. An index is built in the background while the active
+// one keeps serving searches, then swapped in; the one it replaces is retired.
+const (
+	IndexBuilding = "building"
+	IndexActive   = "active"
+	IndexRetired  = "retired"
+)
+
+// ActiveIndex returns the embedding index searches are served from
+func ActiveIndex(database *gorm.DB) (db.EmbeddingIndex, error) {
+	var index db.EmbeddingIndex
+	if err := database.Where("status = ?", IndexActive).First(&index).Error; err != nil {
+		return index, fmt.Errorf("failed to load active embedding index: %w", err)
+	}
+	return index, nil
+}
+
+// LiveIndexes returns the active index and any being built, which both need
+// to be kept up to date as markets change
+func LiveIndexes(database *gorm.DB) ([]db.EmbeddingIndex, error) {
+	var indexes []db.EmbeddingIndex
+	err := database.Where("status IN ?", []string{IndexActive, IndexBuilding}).Order("id").Find(&indexes).Error
+	return indexes, err
+}
+
+// SameModel reports whether vectors from the given model can be compared with the index's
+func SameModel(index db.EmbeddingIndex, model string, dimension int) bool {
+	return index.Model == model && index.Dimension == dimension
+}
+
+// Compatible reports whether the store's index can be searched with vectors from the given model
+func Compatible(store Store, model string, dimension int) bool {
+	index, err := store.Index()
+	return err == nil && SameModel(index, model, dimension)
+}
+
+// BeginIndex returns the index being built for this model and template, creating it
+// and its empty vector table if there is none, so an interrupted build resumes.
+// Builds for any other configuration are abandoned.
+func BeginIndex(database *gorm.DB, model string, dimension, templateVersion int) (db.EmbeddingIndex, error) {
+	var index db.EmbeddingIndex
+	if err := database.Where("status = ? AND model = ? AND dimension = ? AND template_version = ?",
+		IndexBuilding, model, dimension, templateVersion).Limit(1).Find(&index).Error; err != nil {
+		return index, fmt.Errorf("failed to look up embedding index: %w", err)
+	}
+	if index.ID != 0 {
+		return index, nil
+	}
+
+	// 1. Abandon builds for other configurations
+	if err := database.Model(&db.EmbeddingIndex{}).Where("status = ?", IndexBuilding).
+		Update("status", IndexRetired).Error; err != nil {
+		return index, fmt.Errorf("failed to abandon stale index builds: %w", err)
+	}
+	dropRetired(database)
+
+	// 2. Register the index and create its table, named after its ID
+	err := database.Transaction(func(tx *gorm.DB) error {
+		index = db.EmbeddingIndex{
+			Model:           model,
+			Dimension:       dimension,
+			TemplateVersion: templateVersion,
+			Status:          IndexBuilding,
+		}
+		if err := tx.Create(&index).Error; err != nil {
+			return err
+		}
+		index.VectorTable = fmt.Sprintf("vec_markets_%d", index.ID)
+		if err := tx.Model(&index).Update("vector_table", index.VectorTable).Error; err != nil {
+			return err
+		}
+		if db.Dialect(tx) == db.DialectPostgres {
+			return createPGVectorTable(tx, index.VectorTable, dimension)
+		}
+		return createSQLiteVectorTable(tx, index.VectorTable, dimension)
+	})
+	if err != nil {
+		return index, fmt.Errorf("failed to create embedding index: %w", err)
+	}
+	return index, nil
+}
+
+// ActivateIndex atomically makes a built index the one searches use and retires the
+// previous one. Readers resolve the active index per query, so they switch over on
+// their next search.
+func ActivateIndex(database *gorm.DB, index db.EmbeddingIndex) error {
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&db.EmbeddingIndex{}).Where("status = ?", IndexActive).
+			Update("status", IndexRetired).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&db.EmbeddingIndex{}).Where("id = ? AND status = ?", index.ID, IndexBuilding).
+			Updates(map[string]interface{}{"status": IndexActive, "activated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("index %d is no longer being built", index.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to activate embedding index %d: %w", index.ID, err)
+	}
+
+	dropRetired(database)
+	return nil
+}
+
+// dropRetired deletes the vectors of retired indexes. The rows are kept as history.
+func dropRetired(database *gorm.DB) {
+	var retired []db.EmbeddingIndex
+	if err := database.Where("status = ?", IndexRetired).Find(&retired).Error; err != nil {
+		log.Printf("Failed to list retired embedding indexes: %v", err)
+		return
+	}
+	for _, index := range retired {
+		if err := database.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", index.VectorTable)).Error; err != nil {
+			log.Printf("Failed to drop vector table %s: %v", index.VectorTable, err)
+			continue
+		}
+		if err := database.Where("index_id = ?", index.ID).Delete(&db.MarketEmbedding{}).Error; err != nil {
+			log.Printf("Failed to delete embedding records for index %d: %v", index.ID, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package vectorstore
+
+import (
+	"fmt"
+	"strings"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pgvectorStore keeps embeddings in a pgvector table (vec_markets for the original
+// index) keyed by market_id. Filters are applied by joining markets, so no metadata is copied.
+type pgvectorStore struct {
+	indexRef
+}
+
+func (s *pgvectorStore) Upsert(market db.Market, vec []float32) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	vecString, err := encode(vec)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Exec(fmt.Sprintf(`
+		INSERT INTO %s(market_id, embedding) VALUES (?, CAST(? AS vector))
+		ON CONFLICT (market_id) DO UPDATE SET embedding = EXCLUDED.embedding
+	`, table), market.ID, vecString).Error
+}
+
+func (s *pgvectorStore) Delete(ids ...uint) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	return inBatches(ids, func(batch []uint) error {
+		return s.DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE market_id IN ?", table), batch).Error
+	})
+}
+
+func (s *pgvectorStore) Search(vec []float32, k int, filters Filters) ([]Result, error) {
+	table, err := s.table()
+	if err != nil {
+		return nil, err
+	}
+	vecString, err := encode(vec)
+	if err != nil {
+		return nil, err
+	}
+
+	// The HNSW scan only yields ef_search candidates (40 by default), and the filters
+	// and the join run after it, so they could leave fewer than k. Widen the scan as
+	// the SQLite store overfetches, and let it iterate until k rows pass the filters
+	// (iterative scans need pgvector 0.8).
+	candidates := max(k, defaultEFSearch)
+	if !filters.IsZero() {
+		candidates = max(k*filteredOverfetch, defaultEFSearch)
+	}
+	candidates = min(candidates, maxEFSearch)
+
+	// <-> is L2 distance, matching the sqlite-vec default. Ordering by the
+	// expression rather than its alias lets Postgres use the HNSW index.
+	var results []Result
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// SET LOCAL lasts until the end of the transaction, so pooled connections
+		// keep the defaults
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", candidates)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("SET LOCAL hnsw.iterative_scan = strict_order").Error; err != nil {
+			return err
+		}
+		return filters.apply(tx.Table(table+" v").
+			Select("v.market_id AS id, v.embedding <-> CAST(? AS vector) AS score", vecString).
+			Joins("JOIN markets m ON m.id = v.market_id")).
+			Order(clause.OrderBy{Expression: clause.Expr{SQL: "v.embedding <-> CAST(? AS vector)", Vars: []interface{}{vecString}}}).
+			Limit(k).
+			Scan(&results).Error
+	})
+	return results, err
+}
+
+// hnsw.ef_search bounds in pgvector
+const (
+	defaultEFSearch = 40
+	maxEFSearch     = 1000
+)
+
+func (s *pgvectorStore) SearchText(query string, k int, filters Filters) ([]Result, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	// plainto_tsquery ANDs every term; swap to OR so partial matches still rank,
+	// mirroring the SQLite query. ts_rank_cd is negated so lower is better.
+	var results []Result
+	err := filters.apply(s.DB.Table("markets m").
+		Select("m.id AS id, -ts_rank_cd(m.search_vector, q.query) AS score").
+		Joins("CROSS JOIN (SELECT replace(plainto_tsquery('english', ?)::text, ' & ', ' | ')::tsquery AS query) q", query).
+		Where("m.search_vector @@ q.query")).
+		Order("score").
+		Limit(k).
+		Scan(&results).Error
+	return results, err
+}
+
+func (s *pgvectorStore) Count() (int64, error) {
+	table, err := s.table()
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = s.DB.Table(table).Count(&count).Error
+	return count, err
+}
+
+// createPGVectorTable creates a pgvector table and its HNSW index
+func createPGVectorTable(tx *gorm.DB, table string, dimension int) error {
+	if err := tx.Exec(fmt.Sprintf(`
+		CREATE TABLE %s (
+			market_id bigint PRIMARY KEY,
+			embedding vector(%d) NOT NULL
+		)
+	`, table, dimension)).Error; err != nil {
+		return err
+	}
+	return tx.Exec(fmt.Sprintf("CREATE INDEX idx_%s_embedding ON %s USING hnsw (embedding vector_l2_ops)", table, table)).Error
+}
@@ -0,0 +1,149 @@
+package vectorstore
+
+import (
+	"fmt"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// sqliteStore keeps embeddings in a sqlite-vec vec0 table (vec_markets for the
+// original index), keyed by rowid = markets.id, with a copy of the market's
+// filterable fields as metadata columns. The sqlite-vec extension must be
+// registered (sqlite_vec.Auto()) by the calling service.
+type sqliteStore struct {
+	indexRef
+}
+
+func (s *sqliteStore) Upsert(market db.Market, vec []float32) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	vecString, err := encode(vec)
+	if err != nil {
+		return err
+	}
+
+	// vec0 tables don't support upserts, so replace the row
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE rowid = ?", table), market.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf(`
+			INSERT INTO %s(rowid, provider_id, embedding, status, category, event_ticker, close_time)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, table), market.ID, market.ProviderID, vecString, market.Status, market.Category, market.EventTicker, market.CloseTime.Unix()).Error
+	})
+}
+
+func (s *sqliteStore) Delete(ids ...uint) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	return inBatches(ids, func(batch []uint) error {
+		return s.DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE rowid IN ?", table), batch).Error
+	})
+}
+
+// maxK is the largest k sqlite-vec accepts in a KNN query
+const maxK = 4096
+
+func (s *sqliteStore) Search(vec []float32, k int, filters Filters) ([]Result, error) {
+	table, err := s.table()
+	if err != nil {
+		return nil, err
+	}
+	vecString, err := encode(vec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filters are applied inside the KNN query so they narrow the candidates before
+	// ranking. Metadata is copied when a market is embedded and can lag behind it, so
+	// the join re-checks against markets and a few extra neighbours cover the difference.
+	candidates := k
+	if !filters.IsZero() {
+		candidates = min(k*filteredOverfetch, maxK)
+	}
+
+	// sqlite-vec uses 'distance' as the column for the score in `vec0` queries
+	knn := s.DB.Table(table).
+		Select("rowid AS id, distance AS score").
+		Where("embedding MATCH ? AND k = ?", vecString, candidates)
+	if filters.ProviderID != 0 {
+		knn = knn.Where("provider_id = ?", filters.ProviderID)
+	}
+	if filters.Category != "" {
+		knn = knn.Where("category = ?", filters.Category)
+	}
+	if filters.Status != "" {
+		knn = knn.Where("status = ?", filters.Status)
+	}
+	if !filters.CloseAfter.IsZero() {
+		knn = knn.Where("close_time >= ?", filters.CloseAfter.Unix())
+	}
+	if !filters.CloseBefore.IsZero() {
+		knn = knn.Where("close_time < ?", filters.CloseBefore.Unix())
+	}
+	if filters.ExcludeEventTicker != "" {
+		knn = knn.Where("event_ticker != ?", filters.ExcludeEventTicker)
+	}
+
+	var results []Result
+	err = filters.apply(s.DB.Table("(?) AS v", knn).
+		Select("v.id, v.score").
+		Joins("JOIN markets m ON m.id = v.id")).
+		Order("v.score").
+		Limit(k).
+		Scan(&results).Error
+	return results, err
+}
+
+// bm25Weights ranks matches in tickers and titles above matches in the rules text.
+// The order follows the markets_fts columns.
+const bm25Weights = "10.0, 5.0, 2.0, 2.0, 1.0, 0.5"
+
+func (s *sqliteStore) SearchText(query string, k int, filters Filters) ([]Result, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	// bm25() is negative, more so for better matches
+	var results []Result
+	err := filters.apply(s.DB.Table("markets_fts").
+		Select("markets_fts.rowid AS id, bm25(markets_fts, "+bm25Weights+") AS score").
+		Joins("JOIN markets m ON m.id = markets_fts.rowid").
+		Where("markets_fts MATCH ?", match)).
+		Order("score").
+		Limit(k).
+		Scan(&results).Error
+	return results, err
+}
+
+func (s *sqliteStore) Count() (int64, error) {
+	table, err := s.table()
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = s.DB.Table(table).Count(&count).Error
+	return count, err
+}
+
+// createSQLiteVectorTable creates a vec0 table. Its dimension is fixed at creation.
+func createSQLiteVectorTable(tx *gorm.DB, table string, dimension int) error {
+	return tx.Exec(fmt.Sprintf(`
+		CREATE VIRTUAL TABLE %s USING vec0(
+			provider_id integer partition key,
+			embedding FLOAT[%d],
+			status text,
+			category text,
+			event_ticker text,
+			close_time integer
+		)
+	`, table, dimension)).Error
+}
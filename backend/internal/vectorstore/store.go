@@ -0,0 +1,162 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// deleteBatchSize bounds the number of IDs bound into a single DELETE
+	deleteBatchSize = 500
+	// filteredOverfetch is how many neighbours to consider per requested result when
+	// filters may discard some of the nearest ones
+	filteredOverfetch = 2
+)
+
+// Result is a single search hit. Results are ordered by ascending Score, which is
+// the L2 distance for vector search, the BM25 rank for text search and the negated
+// fused rank for hybrid search, so lower is always better.
+type Result struct {
+	ID    uint // markets.id
+	Score float64
+}
+
+// Similarities rescales the scores of an ordered result list from 0 to 1, 1 for
+// the best hit and 0 for the worst, so hits from searches whose scores mean
+// different things can be weighed alike. Hits that all score the same are all 1.
+func Similarities(results []Result) []float64 {
+	similarities := make([]float64, len(results))
+	if len(results) == 0 {
+		return similarities
+	}
+	best, worst := results[0].Score, results[0].Score
+	for _, r := range results {
+		best, worst = min(best, r.Score), max(worst, r.Score)
+	}
+	for i, r := range results {
+		similarities[i] = 1
+		if worst > best {
+			similarities[i] = (worst - r.Score) / (worst - best)
+		}
+	}
+	return similarities
+}
+
+// Store indexes market embeddings for nearest-neighbour search.
+// It is backed by sqlite-vec on SQLite and by pgvector on Postgres.
+type Store interface {
+	// Upsert stores the embedding for a market, replacing any previous one
+	Upsert(market db.Market, vec []float32) error
+	// Delete removes the embeddings for the given markets
+	Delete(ids ...uint) error
+	// Search returns the k markets closest to vec that match the filters
+	Search(vec []float32, k int, filters Filters) ([]Result, error)
+	// SearchText returns the k markets whose text best matches the query by keyword
+	SearchText(query string, k int, filters Filters) ([]Result, error)
+	// Count returns the number of indexed embeddings
+	Count() (int64, error)
+	// Index describes the embedding index the store reads and writes
+	Index() (db.EmbeddingIndex, error)
+}
+
+// Filters restricts a search to markets with matching metadata. Zero values match everything.
+type Filters struct {
+	ProviderID  uint
+	Category    string
+	Status      string
+	CloseAfter  time.Time // Only markets closing at or after this time
+	CloseBefore time.Time // Only markets closing before this time
+	// ExcludeEventTicker drops markets belonging to this event, e.g. the query market's own
+	ExcludeEventTicker string
+}
+
+// IsZero reports whether the filters match every market
+func (f Filters) IsZero() bool {
+	return f == Filters{}
+}
+
+// apply adds the filters as conditions on the markets table aliased as m
+func (f Filters) apply(q *gorm.DB) *gorm.DB {
+	if f.ProviderID != 0 {
+		q = q.Where("m.provider_id = ?", f.ProviderID)
+	}
+	if f.Category != "" {
+		q = q.Where("m.category = ?", f.Category)
+	}
+	if f.Status != "" {
+		q = q.Where("m.status = ?", f.Status)
+	}
+	if !f.CloseAfter.IsZero() {
+		q = q.Where("m.close_time >= ?", f.CloseAfter)
+	}
+	if !f.CloseBefore.IsZero() {
+		q = q.Where("m.close_time < ?", f.CloseBefore)
+	}
+	if f.ExcludeEventTicker != "" {
+		q = q.Where("m.event_ticker != ?", f.ExcludeEventTicker)
+	}
+	return q
+}
+
+// New returns a store for whichever embedding index is active at the time of each
+// call, so it follows the swap when a rebuilt index is activated
+func New(database *gorm.DB) Store {
+	return newStore(indexRef{DB: database})
+}
+
+// ForIndex returns a store for one particular embedding index, e.g. one being built
+func ForIndex(database *gorm.DB, index db.EmbeddingIndex) Store {
+	return newStore(indexRef{DB: database, index: &index})
+}
+
+func newStore(ref indexRef) Store {
+	if db.Dialect(ref.DB) == db.DialectPostgres {
+		return &pgvectorStore{ref}
+	}
+	return &sqliteStore{ref}
+}
+
+// indexRef resolves the vector table a store uses: a fixed index's, or the active one's
+type indexRef struct {
+	DB    *gorm.DB
+	index *db.EmbeddingIndex // nil follows the active index
+}
+
+func (r indexRef) Index() (db.EmbeddingIndex, error) {
+	if r.index != nil {
+		return *r.index, nil
+	}
+	return ActiveIndex(r.DB)
+}
+
+func (r indexRef) table() (string, error) {
+	index, err := r.Index()
+	if err != nil {
+		return "", err
+	}
+	return index.VectorTable, nil
+}
+
+// encode formats a vector as a JSON array, which both sqlite-vec and pgvector accept as text
+func encode(vec []float32) (string, error) {
+	b, err := json.Marshal(vec)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// inBatches calls fn with consecutive slices of at most deleteBatchSize IDs
+func inBatches(ids []uint, fn func(batch []uint) error) error {
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(ids))
+		if err := fn(ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
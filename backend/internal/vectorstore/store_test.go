@@ -0,0 +1,221 @@
+package vectorstore
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// newTestDB migrates a throwaway SQLite database
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "vectorstore.db"))
+	database, err := db.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.MigrateUp(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// point is a vector in the active index's space at distance x from the origin along one axis
+func point(t *testing.T, store Store, x float32) []float32 {
+	t.Helper()
+	index, err := store.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vec := make([]float32, index.Dimension)
+	vec[0] = x
+	return vec
+}
+
+func ids(results []Result) []uint {
+	out := make([]uint, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestSQLiteStore(t *testing.T) {
+	database := newTestDB(t)
+	kalshi, other := db.Provider{Name: "kalshi"}, db.Provider{Name: "other"}
+	database.Create(&kalshi)
+	database.Create(&other)
+
+	now := time.Now().Truncate(time.Second)
+	week, month := now.Add(7*24*time.Hour), now.Add(30*24*time.Hour)
+	markets := []db.Market{
+		{ProviderID: kalshi.ID, Ticker: "FED-A", EventTicker: "FED", Category: "Economics", Status: "active", CloseTime: week},
+		{ProviderID: kalshi.ID, Ticker: "FED-B", EventTicker: "FED", Category: "Economics", Status: "active", CloseTime: month},
+		{ProviderID: kalshi.ID, Ticker: "CPI-A", EventTicker: "CPI", Category: "Economics", Status: "closed", CloseTime: week},
+		{ProviderID: kalshi.ID, Ticker: "ELEC-A", EventTicker: "ELEC", Category: "Politics", Status: "active", CloseTime: month},
+		{ProviderID: other.ID, Ticker: "FED-X", EventTicker: "FEDX", Category: "Economics", Status: "active", CloseTime: week},
+	}
+	store := New(database)
+	for i := range markets {
+		markets[i].ExternalID = markets[i].Ticker
+		database.Create(&markets[i])
+		// Markets sit at 1, 2, 3... along one axis, so their distance to the origin orders them
+		if err := store.Upsert(markets[i], point(t, store, float32(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fedA, fedB, cpi, elec, fedX := markets[0].ID, markets[1].ID, markets[2].ID, markets[3].ID, markets[4].ID
+
+	if count, err := store.Count(); err != nil || count != 5 {
+		t.Fatalf("Count() = %d, %v, want 5", count, err)
+	}
+
+	tests := []struct {
+		name    string
+		k       int
+		filters Filters
+		want    []uint
+	}{
+		{"nearest first", 3, Filters{}, []uint{fedA, fedB, cpi}},
+		{"k larger than the index", 10, Filters{}, []uint{fedA, fedB, cpi, elec, fedX}},
+		{"provider", 10, Filters{ProviderID: other.ID}, []uint{fedX}},
+		{"category", 10, Filters{Category: "Politics"}, []uint{elec}},
+		{"status", 10, Filters{Status: "closed"}, []uint{cpi}},
+		{"closing at or after", 10, Filters{CloseAfter: month}, []uint{fedB, elec}},
+		{"closing before", 10, Filters{CloseBefore: month}, []uint{fedA, cpi, fedX}},
+		{"excluding an event", 10, Filters{ExcludeEventTicker: "FED"}, []uint{cpi, elec, fedX}},
+		{"combined", 10, Filters{ProviderID: kalshi.ID, Category: "Economics", Status: "active"}, []uint{fedA, fedB}},
+		// Filters narrow the candidates before the top k is taken
+		{"filtered top k", 1, Filters{Category: "Politics"}, []uint{elec}},
+		{"nothing matches", 10, Filters{Category: "Sports"}, []uint{}},
+	}
+	for _, tt := range tests {
+		results, err := store.Search(point(t, store, 0), tt.k, tt.filters)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if fmt.Sprint(ids(results)) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, ids(results), tt.want)
+		}
+	}
+
+	// 1. Upsert replaces a market's vector rather than adding a second one
+	if err := store.Upsert(markets[3], point(t, store, 0.5)); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Count(); count != 5 {
+		t.Errorf("Count() = %d after re-embedding, want 5", count)
+	}
+	if results, _ := store.Search(point(t, store, 0), 2, Filters{}); fmt.Sprint(ids(results)) != fmt.Sprint([]uint{elec, fedA}) {
+		t.Errorf("after moving ELEC-A to 0.5 got %v, want %v", ids(results), []uint{elec, fedA})
+	}
+
+	// 2. Filters re-check the market itself, so metadata changed since it was embedded still applies
+	database.Model(&db.Market{}).Where("id = ?", fedA).Update("status", "closed")
+	if results, _ := store.Search(point(t, store, 0), 10, Filters{Status: "active"}); fmt.Sprint(ids(results)) != fmt.Sprint([]uint{elec, fedB, fedX}) {
+		t.Errorf("active markets after FED-A closed: got %v, want %v", ids(results), []uint{elec, fedB, fedX})
+	}
+
+	// 3. Deletes span batches and ignore markets that were never embedded
+	var toDelete []uint
+	for i := range 2*deleteBatchSize + 10 {
+		toDelete = append(toDelete, uint(100000+i))
+	}
+	toDelete[0], toDelete[deleteBatchSize+1], toDelete[len(toDelete)-1] = fedA, cpi, fedX
+	if err := store.Delete(toDelete...); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Count(); count != 2 {
+		t.Errorf("Count() = %d after deleting, want 2", count)
+	}
+	if results, _ := store.Search(point(t, store, 0), 10, Filters{}); fmt.Sprint(ids(results)) != fmt.Sprint([]uint{elec, fedB}) {
+		t.Errorf("after deleting got %v, want %v", ids(results), []uint{elec, fedB})
+	}
+	if err := store.Delete(); err != nil {
+		t.Errorf("Delete() with no IDs: %v", err)
+	}
+}
+
+func TestSimilarities(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   []float64
+	}{
+		{"empty", nil, []float64{}},
+		{"one hit", []float64{0.4}, []float64{1}},
+		{"distances", []float64{1, 2, 5}, []float64{1, 0.75, 0}},
+		{"negated fused ranks", []float64{-0.03, -0.02, -0.01}, []float64{1, 0.5, 0}},
+		{"ties", []float64{-2, -2}, []float64{1, 1}},
+		{"a clear best match", []float64{0, 9, 9.5, 10}, []float64{1, 0.1, 0.05, 0}},
+	}
+	for _, tt := range tests {
+		results := make([]Result, len(tt.scores))
+		for i, score := range tt.scores {
+			results[i] = Result{ID: uint(i + 1), Score: score}
+		}
+		got := Similarities(results)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestInBatches(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []int // Batch sizes
+	}{
+		{0, nil},
+		{1, []int{1}},
+		{deleteBatchSize, []int{deleteBatchSize}},
+		{deleteBatchSize + 1, []int{deleteBatchSize, 1}},
+		{2*deleteBatchSize + 3, []int{deleteBatchSize, deleteBatchSize, 3}},
+	}
+	for _, tt := range tests {
+		all := make([]uint, tt.n)
+		for i := range all {
+			all[i] = uint(i)
+		}
+
+		var sizes []int
+		next := uint(0)
+		inBatches(all, func(batch []uint) error {
+			sizes = append(sizes, len(batch))
+			for _, id := range batch {
+				if id != next {
+					t.Errorf("%d IDs: got ID %d, want %d", tt.n, id, next)
+				}
+				next++
+			}
+			return nil
+		})
+		if fmt.Sprint(sizes) != fmt.Sprint(tt.want) {
+			t.Errorf("%d IDs: batches of %v, want %v", tt.n, sizes, tt.want)
+		}
+	}
+
+	// The first failing batch stops the rest
+	calls := 0
+	err := inBatches(make([]uint, 2*deleteBatchSize), func([]uint) error {
+		calls++
+		return fmt.Errorf("boom")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("got %v after %d calls, want the first batch's error", err, calls)
+	}
+}
@@ -112,6 +112,14 @@ cd backend
 
 # Export CGO_CFLAGS to include the local sqlite headers
 export CGO_CFLAGS="-I$(pwd)/include"
+# Build go-sqlite3 with FTS5, which lexical market search needs
+export GOFLAGS="-tags=sqlite_fts5"
+
+# Bring the database schema up to date before any service connects
+go run ./cmd/migrate up || {
+    echo "Error: Database migration failed."
+    cleanup
+}
 
 # Run Air for each service pointing to its specific config
 air -c .air/bff.toml &